@@ -0,0 +1,291 @@
+// cli는 운영용 명령 모음.
+//
+//	cli reprocess    저장된 AWS 원본 payload로 whatsnews/태그를 다시 계산. payload가 없는
+//	                 행(메일, RSS)은 저장된 제목·본문·태그로 리전·서비스만 다시 연결
+//	cli merges       출처 간 병합 결정 목록
+//	cli unmerge <id> 병합 결정을 되돌려 출처를 별도 레코드로 분리
+//	cli inventory [-clear] [file]
+//	                 인벤토리(Terraform state/plan JSON 또는 서비스 코드 목록) 교체. file이
+//	                 없으면 현재 인벤토리 출력, "-"이면 표준 입력
+//	cli export [-format csv|ndjson] [-o file] [query]
+//	                 필터(/api/whatsnews 쿼리 문자열 형식, 예: "tags=12&from=2024-07-01")에
+//	                 맞는 뉴스 전체를 내보낸다. -o가 없으면 표준 출력
+//	cli apikey create -name <name> [-scopes read,admin]
+//	                 API 키 발급. 키 원문은 이때 표준 출력으로 한 번만 보여 준다
+//	cli apikey list  발급한 키 목록 (원문 없이)
+//	cli apikey revoke <id>
+//	                 키 폐기. 실행 중인 서버에는 늦어도 1분 안에 반영된다
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.krafton.com/ops2022/noti-aws-update/internal"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, store internal.Store, args []string) error
+}
+
+var commands = []command{
+	{"reprocess", "re-derive whatsnews columns and tags from stored AWS payloads", runReprocess},
+	{"merges", "list cross-source merge decisions", runMerges},
+	{"unmerge", "undo a merge decision and split its source into its own record", runUnmerge},
+	{"inventory", "show or replace the service inventory used for relevance", runInventory},
+	{"export", "write every matching announcement as CSV or NDJSON", runExport},
+	{"apikey", "create, list or revoke API keys", runAPIKey},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [flags]\n\ncommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", c.name, c.usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == os.Args[1] {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg := internal.LoadConfig()
+	store, err := internal.NewStore(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+
+	if err := cmd.run(context.Background(), store, os.Args[2:]); err != nil {
+		log.Printf("%s: %v", cmd.name, err)
+		store.Close()
+		os.Exit(1)
+	}
+}
+
+func runReprocess(ctx context.Context, store internal.Store, args []string) error {
+	fs := flag.NewFlagSet("reprocess", flag.ExitOnError)
+	fs.Parse(args)
+
+	res, err := store.ReprocessAwsItems(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("reprocess done: updated=%d failed=%d skipped(no payload)=%d relinked(regions/services of skipped)=%d",
+		res.Updated, res.Failed, res.Skipped, res.Relinked)
+	return nil
+}
+
+func runMerges(ctx context.Context, store internal.Store, args []string) error {
+	fs := flag.NewFlagSet("merges", flag.ExitOnError)
+	limit := fs.Int("limit", 50, "max decisions to list")
+	whatsnew := fs.Int("whatsnew", 0, "only decisions for this whatsnews id")
+	all := fs.Bool("all", false, "include undone decisions")
+	fs.Parse(args)
+
+	merges, err := store.ListMerges(ctx, internal.MergesQuery{
+		Limit:         *limit,
+		WhatsnewId:    *whatsnew,
+		IncludeUndone: *all,
+	})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	for _, m := range merges {
+		if err := enc.Encode(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runUnmerge(ctx context.Context, store internal.Store, args []string) error {
+	fs := flag.NewFlagSet("unmerge", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: unmerge <merge id>")
+	}
+	id, err := strconv.Atoi(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid merge id %q", fs.Arg(0))
+	}
+
+	m, err := store.UndoMerge(ctx, id)
+	if err != nil {
+		return err
+	}
+	log.Printf("merge %d undone: %s %s split from whatsnews %d into whatsnews %d",
+		m.Id, m.SourceType, m.SourceId, m.WhatsnewId, *m.SplitWhatsnewId)
+	return nil
+}
+
+func runInventory(ctx context.Context, store internal.Store, args []string) error {
+	fs := flag.NewFlagSet("inventory", flag.ExitOnError)
+	clearAll := fs.Bool("clear", false, "remove the stored inventory")
+	fs.Parse(args)
+
+	switch {
+	case *clearAll:
+		if err := store.ReplaceInventory(ctx, internal.Inventory{}); err != nil {
+			return err
+		}
+		log.Printf("inventory cleared")
+		return nil
+	case fs.NArg() == 0:
+		inv, err := store.GetInventory(ctx)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(os.Stdout).Encode(inv)
+	case fs.NArg() > 1:
+		return errors.New("usage: inventory [-clear] [file|-]")
+	}
+
+	var (
+		b   []byte
+		err error
+	)
+	if fs.Arg(0) == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+	inv, err := internal.ParseInventory(b)
+	if err != nil {
+		return err
+	}
+	if err := store.ReplaceInventory(ctx, inv); err != nil {
+		return err
+	}
+	log.Printf("inventory replaced: source=%s services=%d", inv.Source, len(inv.Services))
+	for _, t := range inv.Unmapped {
+		log.Printf("unmapped resource type: %s", t)
+	}
+	return nil
+}
+
+func runExport(ctx context.Context, store internal.Store, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", internal.ExportCSV, "csv or ndjson")
+	out := fs.String("o", "", "output file (default stdout)")
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		return errors.New("usage: export [-format csv|ndjson] [-o file] [query]")
+	}
+
+	values, err := url.ParseQuery(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid query: %w", err)
+	}
+	q, err := internal.ParseWhatsNewsQuery(values)
+	if err != nil {
+		return err
+	}
+
+	w := os.Stdout
+	if *out != "" && *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	n, err := internal.ExportWhatsNews(ctx, store, q, *format, bw, nil)
+	if err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if w != os.Stdout {
+		if err := w.Close(); err != nil {
+			return err
+		}
+		log.Printf("exported %d announcements to %s", n, *out)
+	}
+	return nil
+}
+
+func runAPIKey(ctx context.Context, store internal.Store, args []string) error {
+	const usage = "usage: apikey create -name <name> [-scopes read,admin] | apikey list | apikey revoke <id>"
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("apikey create", flag.ExitOnError)
+		name := fs.String("name", "", "who or what uses the key")
+		scopes := fs.String("scopes", internal.ScopeRead, "comma-separated scopes: read, admin")
+		fs.Parse(args[1:])
+		sc, err := internal.ParseAPIKeyScopes(*scopes)
+		if err != nil {
+			return err
+		}
+		secret, k, err := internal.IssueAPIKey(ctx, store, *name, sc)
+		if err != nil {
+			return err
+		}
+		log.Printf("api key %d (%s) created for %q with scopes %v; it is shown only once", k.Id, k.Prefix, k.Name, k.Scopes)
+		fmt.Println(secret)
+		return nil
+	case "list":
+		keys, err := store.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(os.Stdout)
+		for _, k := range keys {
+			if err := enc.Encode(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New(usage)
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid api key id %q", args[1])
+		}
+		k, err := store.RevokeAPIKey(ctx, id)
+		if err != nil {
+			return err
+		}
+		// 서버는 확인한 키를 1분 동안 캐시한다 (internal/auth.go apiKeyCacheTTL)
+		log.Printf("api key %d (%s, %q) revoked at %s; running servers reject it within a minute (restart them to cut it off now)",
+			k.Id, k.Prefix, k.Name, k.RevokedAt.Format(time.RFC3339))
+		return nil
+	}
+	return errors.New(usage)
+}
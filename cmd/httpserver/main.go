@@ -1,19 +1,35 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.krafton.com/ops2022/noti-aws-update/internal"
 )
 
 func main() {
+	dev := flag.Bool("dev", false, "serve the web UI from ./static, re-read on every request")
+	flag.Parse()
+
 	cfg := internal.LoadConfig()
+	if *dev {
+		cfg.StaticDir = "static"
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	pool, err := internal.NewDBPool(cfg)
+	store, err := internal.NewStore(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer pool.Close()
 
-	internal.StartHTTPServer(pool, cfg.AppPort)
+	err = internal.StartHTTPServer(ctx, store, cfg)
+	store.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,46 @@
+// standalone은 스케줄러와 HTTP 서버를 한 프로세스로 실행한다.
+// DATABASE_DRIVER=sqlite와 함께 쓰면 DB 서버 없이 파일 하나로 전체 시스템을 운영할 수 있다.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.krafton.com/ops2022/noti-aws-update/internal"
+)
+
+func main() {
+	dev := flag.Bool("dev", false, "serve the web UI from ./static, re-read on every request")
+	flag.Parse()
+
+	cfg := internal.LoadConfig()
+	if *dev {
+		cfg.StaticDir = "static"
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	store, err := internal.NewStore(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		internal.RunScheduler(ctx, cfg, store)
+	}()
+
+	err = internal.StartHTTPServer(ctx, store, cfg)
+	// 서버가 먼저 실패해도 스케줄러를 멈추고, 진행 중인 수집이 끝난 뒤에 DB를 닫는다
+	stop()
+	<-schedulerDone
+	store.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -99,13 +99,14 @@ async function transactionCustom(item, tags, maxRetry = 3) {
     try {
       await client.query("BEGIN");
       const whatsnews_res = await client.query(
-        "INSERT INTO whatsnews(title, content, source_id, source_url, source_created_at, created_at, updated_at) VALUES($1, $2, $3, $4, $5, NOW(), NOW()) ON CONFLICT (source_id) DO NOTHING RETURNING id",
+        "INSERT INTO whatsnews(title, content, source_id, source_url, source_created_at, raw_payload, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, NOW(), NOW()) ON CONFLICT (source_id) DO UPDATE SET raw_payload = EXCLUDED.raw_payload WHERE whatsnews.raw_payload IS NULL RETURNING id",
         [
           item.additionalFields.headline,
           item.additionalFields.postBody,
           item.id,
           item.additionalFields.headlineUrl,
           item.additionalFields.postDateTime,
+          JSON.stringify({ item, tags }),
         ],
       );
 
@@ -121,25 +122,22 @@ async function transactionCustom(item, tags, maxRetry = 3) {
       }
 
       for (let j = 0; j < tags.length; j++) {
+        const namespace = tagNamespace(tags[j]);
         const tags_res = await client.query(
-          "INSERT INTO tags(name, created_at) VALUES($1, NOW()) ON CONFLICT (name) DO NOTHING RETURNING id",
-          [tags[j].name],
+          "INSERT INTO tags(name, source_id, namespace, created_at) VALUES($1, $2, $3, NOW()) ON CONFLICT (source_id) DO UPDATE SET name = EXCLUDED.name, namespace = EXCLUDED.namespace RETURNING id",
+          [tags[j].name, tags[j].id, namespace],
         );
-
-        let tagId;
-        if (tags_res.rows.length > 0) {
-          tagId = tags_res.rows[0].id;
-        } else {
-          const oldTag = await client.query(
-            "SELECT id FROM tags WHERE name = $1",
-            [tags[j].name],
-          );
-          tagId = oldTag.rows[0].id;
-        }
-        await client.query(
+        const tagId = tags_res.rows[0].id;
+        const link_res = await client.query(
           "INSERT INTO whatsnews_tags(whatsnew_id, tag_id, created_at) VALUES($1, $2, NOW()) ON CONFLICT(whatsnew_id, tag_id) DO NOTHING",
           [whatsnewsId, tagId],
         );
+        if (link_res.rowCount > 0) {
+          await client.query(
+            "INSERT INTO tag_stats(tag_id, news_cnt) VALUES($1, 1) ON CONFLICT(tag_id) DO UPDATE SET news_cnt = tag_stats.news_cnt + 1",
+            [tagId],
+          );
+        }
       }
       await client.query("COMMIT");
       client.release();
@@ -165,6 +163,18 @@ async function transactionCustom(item, tags, maxRetry = 3) {
   }
 }
 
+// internal/aws.go의 awsTagNamespace와 같은 규칙 (whats-new-v2#general-products -> general-products).
+// tagNamespaceId가 없으면 태그 id의 마지막 구간을 제외한 부분을 쓴다
+function tagNamespace(tag) {
+  let ns = tag.tagNamespaceId || "";
+  if (ns === "") {
+    const id = tag.id || "";
+    const i = id.lastIndexOf("#");
+    if (i > 0) ns = id.slice(0, i);
+  }
+  return ns.slice(ns.lastIndexOf("#") + 1);
+}
+
 async function fetch_and_save(apiUrl) {
   const response = await fetchWithRetry(apiUrl);
   const data = await response.json();
@@ -298,4 +308,5 @@ for (const [directory_id, tags_id] of groups) {
 
 await Promise.all(promises);
 await pool.end();
-console.log("All Completed");
+// 리전·서비스 연결은 Go 쪽 카탈로그로 만든다
+console.log("All Completed. Run `cli reprocess` to link regions and services.");
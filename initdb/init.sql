@@ -1,14 +1,33 @@
 CREATE EXTENSION IF NOT EXISTS pg_trgm;
-CREATE EXTENSION IF NOT EXISTS pg_cron;
 
-DROP MATERIALIZED VIEW IF EXISTS tag_stats CASCADE;
+-- 예전 스키마는 tag_stats를 pg_cron으로 갱신하는 materialized view로 두었음
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM pg_matviews WHERE matviewname = 'tag_stats') THEN
+    DROP MATERIALIZED VIEW tag_stats CASCADE;
+  END IF;
+  IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_cron') THEN
+    PERFORM cron.unschedule(jobid) FROM cron.job WHERE jobname = 'refresh_tag_stats';
+  END IF;
+END $$;
+DROP TABLE IF EXISTS api_keys CASCADE;
+DROP TABLE IF EXISTS data_version CASCADE;
+DROP TABLE IF EXISTS inventory_services CASCADE;
+DROP TABLE IF EXISTS whatsnews_services CASCADE;
+DROP TABLE IF EXISTS services CASCADE;
+DROP TABLE IF EXISTS whatsnews_regions CASCADE;
+DROP TABLE IF EXISTS whatsnews_merges CASCADE;
+DROP TABLE IF EXISTS tag_stats CASCADE;
 DROP TABLE IF EXISTS whatsnews_tags CASCADE;
 DROP TABLE IF EXISTS tags CASCADE;
 DROP TABLE IF EXISTS whatsnews CASCADE;
 
 CREATE TABLE IF NOT EXISTS tags (
   id SERIAL PRIMARY KEY,
-  name VARCHAR(128) UNIQUE NOT NULL,
+  name VARCHAR(128) NOT NULL,
+  -- AWS 태그 id (예: whats-new-v2#general-products#amazon-ec2)와 네임스페이스 (예: general-products)
+  source_id VARCHAR(256) UNIQUE,
+  namespace VARCHAR(128),
   created_at TIMESTAMP
 );
 
@@ -16,11 +35,22 @@ CREATE TABLE IF NOT EXISTS whatsnews (
   id SERIAL PRIMARY KEY,
   title VARCHAR(512) NOT NULL,
   content TEXT,
-  source_id VARCHAR(256) UNIQUE NOT NULL,
+  -- API 항목은 AWS item id, 메일/RSS로 만들어진 레코드는 '<source_type>:<id>'
+  source_id VARCHAR(1024) UNIQUE NOT NULL,
+  source_type VARCHAR(32) NOT NULL DEFAULT 'aws-api',
   source_url VARCHAR(1024),
+  -- 출처 간 중복 판단용으로 정규화한 source_url (internal/canonical.go)
+  canonical_url VARCHAR(1024),
   source_created_at TIMESTAMP,
+  -- AWS API가 돌려준 항목 원본 (item + tags). cli reprocess로 컬럼을 다시 계산할 때 쓴다
+  raw_payload JSONB,
   created_at TIMESTAMP NOT NULL DEFAULT now(),
-  updated_at TIMESTAMP NOT NULL DEFAULT now()
+  updated_at TIMESTAMP NOT NULL DEFAULT now(),
+  -- 전문 검색용. 제목(A)이 본문(B)보다 높은 가중치를 가지며 본문은 HTML 태그를 제외한다
+  search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', regexp_replace(coalesce(content, ''), '<[^>]*>', ' ', 'g')), 'B')
+  ) STORED
 );
 
 CREATE TABLE IF NOT EXISTS whatsnews_tags (
@@ -30,28 +60,128 @@ CREATE TABLE IF NOT EXISTS whatsnews_tags (
   PRIMARY KEY (whatsnew_id, tag_id)
 );
 
-CREATE MATERIALIZED VIEW tag_stats AS
-SELECT wnt.tag_id AS tag_id, COUNT(*) AS news_cnt
-FROM whatsnews_tags wnt
-GROUP BY wnt.tag_id;
+-- 태그별 뉴스 건수. whatsnews_tags에 행이 추가되는 같은 트랜잭션 안에서 애플리케이션이 갱신한다.
+CREATE TABLE IF NOT EXISTS tag_stats (
+  tag_id INTEGER PRIMARY KEY REFERENCES tags(id) ON DELETE CASCADE,
+  news_cnt INTEGER NOT NULL DEFAULT 0
+);
+
+-- 제목/본문에서 추출한 리전 (코드는 internal/regions.go의 카탈로그 기준)
+CREATE TABLE IF NOT EXISTS whatsnews_regions (
+  whatsnew_id INTEGER NOT NULL REFERENCES whatsnews(id) ON DELETE CASCADE,
+  region_code VARCHAR(32) NOT NULL,
+  PRIMARY KEY (whatsnew_id, region_code)
+);
+
+-- 서비스 카탈로그 (internal/catalog/services.json). 애플리케이션이 시작할 때 카탈로그와 맞춘다
+CREATE TABLE IF NOT EXISTS services (
+  code VARCHAR(64) PRIMARY KEY,
+  name VARCHAR(128) NOT NULL,
+  aliases TEXT[] NOT NULL DEFAULT '{}'
+);
+
+-- 제목과 제품 태그에서 추출한 서비스
+CREATE TABLE IF NOT EXISTS whatsnews_services (
+  whatsnew_id INTEGER NOT NULL REFERENCES whatsnews(id) ON DELETE CASCADE,
+  service_code VARCHAR(64) NOT NULL REFERENCES services(code) ON DELETE CASCADE,
+  PRIMARY KEY (whatsnew_id, service_code)
+);
+
+-- 팀이 실제로 쓰는 서비스 (업로드한 Terraform state/plan 또는 서비스 목록)
+CREATE TABLE IF NOT EXISTS inventory_services (
+  service_code VARCHAR(64) PRIMARY KEY REFERENCES services(code) ON DELETE CASCADE,
+  source VARCHAR(32) NOT NULL,
+  resource_types TEXT[] NOT NULL DEFAULT '{}',
+  resource_count INTEGER NOT NULL DEFAULT 0,
+  uploaded_at TIMESTAMPTZ NOT NULL
+);
+
+-- 다른 출처에서 들어와 whatsnews 레코드에 병합된 항목과 그 결정.
+-- undone_at이 채워진 행은 관리자가 되돌린 결정이며, 분리된 레코드는 split_whatsnew_id
+CREATE TABLE IF NOT EXISTS whatsnews_merges (
+  id SERIAL PRIMARY KEY,
+  whatsnew_id INTEGER NOT NULL REFERENCES whatsnews(id) ON DELETE CASCADE,
+  source_type VARCHAR(32) NOT NULL,
+  source_id VARCHAR(1024) NOT NULL,
+  title VARCHAR(512) NOT NULL,
+  content TEXT,
+  url VARCHAR(1024),
+  source_created_at TIMESTAMP,
+  method VARCHAR(16) NOT NULL,
+  score DOUBLE PRECISION NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  undone_at TIMESTAMP,
+  split_whatsnew_id INTEGER REFERENCES whatsnews(id) ON DELETE SET NULL
+);
 
-CREATE UNIQUE INDEX IF NOT EXISTS tag_stats_pk ON tag_stats(tag_id);
+-- API 키. 키 자체는 저장하지 않고 SHA-256 해시만 둔다. prefix는 목록에서 키를 알아보는 용도.
+-- API 응답과 무관하므로 data_version 트리거를 걸지 않는다
+CREATE TABLE IF NOT EXISTS api_keys (
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(128) NOT NULL,
+  prefix VARCHAR(16) NOT NULL,
+  key_hash CHAR(64) NOT NULL UNIQUE,
+  scopes TEXT[] NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_used_at TIMESTAMPTZ,
+  revoked_at TIMESTAMPTZ
+);
+
+-- 데이터 버전. 아래 트리거가 API가 읽는 테이블이 바뀔 때마다 올리고, HTTP 응답 캐시
+-- (internal/cache.go)가 이 값이 바뀌면 캐시를 비운다
+CREATE TABLE IF NOT EXISTS data_version (
+  id INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+  version BIGINT NOT NULL DEFAULT 0,
+  modified_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+INSERT INTO data_version (id) VALUES (1) ON CONFLICT DO NOTHING;
 
-SELECT cron.unschedule(jobid) FROM cron.job WHERE jobname = 'refresh_tag_stats';
+-- 캐시는 "무언가 바뀌었다"만 알면 되므로 한 트랜잭션에서는 처음 한 번만 올린다. 그러지 않으면
+-- 수집·재처리 한 번에 이 행을 수천 번 다시 쓴다. 문장 단위 트리거는 바뀐 행이 없어도 불리므로
+-- 행 단위로 걸고, UPDATE는 값이 실제로 바뀐 행에서만 부른다 (ON CONFLICT DO UPDATE로 같은 값을 다시 쓰는 경우)
+CREATE OR REPLACE FUNCTION bump_data_version() RETURNS trigger AS $$
+BEGIN
+  IF current_setting('noti.data_version_bumped', true) IS DISTINCT FROM 'on' THEN
+    UPDATE data_version SET version = version + 1, modified_at = clock_timestamp() WHERE id = 1;
+    PERFORM set_config('noti.data_version_bumped', 'on', true);
+  END IF;
+  RETURN NULL;
+END $$ LANGUAGE plpgsql;
 
-SELECT cron.schedule(
-         'refresh_tag_stats',
-         '*/1 * * * *',
-         $$ REFRESH MATERIALIZED VIEW CONCURRENTLY tag_stats $$
-       );
+-- 테이블 목록은 internal/cache.go의 dataVersionTables와 같다
+DO $$
+DECLARE t TEXT;
+BEGIN
+  FOREACH t IN ARRAY ARRAY['tags', 'whatsnews', 'whatsnews_tags', 'tag_stats', 'whatsnews_regions',
+                           'services', 'whatsnews_services', 'inventory_services', 'whatsnews_merges'] LOOP
+    EXECUTE format('CREATE TRIGGER %I AFTER INSERT OR DELETE ON %I
+                    FOR EACH ROW EXECUTE FUNCTION bump_data_version()', t || '_data_version', t);
+    EXECUTE format('CREATE TRIGGER %I AFTER UPDATE ON %I
+                    FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION bump_data_version()',
+                   t || '_data_version_update', t);
+  END LOOP;
+END $$;
 
 CREATE INDEX IF NOT EXISTS idx_whatsnews_source_created_at ON whatsnews (source_created_at DESC);
 CREATE INDEX IF NOT EXISTS idx_whatsnews_scid_id ON whatsnews (source_created_at DESC, id);
+CREATE INDEX IF NOT EXISTS idx_whatsnews_created_at ON whatsnews (created_at);
+CREATE INDEX IF NOT EXISTS idx_whatsnews_updated_at_id ON whatsnews (updated_at DESC, id);
 
 CREATE INDEX IF NOT EXISTS idx_whatsnews_tags_tag_id ON whatsnews_tags (tag_id);
 CREATE INDEX IF NOT EXISTS idx_whatsnews_tags_whatsnew_id ON whatsnews_tags (whatsnew_id);
 CREATE INDEX IF NOT EXISTS idx_whatsnews_tags_tag_whatsnew ON whatsnews_tags (tag_id, whatsnew_id);
 
+CREATE INDEX IF NOT EXISTS idx_whatsnews_regions_region ON whatsnews_regions (region_code, whatsnew_id);
+CREATE INDEX IF NOT EXISTS idx_whatsnews_services_service ON whatsnews_services (service_code, whatsnew_id);
+CREATE INDEX IF NOT EXISTS idx_whatsnews_canonical_url ON whatsnews (canonical_url);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_whatsnews_merges_source ON whatsnews_merges (source_type, source_id) WHERE undone_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_whatsnews_merges_whatsnew_id ON whatsnews_merges (whatsnew_id);
+
+CREATE INDEX IF NOT EXISTS idx_whatsnews_search_vector ON whatsnews USING gin (search_vector);
+
+CREATE INDEX IF NOT EXISTS idx_tags_name ON tags (name);
+CREATE INDEX IF NOT EXISTS idx_tags_namespace_name ON tags (namespace, lower(name));
+
 CREATE INDEX IF NOT EXISTS idx_tags_name_trgm ON tags USING gin (name gin_trgm_ops);
 CREATE INDEX IF NOT EXISTS idx_whatsnews_title_trgm ON whatsnews USING gin (title gin_trgm_ops);
 CREATE INDEX IF NOT EXISTS idx_whatsnews_content_trgm ON whatsnews USING gin (content gin_trgm_ops);
@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.krafton.com/ops2022/noti-aws-update/static"
+)
+
+// 지문이 들어간 주소는 내용이 바뀌면 주소도 바뀌므로 오래 캐시해도 된다. 그 밖의 주소(/,
+// 지문 없는 /static/main.js)는 매번 ETag로 확인하게 한다.
+const (
+	immutableCacheControl  = "public, max-age=31536000, immutable"
+	revalidateCacheControl = "no-cache"
+)
+
+// Assets는 웹 UI 정적 파일. 파일마다 내용 해시를 넣은 이름(main.3f2a9c1e04b7.js)으로도 내보내고,
+// HTML의 "/static/<이름>" 참조를 그 이름으로 바꿔 둔다. 압축본(.br, .gz)이 옆에 있으면 그대로
+// 쓰고, gzip 압축본이 없으면 읽을 때 만든다.
+type Assets struct {
+	dir string    // 비어 있지 않으면 개발 모드: 요청마다 이 디렉터리에서 다시 읽는다
+	set *assetSet // 바이너리에 넣은 파일
+}
+
+type assetSet struct {
+	files map[string]*asset // 원래 이름과 지문 넣은 이름 모두
+	index *asset
+}
+
+type asset struct {
+	name        string
+	hashedName  string
+	hash        string
+	contentType string
+	body        []byte
+	gzip, br    []byte
+}
+
+var embeddedAssets = sync.OnceValue(func() *Assets {
+	set, err := loadAssets(static.Files)
+	if err != nil {
+		panic(fmt.Sprintf("embedded static files: %v", err))
+	}
+	return &Assets{set: set}
+})
+
+// NewAssets는 바이너리에 넣은 파일을 돌려준다. dir이 있으면 그 디렉터리의 파일을 요청마다
+// 다시 읽고 캐시하지 않게 한다 (httpserver -dev).
+func NewAssets(dir string) *Assets {
+	if dir != "" {
+		return &Assets{dir: dir}
+	}
+	return embeddedAssets()
+}
+
+func (a *Assets) current() (*assetSet, error) {
+	if a.dir == "" {
+		return a.set, nil
+	}
+	return loadAssets(os.DirFS(a.dir))
+}
+
+// ServeIndex는 웹 UI 첫 화면. 다른 라우트에 맞지 않는 경로도 여기로 온다.
+func (a *Assets) ServeIndex(w http.ResponseWriter, r *http.Request) {
+	set, err := a.current()
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+	if set.index == nil {
+		writeNotFound(w, r, "index.html not found")
+		return
+	}
+	a.serve(w, r, set.index, false)
+}
+
+// ServeStatic은 /static/{name}. 지문 넣은 이름으로 요청하면 1년 동안 캐시하게 한다.
+func (a *Assets) ServeStatic(w http.ResponseWriter, r *http.Request) {
+	set, err := a.current()
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+	name := r.PathValue("name")
+	f, ok := set.files[name]
+	if !ok {
+		writeNotFound(w, r, "static file not found")
+		return
+	}
+	a.serve(w, r, f, name == f.hashedName)
+}
+
+func (a *Assets) serve(w http.ResponseWriter, r *http.Request, f *asset, immutable bool) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Type", f.contentType)
+	h.Add("Vary", "Accept-Encoding")
+	if immutable && a.dir == "" {
+		h.Set("Cache-Control", immutableCacheControl)
+	} else {
+		h.Set("Cache-Control", revalidateCacheControl)
+	}
+
+	body, etag := f.body, f.hash
+	switch acceptedEncoding(r.Header.Get("Accept-Encoding"), f.br != nil, f.gzip != nil) {
+	case "br":
+		body, etag = f.br, etag+"-br"
+		h.Set("Content-Encoding", "br")
+	case "gzip":
+		body, etag = f.gzip, etag+"-gz"
+		h.Set("Content-Encoding", "gzip")
+	}
+	h.Set("ETag", `"`+etag+`"`)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+}
+
+// acceptedEncoding은 Accept-Encoding이 허용하고 압축본이 있는 인코딩 중 br, gzip 순으로 고른다.
+// 없으면 빈 문자열(원본).
+func acceptedEncoding(header string, hasBr, hasGzip bool) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = f
+			}
+		}
+		accepted[name] = q > 0
+	}
+	ok := func(enc string) bool {
+		if v, listed := accepted[enc]; listed {
+			return v
+		}
+		return accepted["*"]
+	}
+	switch {
+	case hasBr && ok("br"):
+		return "br"
+	case hasGzip && ok("gzip"):
+		return "gzip"
+	}
+	return ""
+}
+
+// loadAssets는 fsys의 파일을 읽어 지문을 붙인다. .go 파일과 압축본(.br, .gz)은 따로 내보내지 않는다.
+func loadAssets(fsys fs.FS) (*assetSet, error) {
+	raw := map[string][]byte{}
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		raw[p] = b
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	set := &assetSet{files: map[string]*asset{}}
+	var pages []string
+	for name, b := range raw {
+		switch path.Ext(name) {
+		case ".go", ".br", ".gz":
+			continue
+		case ".html":
+			// 다른 파일의 지문이 정해진 뒤에 참조를 바꾼다
+			pages = append(pages, name)
+			continue
+		}
+		set.add(newAsset(name, b, raw[name+".br"], raw[name+".gz"]))
+	}
+	for _, name := range pages {
+		b := raw[name]
+		rewritten := b
+		for _, f := range set.files {
+			for _, q := range []string{`"`, `'`} {
+				rewritten = bytes.ReplaceAll(rewritten, []byte(q+"/static/"+f.name+q), []byte(q+"/static/"+f.hashedName+q))
+			}
+		}
+		br, gz := raw[name+".br"], raw[name+".gz"]
+		if !bytes.Equal(rewritten, b) {
+			// 미리 만든 압축본은 바꾸기 전 내용이다
+			br, gz = nil, nil
+		}
+		f := newAsset(name, rewritten, br, gz)
+		set.add(f)
+		if name == "index.html" {
+			set.index = f
+		}
+	}
+	return set, nil
+}
+
+func (s *assetSet) add(f *asset) {
+	s.files[f.name] = f
+	s.files[f.hashedName] = f
+}
+
+func newAsset(name string, body, br, gz []byte) *asset {
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:6])
+	ext := path.Ext(name)
+	ctype := mime.TypeByExtension(ext)
+	if ctype == "" {
+		ctype = http.DetectContentType(body)
+	}
+	if gz == nil && compressible(ctype) {
+		var buf bytes.Buffer
+		zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		zw.Write(body)
+		zw.Close()
+		if buf.Len() < len(body) {
+			gz = buf.Bytes()
+		}
+	}
+	return &asset{
+		name:        name,
+		hashedName:  strings.TrimSuffix(name, ext) + "." + hash + ext,
+		hash:        hash,
+		contentType: ctype,
+		body:        body,
+		gzip:        gz,
+		br:          br,
+	}
+}
+
+func compressible(ctype string) bool {
+	return strings.HasPrefix(ctype, "text/") || strings.Contains(ctype, "javascript") ||
+		strings.Contains(ctype, "json") || strings.Contains(ctype, "svg")
+}
@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAssets(t *testing.T) {
+	js := strings.Repeat("console.log('hello');\n", 20)
+	set, err := loadAssets(fstest.MapFS{
+		"index.html":    {Data: []byte(strings.Repeat("<p>hello</p>\n", 20) + `<script src="/static/app.js"></script>`)},
+		"index.html.br": {Data: []byte("stale")},
+		"app.js":        {Data: []byte(js)},
+		"app.js.br":     {Data: []byte("brotli bytes")},
+		"static.go":     {Data: []byte("package static")},
+		"logo.png":      {Data: []byte("\x89PNG\r\n\x1a\n")},
+	})
+	if err != nil {
+		t.Fatalf("loadAssets: %v", err)
+	}
+	a := &Assets{set: set}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/static/{name...}", a.ServeStatic)
+	mux.HandleFunc("/", a.ServeIndex)
+	get := func(target, encoding string, header ...string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if encoding != "" {
+			req.Header.Set("Accept-Encoding", encoding)
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// index.html의 참조는 지문 넣은 주소로 바뀌고, 바꾸기 전의 압축본은 쓰지 않는다
+	rec := get("/", "br, gzip")
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "no-cache" || rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("index: %d %v", rec.Code, rec.Header())
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	page, _ := io.ReadAll(zr)
+	m := regexp.MustCompile(`/static/(app\.[0-9a-f]{12}\.js)`).FindSubmatch(page)
+	if m == nil {
+		t.Fatalf("index not rewritten: %s", page)
+	}
+	hashed := string(m[1])
+
+	rec = get("/static/"+hashed, "gzip;q=0.5, br")
+	if rec.Code != http.StatusOK || rec.Body.String() != "brotli bytes" || rec.Header().Get("Content-Encoding") != "br" ||
+		rec.Header().Get("Cache-Control") != immutableCacheControl || !strings.Contains(rec.Header().Get("Vary"), "Accept-Encoding") {
+		t.Errorf("hashed br: %d %v %q", rec.Code, rec.Header(), rec.Body)
+	}
+	rec = get("/static/app.js", "")
+	if rec.Body.String() != js || rec.Header().Get("Content-Encoding") != "" || rec.Header().Get("Cache-Control") != "no-cache" ||
+		!strings.HasPrefix(rec.Header().Get("Content-Type"), "text/javascript") {
+		t.Errorf("plain: %v", rec.Header())
+	}
+	if rec := get("/static/app.js", "br;q=0, gzip"); rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("br refused: %v", rec.Header())
+	}
+	if rec := get("/static/app.js", "*"); rec.Header().Get("Content-Encoding") != "br" {
+		t.Errorf("wildcard: %v", rec.Header())
+	}
+	// 압축해도 줄지 않거나 압축할 종류가 아니면 원본만 있다
+	if rec := get("/static/logo.png", "gzip"); rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("png: %d %v", rec.Code, rec.Header())
+	}
+
+	etag := get("/static/app.js", "gzip").Header().Get("ETag")
+	if rec := get("/static/app.js", "gzip", "If-None-Match", etag); rec.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match %s: %d", etag, rec.Code)
+	}
+	for _, name := range []string{"static.go", "app.js.br", "missing.js"} {
+		if rec := get("/static/"+name, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: %d", name, rec.Code)
+		}
+	}
+}
+
+func TestAssetsEmbedded(t *testing.T) {
+	// 바이너리에 넣은 index.html이 main.js를 지문 넣은 주소로 부른다
+	set := NewAssets("").set
+	if set.index == nil || !bytes.Contains(set.index.body, []byte(`"/static/`+set.files["main.js"].hashedName+`"`)) {
+		t.Error("embedded index.html does not reference the fingerprinted main.js")
+	}
+
+	// 개발 모드는 요청마다 디스크에서 다시 읽는다
+	dir := t.TempDir()
+	write := func(body string) {
+		if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dev := NewAssets(dir)
+	for _, body := range []string{"one", "two"} {
+		write(body)
+		rec := httptest.NewRecorder()
+		dev.ServeIndex(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Body.String() != body {
+			t.Errorf("dev index: got %q, want %q", rec.Body, body)
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// API 키 범위. admin은 read를 포함한다.
+const (
+	ScopeRead  = "read"
+	ScopeAdmin = "admin"
+)
+
+var apiKeyScopes = []string{ScopeRead, ScopeAdmin}
+
+// apiKeyPrefix로 시작하는 키만 발급한다. 로그나 설정 파일에 흘린 키를 찾기 쉽게 하려는 것.
+const apiKeyPrefix = "nau_"
+
+// apiKeyCacheTTL 동안은 확인한 키를 DB에 다시 묻지 않는다. 폐기한 키도 늦어도 이 시간 뒤에는 막힌다.
+var apiKeyCacheTTL = time.Minute
+
+// APIKey는 발급한 키의 메타데이터. 키 자체는 발급할 때 한 번만 보여 주고 해시만 저장한다.
+type APIKey struct {
+	Id         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // 키의 앞부분. 어느 키인지 알아보는 용도
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// HasScope는 키가 scope 권한을 가졌는지. admin 키는 read도 할 수 있다.
+func (k APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope) || slices.Contains(k.Scopes, ScopeAdmin)
+}
+
+// ParseAPIKeyScopes는 쉼표로 구분한 범위 목록("read,admin")을 검사해 정해진 순서로 돌려준다.
+func ParseAPIKeyScopes(s string) ([]string, error) {
+	var scopes []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if !slices.Contains(apiKeyScopes, v) {
+			return nil, fmt.Errorf("unknown scope %q (want %s)", v, strings.Join(apiKeyScopes, ", "))
+		}
+		scopes = append(scopes, v)
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	var out []string
+	for _, v := range apiKeyScopes {
+		if slices.Contains(scopes, v) {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+// IssueAPIKey는 새 키를 만들어 저장하고, 다시 볼 수 없는 키 원문을 돌려준다.
+func IssueAPIKey(ctx context.Context, store Store, name string, scopes []string) (string, APIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", APIKey{}, errors.New("name is required")
+	}
+	var b [24]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", APIKey{}, err
+	}
+	secret := apiKeyPrefix + hex.EncodeToString(b[:])
+	k, err := store.CreateAPIKey(ctx, APIKey{
+		Name:   name,
+		Prefix: secret[:len(apiKeyPrefix)+8],
+		Scopes: scopes,
+	}, hashAPIKey(secret))
+	if err != nil {
+		return "", APIKey{}, err
+	}
+	return secret, k, nil
+}
+
+// hashAPIKey는 저장·조회용 해시. 키는 192비트 난수라 느린 해시를 쓸 필요가 없다.
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// requestAPIKey는 Authorization: Bearer 또는 X-API-Key 헤더의 키. 없으면 빈 문자열.
+func requestAPIKey(r *http.Request) string {
+	if v := r.Header.Get("X-API-Key"); v != "" {
+		return strings.TrimSpace(v)
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// requiredScope는 요청에 필요한 범위. 인벤토리는 팀이 쓰는 리소스 목록이라 읽기도 admin 키가 있어야 한다.
+func requiredScope(r *http.Request) string {
+	if r.URL.Path == "/api/inventory" {
+		return ScopeAdmin
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions {
+		return ScopeAdmin
+	}
+	return ScopeRead
+}
+
+// isAuthPublicPath는 API_REQUIRE_KEY여도 키 없이 열리는 경로. 웹 UI 껍데기와 API 문서.
+func isAuthPublicPath(path string) bool {
+	return path == "/" || path == "/api/openapi.json" || strings.HasPrefix(path, "/static/")
+}
+
+type cachedAPIKey struct {
+	key     APIKey
+	expires time.Time
+}
+
+type apiKeyAuth struct {
+	store      Store
+	requireKey bool
+	ipLimiter  *rateLimiter
+	keyLimiter *rateLimiter
+
+	mu    sync.Mutex
+	keys  map[string]cachedAPIKey // 키 해시 → 확인한 키
+	swept time.Time
+}
+
+// AuthMiddleware는 API 키를 확인하고 요청 수를 제한한다.
+//   - GET/HEAD 외의 요청과 인벤토리 조회는 admin 키가 있어야 한다. 다른 읽기는 cfg.RequireKey일 때만 키를 요구한다.
+//   - 키가 있으면 키마다, 없으면 클라이언트 IP(getRealIP)마다 토큰 버킷으로 제한한다.
+//     캐시에 없는 키는 DB에서 확인하기 전에 IP 제한에도 센다.
+//   - 잘못되거나 폐기한 키는 읽기 요청이라도 401로 거부한다.
+//
+// /health는 로드 밸런서가 부르므로 인증과 제한 모두 거치지 않는다.
+func AuthMiddleware(store Store, cfg AuthConfig, next http.Handler) http.Handler {
+	a := &apiKeyAuth{
+		store:      store,
+		requireKey: cfg.RequireKey,
+		ipLimiter:  newRateLimiter(cfg.IPLimit),
+		keyLimiter: newRateLimiter(cfg.KeyLimit),
+		keys:       make(map[string]cachedAPIKey),
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var key *APIKey
+		if secret := requestAPIKey(r); secret != "" {
+			k, ok := a.cached(secret)
+			if !ok {
+				// 캐시에 없는 키는 DB에 물어야 하므로, 없는 키로 DB를 두드리는 요청은 먼저 IP 제한에 센다
+				if !a.allow(w, r, a.ipLimiter, getRealIP(r)) {
+					return
+				}
+				var err error
+				k, err = a.lookup(r.Context(), secret)
+				if errors.Is(err, ErrNotFound) {
+					writeUnauthorized(w, r, "invalid or revoked API key")
+					return
+				}
+				if err != nil {
+					writeServerError(w, r, err)
+					return
+				}
+			}
+			key = &k
+		}
+
+		if key != nil {
+			if !a.allow(w, r, a.keyLimiter, strconv.Itoa(key.Id)) {
+				return
+			}
+		} else if !a.allow(w, r, a.ipLimiter, getRealIP(r)) {
+			return
+		}
+
+		scope := requiredScope(r)
+		switch {
+		case key == nil && (scope == ScopeAdmin || a.requireKey && !isAuthPublicPath(r.URL.Path)):
+			writeUnauthorized(w, r, "API key required")
+			return
+		case key != nil && !key.HasScope(scope):
+			writeAPIError(w, r, http.StatusForbidden, APIError{
+				Code:    "forbidden",
+				Message: fmt.Sprintf("API key lacks the %s scope", scope),
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// cached는 최근에 확인한 키를 캐시에서 찾는다.
+func (a *apiKeyAuth) cached(secret string) (APIKey, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	c, ok := a.keys[hashAPIKey(secret)]
+	if !ok || !time.Now().Before(c.expires) {
+		return APIKey{}, false
+	}
+	return c.key, true
+}
+
+// lookup은 키 원문으로 유효한 키를 DB에서 찾아 캐시에 넣는다. 없거나 폐기됐으면 ErrNotFound.
+func (a *apiKeyAuth) lookup(ctx context.Context, secret string) (APIKey, error) {
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return APIKey{}, ErrNotFound
+	}
+	hash := hashAPIKey(secret)
+	k, err := a.store.UseAPIKey(ctx, hash)
+	if err != nil {
+		return APIKey{}, err
+	}
+	now := time.Now()
+	a.mu.Lock()
+	if now.Sub(a.swept) >= apiKeyCacheTTL {
+		for h, c := range a.keys {
+			if !now.Before(c.expires) {
+				delete(a.keys, h)
+			}
+		}
+		a.swept = now
+	}
+	a.keys[hash] = cachedAPIKey{key: k, expires: now.Add(apiKeyCacheTTL)}
+	a.mu.Unlock()
+	return k, nil
+}
+
+// allow는 l의 bucket에서 요청 하나를 쓴다. 한도를 넘었으면 429를 쓰고 false.
+func (a *apiKeyAuth) allow(w http.ResponseWriter, r *http.Request, l *rateLimiter, bucket string) bool {
+	ok, retryAfter := l.take(bucket, time.Now())
+	if ok {
+		return true
+	}
+	secs := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	writeAPIError(w, r, http.StatusTooManyRequests, APIError{
+		Code:    "rate_limited",
+		Message: fmt.Sprintf("rate limit exceeded; retry after %d seconds", secs),
+	})
+	return false
+}
+
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="noti-aws-update"`)
+	writeAPIError(w, r, http.StatusUnauthorized, APIError{Code: "unauthorized", Message: message})
+}
+
+const apiKeyColumns = `id, name, prefix, scopes, created_at, last_used_at, revoked_at`
+
+func scanPgAPIKey(r rowScanner) (APIKey, error) {
+	var k APIKey
+	err := r.Scan(&k.Id, &k.Name, &k.Prefix, &k.Scopes, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return APIKey{}, ErrNotFound
+	}
+	return k, err
+}
+
+func (s *PgStore) CreateAPIKey(ctx context.Context, k APIKey, keyHash string) (APIKey, error) {
+	k, err := scanPgAPIKey(s.pool.QueryRow(ctx, `
+INSERT INTO api_keys(name, prefix, key_hash, scopes)
+VALUES ($1, $2, $3, $4)
+RETURNING `+apiKeyColumns, k.Name, k.Prefix, keyHash, k.Scopes))
+	if err != nil {
+		return APIKey{}, fmt.Errorf("insert api key: %w", err)
+	}
+	return k, nil
+}
+
+func (s *PgStore) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []APIKey
+	for rows.Next() {
+		k, err := scanPgAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+func (s *PgStore) RevokeAPIKey(ctx context.Context, id int) (APIKey, error) {
+	return scanPgAPIKey(s.pool.QueryRow(ctx, `
+UPDATE api_keys SET revoked_at = COALESCE(revoked_at, now())
+WHERE  id = $1
+RETURNING `+apiKeyColumns, id))
+}
+
+func (s *PgStore) UseAPIKey(ctx context.Context, keyHash string) (APIKey, error) {
+	return scanPgAPIKey(s.pool.QueryRow(ctx, `
+UPDATE api_keys SET last_used_at = now()
+WHERE  key_hash = $1 AND revoked_at IS NULL
+RETURNING `+apiKeyColumns, keyHash))
+}
+
+func scanSQLiteAPIKey(r rowScanner) (APIKey, error) {
+	var (
+		k      APIKey
+		scopes string
+	)
+	err := r.Scan(&k.Id, &k.Name, &k.Prefix, &scopes, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIKey{}, ErrNotFound
+	}
+	if err != nil {
+		return APIKey{}, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &k.Scopes); err != nil {
+		return APIKey{}, fmt.Errorf("api key %d scopes: %w", k.Id, err)
+	}
+	return k, nil
+}
+
+func (s *SQLiteStore) CreateAPIKey(ctx context.Context, k APIKey, keyHash string) (APIKey, error) {
+	scopes, err := json.Marshal(k.Scopes)
+	if err != nil {
+		return APIKey{}, err
+	}
+	k, err = scanSQLiteAPIKey(s.db.QueryRowContext(ctx, `
+INSERT INTO api_keys(name, prefix, key_hash, scopes, created_at)
+VALUES (?, ?, ?, ?, ?)
+RETURNING `+apiKeyColumns, k.Name, k.Prefix, keyHash, string(scopes), time.Now().UTC()))
+	if err != nil {
+		return APIKey{}, fmt.Errorf("insert api key: %w", err)
+	}
+	return k, nil
+}
+
+func (s *SQLiteStore) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []APIKey
+	for rows.Next() {
+		k, err := scanSQLiteAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLiteStore) RevokeAPIKey(ctx context.Context, id int) (APIKey, error) {
+	return scanSQLiteAPIKey(s.db.QueryRowContext(ctx, `
+UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?)
+WHERE  id = ?
+RETURNING `+apiKeyColumns, time.Now().UTC(), id))
+}
+
+func (s *SQLiteStore) UseAPIKey(ctx context.Context, keyHash string) (APIKey, error) {
+	return scanSQLiteAPIKey(s.db.QueryRowContext(ctx, `
+UPDATE api_keys SET last_used_at = ?
+WHERE  key_hash = ? AND revoked_at IS NULL
+RETURNING `+apiKeyColumns, time.Now().UTC(), keyHash))
+}
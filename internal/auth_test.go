@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(RateLimit{PerMinute: 60, Burst: 2})
+	now := mustTime(t, "2024-06-01T00:00:00Z")
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.take("a", now); !ok {
+			t.Fatalf("request %d within burst was limited", i)
+		}
+	}
+	ok, wait := l.take("a", now)
+	if ok || wait != time.Second {
+		t.Errorf("over burst: ok=%v wait=%s, want false 1s", ok, wait)
+	}
+	if ok, _ := l.take("b", now); !ok {
+		t.Error("other bucket was limited")
+	}
+	if ok, _ := l.take("a", now.Add(time.Second)); !ok {
+		t.Error("token not refilled after a second")
+	}
+
+	// 가득 찬 버킷은 정리한다
+	l.take("c", now.Add(2*rateLimitSweepInterval))
+	if _, ok := l.buckets["b"]; ok {
+		t.Error("idle bucket not swept")
+	}
+	if ok, _ := newRateLimiter(RateLimit{}).take("a", now); !ok {
+		t.Error("zero limit should not limit")
+	}
+}
+
+// useCountingStore는 UseAPIKey 호출 수를 센다.
+type useCountingStore struct {
+	Store
+	uses int
+}
+
+func (s *useCountingStore) UseAPIKey(ctx context.Context, keyHash string) (APIKey, error) {
+	s.uses++
+	return s.Store.UseAPIKey(ctx, keyHash)
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	readKey, _, err := IssueAPIKey(ctx, s, "reader", []string{ScopeRead})
+	if err != nil {
+		t.Fatalf("IssueAPIKey: %v", err)
+	}
+	adminKey, admin, err := IssueAPIKey(ctx, s, "ci", []string{ScopeAdmin})
+	if err != nil {
+		t.Fatalf("IssueAPIKey: %v", err)
+	}
+	if !strings.HasPrefix(adminKey, admin.Prefix) || admin.Prefix == adminKey {
+		t.Errorf("prefix %q of key %q", admin.Prefix, adminKey)
+	}
+
+	h := NewHTTPHandler(s, Config{Auth: AuthConfig{
+		IPLimit:  RateLimit{PerMinute: 1, Burst: 3},
+		KeyLimit: RateLimit{PerMinute: 1, Burst: 2},
+	}})
+	do := func(method, target, key, ip string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader("rds\n"))
+		req.RemoteAddr = ip + ":1234"
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	code := func(rec *httptest.ResponseRecorder) string {
+		var e APIError
+		_ = json.Unmarshal(rec.Body.Bytes(), &e)
+		return e.Code
+	}
+
+	// 인벤토리는 읽기도 쓰기도 admin 키로만
+	if rec := do("GET", "/api/inventory", "", "192.0.2.1"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous read: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do("PUT", "/api/inventory", "", "192.0.2.1"); rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("anonymous write: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do("PUT", "/api/inventory", readKey, "192.0.2.2"); rec.Code != http.StatusForbidden || code(rec) != "forbidden" {
+		t.Errorf("read key write: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do("PUT", "/api/inventory", adminKey, "192.0.2.2"); rec.Code != http.StatusOK {
+		t.Errorf("admin write: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do("GET", "/api/inventory", "nau_0000", "192.0.2.3"); rec.Code != http.StatusUnauthorized || code(rec) != "unauthorized" {
+		t.Errorf("unknown key: %d %s", rec.Code, rec.Body)
+	}
+
+	// IP마다, 키마다 따로 센다. 192.0.2.1은 위에서 두 번, admin 키는 한 번 썼다
+	if rec := do("GET", "/api/tags", "", "192.0.2.1"); rec.Code != http.StatusOK {
+		t.Errorf("third anonymous request: %d", rec.Code)
+	}
+	rec := do("GET", "/api/tags", "", "192.0.2.1")
+	if rec.Code != http.StatusTooManyRequests || code(rec) != "rate_limited" || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("over IP limit: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do("GET", "/api/tags", adminKey, "192.0.2.1"); rec.Code != http.StatusOK {
+		t.Errorf("key on a limited IP: %d", rec.Code)
+	}
+	if rec := do("GET", "/api/tags", adminKey, "192.0.2.1"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("over key limit: %d", rec.Code)
+	}
+	if rec := do("GET", "/api/tags", readKey, "192.0.2.1"); rec.Code != http.StatusOK {
+		t.Errorf("other key: %d", rec.Code)
+	}
+	if rec := do("GET", "/health", "", "192.0.2.1"); rec.Code != http.StatusOK {
+		t.Errorf("health is limited: %d", rec.Code)
+	}
+
+	// 폐기한 키는 캐시에 없으면 막힌다
+	if _, err := s.RevokeAPIKey(ctx, admin.Id); err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	h = NewHTTPHandler(s, Config{})
+	if rec := do("GET", "/api/tags", adminKey, "192.0.2.4"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key: %d", rec.Code)
+	}
+	keys, err := s.ListAPIKeys(ctx)
+	if err != nil || len(keys) != 2 || keys[1].RevokedAt == nil || keys[0].LastUsedAt == nil {
+		t.Errorf("ListAPIKeys: %+v, %v", keys, err)
+	}
+
+	// API_REQUIRE_KEY면 읽기에도 키가 필요하다. UI 껍데기와 문서는 열려 있다
+	h = NewHTTPHandler(s, Config{Auth: AuthConfig{RequireKey: true}})
+	if rec := do("GET", "/api/tags", "", "192.0.2.5"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("require key, anonymous read: %d", rec.Code)
+	}
+	if rec := do("GET", "/api/openapi.json", "", "192.0.2.5"); rec.Code != http.StatusOK {
+		t.Errorf("require key, openapi.json: %d", rec.Code)
+	}
+	if rec := do("GET", "/api/tags", readKey, "192.0.2.5"); rec.Code != http.StatusOK {
+		t.Errorf("require key, read key: %d", rec.Code)
+	}
+	if rec := do("GET", "/api/inventory", readKey, "192.0.2.5"); rec.Code != http.StatusForbidden || code(rec) != "forbidden" {
+		t.Errorf("require key, read key on inventory: %d %s", rec.Code, rec.Body)
+	}
+
+	// 캐시에 없는 키는 IP 제한을 넘으면 DB에 묻지 않고, 확인한 키는 다시 묻지 않는다
+	cs := &useCountingStore{Store: s}
+	h = NewHTTPHandler(cs, Config{Auth: AuthConfig{IPLimit: RateLimit{PerMinute: 1, Burst: 2}}})
+	for i := 0; i < 4; i++ {
+		rec = do("GET", "/api/tags", "nau_bogus"+strconv.Itoa(i), "192.0.2.6")
+	}
+	if rec.Code != http.StatusTooManyRequests || cs.uses != 2 {
+		t.Errorf("unknown keys over IP limit: %d, %d lookups", rec.Code, cs.uses)
+	}
+	for i := 0; i < 3; i++ {
+		rec = do("GET", "/api/tags", readKey, "192.0.2.7")
+	}
+	if rec.Code != http.StatusOK || cs.uses != 3 {
+		t.Errorf("cached key: %d, %d lookups", rec.Code, cs.uses)
+	}
+}
+
+func TestParseAPIKeyScopes(t *testing.T) {
+	if got, err := ParseAPIKeyScopes("admin, read,admin"); err != nil || strings.Join(got, ",") != "read,admin" {
+		t.Errorf("ParseAPIKeyScopes: %v, %v", got, err)
+	}
+	for _, bad := range []string{"", "write", " , "} {
+		if _, err := ParseAPIKeyScopes(bad); err == nil {
+			t.Errorf("ParseAPIKeyScopes(%q): no error", bad)
+		}
+	}
+}
+
+func TestLoadAuthConfigRequireKey(t *testing.T) {
+	for v, want := range map[string]bool{"": false, "false": false, "true": true, "1": true, "yes": true} {
+		t.Setenv("API_REQUIRE_KEY", v)
+		if got := loadAuthConfig().RequireKey; got != want {
+			t.Errorf("API_REQUIRE_KEY=%q: got %v, want %v", v, got, want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type AwsItem struct {
+	Id               string         `json:"id"`
+	AdditionalFields map[string]any `json:"additionalFields"`
+}
+type AwsTag struct {
+	Id             string `json:"id"`             // whats-new-v2#general-products#amazon-ec2
+	Name           string `json:"name"`           // Amazon EC2
+	TagNamespaceId string `json:"tagNamespaceId"` // whats-new-v2#general-products
+}
+type AwsApiItem struct {
+	Item AwsItem  `json:"item"`
+	Tags []AwsTag `json:"tags"`
+
+	// Raw는 API가 돌려준 원본 JSON. 구조체에 없는 필드까지 그대로 보관하기 위해 쓴다.
+	Raw json.RawMessage `json:"-"`
+}
+
+type AwsApiResponse struct {
+	Items    []AwsApiItem `json:"items"`
+	Metadata struct {
+		Count int `json:"count"`
+	} `json:"metadata"`
+}
+
+func (el *AwsApiItem) UnmarshalJSON(b []byte) error {
+	type plain AwsApiItem
+	if err := json.Unmarshal(b, (*plain)(el)); err != nil {
+		return err
+	}
+	el.Raw = append(json.RawMessage(nil), b...)
+	return nil
+}
+
+// payload는 저장할 원본 JSON을 돌려준다. API에서 받은 것이 아니면 구조체를 직렬화한다.
+func (el AwsApiItem) payload() ([]byte, error) {
+	if len(el.Raw) > 0 {
+		return el.Raw, nil
+	}
+	return json.Marshal(el)
+}
+
+// parseAwsPayload는 저장된 원본 JSON을 다시 AwsApiItem으로 읽는다.
+func parseAwsPayload(b []byte) (AwsApiItem, error) {
+	var el AwsApiItem
+	err := json.Unmarshal(b, &el)
+	return el, err
+}
+
+// awsItemFields는 저장 대상 필드를 AdditionalFields에서 꺼낸다.
+func awsItemFields(el AwsApiItem) (title, body, url string, sourceTime *time.Time) {
+	title, _ = el.Item.AdditionalFields["headline"].(string)
+	body, _ = el.Item.AdditionalFields["postBody"].(string)
+	url, _ = el.Item.AdditionalFields["headlineUrl"].(string)
+	sourceTimeStr, _ := el.Item.AdditionalFields["postDateTime"].(string)
+	if sourceTimeStr != "" {
+		t, err := time.Parse(time.RFC3339, sourceTimeStr)
+		if err == nil {
+			sourceTime = &t
+		}
+	}
+	return title, body, url, sourceTime
+}
+
+// awsTagNamespace는 태그 네임스페이스에서 디렉터리 접두어를 뗀 이름을 돌려준다.
+// (whats-new-v2#general-products -> general-products)
+func awsTagNamespace(tag AwsTag) string {
+	ns := tag.TagNamespaceId
+	if ns == "" {
+		// tagNamespaceId가 없으면 태그 id의 마지막 구간을 제외한 부분을 쓴다
+		if i := strings.LastIndex(tag.Id, "#"); i > 0 {
+			ns = tag.Id[:i]
+		}
+	}
+	if i := strings.LastIndex(ns, "#"); i >= 0 {
+		ns = ns[i+1:]
+	}
+	return ns
+}
+
+func ParseUntilExisting(ctx context.Context, store Store, pageSize int) error {
+	directoryID := "whats-new-v2"
+	baseUrl := "https://aws.amazon.com/api/dirs/items/search"
+	page := 0
+	for {
+		reqUrl := fmt.Sprintf("%s?item.directoryId=%s&sort_by=item.additionalFields.postDateTime&sort_order=desc&size=%d&page=%d&item.locale=en_US",
+			baseUrl, directoryID, pageSize, page)
+
+		req, err := http.NewRequestWithContext(ctx, "GET", reqUrl, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != 200 {
+			b, _ := io.ReadAll(resp.Body)
+			return fmt.Errorf("api error: %v, %s", resp.Status, string(b))
+		}
+
+		var apiResp AwsApiResponse
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			resp.Body.Close()
+			return err
+		}
+		resp.Body.Close()
+
+		if len(apiResp.Items) == 0 {
+			break
+		}
+
+		for _, el := range apiResp.Items {
+			found, err := store.SourceIdExists(ctx, el.Item.Id)
+			if err != nil {
+				return err
+			}
+			if found {
+				log.Printf("source_id %s already exists; stop", el.Item.Id)
+				return nil
+			}
+			if err := store.InsertAwsItem(ctx, el); err != nil {
+				log.Printf("Failed insert %s: %v", el.Item.Id, err)
+				return err
+			}
+			log.Printf("Inserted source_id %s, headline='%s'", el.Item.Id, el.Item.AdditionalFields["headline"])
+		}
+		if len(apiResp.Items) < pageSize {
+			break
+		}
+		page++
+	}
+	return nil
+}
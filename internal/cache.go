@@ -0,0 +1,292 @@
+package internal
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultCacheEntries는 응답 캐시에 두는 최대 응답 수
+	defaultCacheEntries = 1000
+	// maxCachedBodyBytes보다 큰 응답은 캐시하지 않는다
+	maxCachedBodyBytes = 2 << 20
+)
+
+// cacheVersionCheckInterval마다 한 번 data_version을 읽는다. 수집 후 이 시간 안에는
+// 이전 응답이 나갈 수 있다.
+var cacheVersionCheckInterval = time.Second
+
+// dataVersionTables는 바뀌면 data_version을 올리는 테이블. initdb/init.sql의 트리거와 같은 목록이다.
+var dataVersionTables = []string{
+	"tags", "whatsnews", "whatsnews_tags", "tag_stats", "whatsnews_regions",
+	"services", "whatsnews_services", "inventory_services", "whatsnews_merges",
+}
+
+// DataVersion은 API가 읽는 데이터의 버전. 수집·재처리·인벤토리 교체 등 어느 프로세스가 쓰든
+// DB 트리거가 올리므로, HTTP 서버는 이 값만 보고 캐시를 무효화한다.
+type DataVersion struct {
+	Version    int64
+	ModifiedAt time.Time
+}
+
+func (s *PgStore) DataVersion(ctx context.Context) (DataVersion, error) {
+	var v DataVersion
+	err := s.pool.QueryRow(ctx, `SELECT version, modified_at FROM data_version WHERE id = 1`).Scan(&v.Version, &v.ModifiedAt)
+	v.ModifiedAt = v.ModifiedAt.UTC()
+	return v, err
+}
+
+// CacheStats는 /api/cache/stats 응답.
+type CacheStats struct {
+	Entries       int     `json:"entries"`
+	Bytes         int64   `json:"bytes"`
+	Hits          uint64  `json:"hits"`
+	Misses        uint64  `json:"misses"`
+	HitRatio      float64 `json:"hit_ratio"`
+	NotModified   uint64  `json:"not_modified"` // 304로 답한 조건부 요청
+	Invalidations uint64  `json:"invalidations"`
+	Evictions     uint64  `json:"evictions"`
+	DataVersion   int64   `json:"data_version"`
+}
+
+type cachedResponse struct {
+	key         string
+	version     int64
+	contentType string
+	etag        string
+	body        []byte
+}
+
+// ResponseCache는 GET 응답을 프로세스 메모리에 두는 LRU 캐시. 항목은 만들 때의 데이터 버전을
+// 달고 있고, 버전이 바뀌면 전부 버린다. 캐시 여부와 관계없이 응답에 ETag/Last-Modified를 붙이고
+// 조건부 요청에는 304로 답한다.
+type ResponseCache struct {
+	store      Store
+	maxEntries int
+
+	mu        sync.Mutex
+	lru       *list.List // 앞쪽이 최근에 쓴 항목, 값은 *cachedResponse
+	entries   map[string]*list.Element
+	bytes     int64
+	version   DataVersion
+	checkedAt time.Time
+
+	hits, misses, notModified, invalidations, evictions atomic.Uint64
+}
+
+func NewResponseCache(store Store, maxEntries int) *ResponseCache {
+	return &ResponseCache{
+		store:      store,
+		maxEntries: maxEntries,
+		lru:        list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+// currentVersion은 cacheVersionCheckInterval마다 data_version을 다시 읽고, 바뀌었으면 캐시를 비운다.
+func (c *ResponseCache) currentVersion(ctx context.Context) (DataVersion, error) {
+	c.mu.Lock()
+	if !c.checkedAt.IsZero() && time.Since(c.checkedAt) < cacheVersionCheckInterval {
+		v := c.version
+		c.mu.Unlock()
+		return v, nil
+	}
+	c.mu.Unlock()
+
+	v, err := c.store.DataVersion(ctx)
+	if err != nil {
+		return DataVersion{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v.Version != c.version.Version && len(c.entries) > 0 {
+		c.lru.Init()
+		c.entries = map[string]*list.Element{}
+		c.bytes = 0
+		c.invalidations.Add(1)
+	}
+	c.version, c.checkedAt = v, time.Now()
+	return v, nil
+}
+
+func (c *ResponseCache) get(key string, version int64) *cachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*cachedResponse)
+	if e.version != version {
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	return e
+}
+
+func (c *ResponseCache) put(e *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 응답을 만드는 동안 버전이 바뀌었으면 이미 낡은 응답이다
+	if e.version != c.version.Version {
+		return
+	}
+	if el, ok := c.entries[e.key]; ok {
+		c.bytes -= int64(len(el.Value.(*cachedResponse).body))
+		c.lru.Remove(el)
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+	c.bytes += int64(len(e.body))
+	for c.lru.Len() > c.maxEntries {
+		el := c.lru.Back()
+		old := el.Value.(*cachedResponse)
+		c.lru.Remove(el)
+		delete(c.entries, old.key)
+		c.bytes -= int64(len(old.body))
+		c.evictions.Add(1)
+	}
+}
+
+// Stats는 캐시 통계. c가 nil이면 빈 통계.
+func (c *ResponseCache) Stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	c.mu.Lock()
+	st := CacheStats{Entries: c.lru.Len(), Bytes: c.bytes, DataVersion: c.version.Version}
+	c.mu.Unlock()
+	st.Hits, st.Misses = c.hits.Load(), c.misses.Load()
+	st.NotModified = c.notModified.Load()
+	st.Invalidations, st.Evictions = c.invalidations.Load(), c.evictions.Load()
+	if total := st.Hits + st.Misses; total > 0 {
+		st.HitRatio = float64(st.Hits) / float64(total)
+	}
+	return st
+}
+
+// cacheKey는 응답을 구별하는 키. 피드처럼 요청 주소로 절대 URL을 만드는 응답이 있어
+// requestBaseURL도 넣는다. 쿼리는 키 순서로 정렬한다.
+func cacheKey(r *http.Request) string {
+	return strings.Join([]string{requestBaseURL(r), r.URL.Path, r.URL.Query().Encode()}, "\x00")
+}
+
+// strongETag는 본문 내용으로 만든 강한 ETag.
+func strongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:18]) + `"`
+}
+
+// Handler는 cacheable이 참인 GET 요청을 캐시한다. 200이 아닌 응답과
+// maxCachedBodyBytes보다 큰 응답은 캐시하지 않는다. c가 nil이면 next를 그대로 돌려준다.
+// 라우트가 GET만 받으므로 HEAD도 캐시를 거치지 않아야 적중 여부와 관계없이 405로 같다.
+func (c *ResponseCache) Handler(next http.Handler, cacheable func(r *http.Request) bool) http.Handler {
+	if c == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !cacheable(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		v, err := c.currentVersion(r.Context())
+		if err != nil {
+			// 버전을 모르면 캐시하지 않는다
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := cacheKey(r)
+		if e := c.get(key, v.Version); e != nil {
+			c.hits.Add(1)
+			w.Header().Set("X-Cache", "HIT")
+			c.write(w, r, e, v.ModifiedAt)
+			return
+		}
+		c.misses.Add(1)
+
+		rec := &bufferedResponseWriter{header: http.Header{}, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		for k, vs := range rec.header {
+			w.Header()[k] = vs
+		}
+		w.Header().Set("X-Cache", "MISS")
+		if rec.status != http.StatusOK {
+			w.WriteHeader(rec.status)
+			w.Write(rec.body.Bytes())
+			return
+		}
+		e := &cachedResponse{
+			key:         key,
+			version:     v.Version,
+			contentType: rec.header.Get("Content-Type"),
+			etag:        strongETag(rec.body.Bytes()),
+			body:        rec.body.Bytes(),
+		}
+		if len(e.body) <= maxCachedBodyBytes {
+			c.put(e)
+		}
+		c.write(w, r, e, v.ModifiedAt)
+	})
+}
+
+// write는 검증자 헤더를 붙여 응답하고, 조건부 요청이 맞으면 304로 답한다.
+// If-None-Match가 있으면 If-Modified-Since는 보지 않는다 (RFC 9110 13.2.2).
+func (c *ResponseCache) write(w http.ResponseWriter, r *http.Request, e *cachedResponse, modifiedAt time.Time) {
+	h := w.Header()
+	h.Set("Content-Type", e.contentType)
+	h.Set("ETag", e.etag)
+	h.Set("Cache-Control", "no-cache")
+	lastModified := modifiedAt.UTC().Truncate(time.Second)
+	if !modifiedAt.IsZero() {
+		h.Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+
+	notModified := false
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		notModified = etagMatches(inm, e.etag)
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modifiedAt.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !lastModified.After(t) {
+			notModified = true
+		}
+	}
+	if notModified {
+		c.notModified.Add(1)
+		h.Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Write(e.body)
+}
+
+// etagMatches는 If-None-Match 목록에 etag가 있는지 약한 비교로 본다.
+func etagMatches(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedResponseWriter는 캐시에 넣기 위해 응답을 메모리에 모은다.
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponseWriter) Header() http.Header { return b.header }
+
+func (b *bufferedResponseWriter) WriteHeader(code int) { b.status = code }
+
+func (b *bufferedResponseWriter) Write(p []byte) (int, error) { return b.body.Write(p) }
@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSQLiteStoreDataVersion(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	before, err := s.DataVersion(ctx)
+	if err != nil {
+		t.Fatalf("DataVersion: %v", err)
+	}
+	if before.ModifiedAt.IsZero() {
+		t.Error("ModifiedAt is zero")
+	}
+	if err := s.InsertAwsItem(ctx, awsTestItem("a", "Amazon EC2 adds instances", "2024-06-01T00:00:00Z", "Amazon EC2")); err != nil {
+		t.Fatalf("InsertAwsItem: %v", err)
+	}
+	after, err := s.DataVersion(ctx)
+	if err != nil {
+		t.Fatalf("DataVersion: %v", err)
+	}
+	if after.Version <= before.Version {
+		t.Errorf("version: got %d after insert, was %d", after.Version, before.Version)
+	}
+	if after.ModifiedAt.Before(before.ModifiedAt) {
+		t.Errorf("modified_at went back: %v -> %v", before.ModifiedAt, after.ModifiedAt)
+	}
+
+	// 바뀐 것이 없는 재계산·동기화는 버전을 올리지 않는다 (스케줄러가 매번 부른다)
+	if err := s.RebuildTagStats(ctx); err != nil {
+		t.Fatalf("RebuildTagStats: %v", err)
+	}
+	if err := s.SyncServices(ctx); err != nil {
+		t.Fatalf("SyncServices: %v", err)
+	}
+	if v, _ := s.DataVersion(ctx); v.Version != after.Version {
+		t.Errorf("version: got %d after no-op rebuild, want %d", v.Version, after.Version)
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	defer func(d time.Duration) { cacheVersionCheckInterval = d }(cacheVersionCheckInterval)
+	cacheVersionCheckInterval = 0
+
+	ctx := context.Background()
+	s := newTestStore(t)
+	if err := s.InsertAwsItem(ctx, awsTestItem("a", "Amazon EC2 adds instances", "2024-06-01T00:00:00Z", "Amazon EC2")); err != nil {
+		t.Fatalf("InsertAwsItem: %v", err)
+	}
+	h := NewHTTPHandler(s, Config{})
+
+	get := func(target string, header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	itemCount := func(rec *httptest.ResponseRecorder) int {
+		t.Helper()
+		var res WhatsNewsResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return len(res.Items)
+	}
+
+	first := get("/api/whatsnews?limit=5&sort=newest", nil)
+	if first.Code != http.StatusOK || first.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("first: got %d X-Cache=%q", first.Code, first.Header().Get("X-Cache"))
+	}
+	etag, lastModified := first.Header().Get("ETag"), first.Header().Get("Last-Modified")
+	if etag == "" || etag[0] != '"' || lastModified == "" {
+		t.Fatalf("validators: ETag=%q Last-Modified=%q", etag, lastModified)
+	}
+
+	// 쿼리 순서가 달라도 같은 항목
+	second := get("/api/whatsnews?sort=newest&limit=5", nil)
+	if second.Header().Get("X-Cache") != "HIT" || second.Body.String() != first.Body.String() {
+		t.Errorf("second: X-Cache=%q", second.Header().Get("X-Cache"))
+	}
+	if second.Header().Get("ETag") != etag {
+		t.Errorf("ETag changed on hit: %q -> %q", etag, second.Header().Get("ETag"))
+	}
+
+	if rec := get("/api/whatsnews?limit=5&sort=newest", http.Header{"If-None-Match": {`"other", ` + etag}}); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("If-None-Match: got %d with %d bytes", rec.Code, rec.Body.Len())
+	}
+	if rec := get("/api/whatsnews?limit=5&sort=newest", http.Header{"If-None-Match": {`"other"`}}); rec.Code != http.StatusOK {
+		t.Errorf("stale If-None-Match: got %d", rec.Code)
+	}
+	if rec := get("/api/whatsnews?limit=5&sort=newest", http.Header{"If-Modified-Since": {lastModified}}); rec.Code != http.StatusNotModified {
+		t.Errorf("If-Modified-Since: got %d", rec.Code)
+	}
+
+	// 400/404는 캐시하지 않는다
+	get("/api/whatsnews/999", nil)
+	if rec := get("/api/whatsnews/999", nil); rec.Code != http.StatusNotFound || rec.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("not found: got %d X-Cache=%q", rec.Code, rec.Header().Get("X-Cache"))
+	}
+	// HEAD는 캐시에 든 응답이 있어도 GET만 받는 라우트가 답한다
+	head := httptest.NewRecorder()
+	h.ServeHTTP(head, httptest.NewRequest(http.MethodHead, "/api/whatsnews?limit=5&sort=newest", nil))
+	if head.Code != http.StatusMethodNotAllowed || head.Header().Get("X-Cache") != "" {
+		t.Errorf("HEAD after a cached GET: got %d X-Cache=%q", head.Code, head.Header().Get("X-Cache"))
+	}
+	// 캐시 대상이 아닌 라우트
+	if rec := get("/health", nil); rec.Header().Get("X-Cache") != "" || rec.Header().Get("ETag") != "" {
+		t.Errorf("/health went through the cache")
+	}
+
+	// 수집이 데이터를 바꾸면 다음 요청은 새로 만든다
+	if err := s.InsertAwsItem(ctx, awsTestItem("b", "Amazon RDS supports engine", "2024-06-02T00:00:00Z", "Amazon RDS")); err != nil {
+		t.Fatalf("InsertAwsItem: %v", err)
+	}
+	third := get("/api/whatsnews?limit=5&sort=newest", http.Header{"If-None-Match": {etag}})
+	if third.Code != http.StatusOK || third.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("after insert: got %d X-Cache=%q", third.Code, third.Header().Get("X-Cache"))
+	}
+	if n := itemCount(third); n != 2 {
+		t.Errorf("after insert: got %d items, want 2", n)
+	}
+	if third.Header().Get("ETag") == etag {
+		t.Error("ETag unchanged after insert")
+	}
+
+	var st CacheStats
+	if err := json.Unmarshal(get("/api/cache/stats", nil).Body.Bytes(), &st); err != nil {
+		t.Fatalf("decode stats: %v", err)
+	}
+	if st.Hits != 4 || st.Misses != 4 || st.NotModified != 2 || st.Invalidations != 1 || st.Entries != 1 {
+		t.Errorf("stats: %+v", st)
+	}
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	s := newTestStore(t)
+	c := NewResponseCache(s, 2)
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}), func(*http.Request) bool { return true })
+
+	for _, p := range []string{"/a", "/b", "/a", "/c", "/a", "/b"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, p, nil))
+	}
+	// /b는 /c가 들어올 때 밀려났고, /a는 최근에 쓰여 남는다
+	st := c.Stats()
+	if st.Hits != 2 || st.Misses != 4 || st.Evictions != 2 || st.Entries != 2 {
+		t.Errorf("stats: %+v", st)
+	}
+}
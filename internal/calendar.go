@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// calendarDefaultLimit는 limit 파라미터가 없을 때 넣는 발표 수 (limit의 최댓값)
+	calendarDefaultLimit = 100
+	calendarContentType  = "text/calendar; charset=utf-8"
+	calendarProductID    = "-//noti-aws-update//calendar//KO"
+	// 캘린더 앱이 구독을 다시 받아 오는 간격
+	calendarRefresh = "PT1H"
+)
+
+// CalendarEvent는 하루 종일 일정 하나. Date는 일정 날짜만 의미가 있다.
+type CalendarEvent struct {
+	UID         string
+	Date        time.Time
+	Summary     string
+	Description string
+	URL         string
+	Categories  []string
+	Modified    time.Time
+}
+
+// Calendar는 iCalendar(RFC 5545) 구독 피드. 지금은 발표 날짜만 싣는다. 뉴스레터의
+// Upcoming Launches 표는 서비스와 기능만 있고 예정일이 없어 아직 수집하지 않는다.
+type Calendar struct {
+	Name   string
+	Events []CalendarEvent
+}
+
+// announcementEvents는 발표를 loc 기준 발표 날짜(source_created_at)의 일정으로 만든다.
+// 발표 시각이 없는 레코드는 뺀다.
+func announcementEvents(f Feed, loc *time.Location) []CalendarEvent {
+	var events []CalendarEvent
+	for _, it := range f.Items {
+		if it.SourceCreatedAt == nil {
+			continue
+		}
+		desc := entrySummary(it)
+		if desc != "" {
+			desc += "\n\n"
+		}
+		desc += f.permalinkURL(it.Id)
+		ev := CalendarEvent{
+			UID:         feedEntryID(it),
+			Date:        it.SourceCreatedAt.In(loc),
+			Summary:     it.Title,
+			Description: desc,
+			URL:         f.entryURL(it),
+			Modified:    feedEntryUpdated(it),
+		}
+		for _, t := range it.Tags {
+			ev.Categories = append(ev.Categories, t.Name)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+// Write는 캘린더를 text/calendar 형식으로 쓴다.
+func (c Calendar) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeICalLine(bw, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", calendarProductID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("X-WR-CALNAME", escapeICalText(c.Name))
+	line("X-PUBLISHED-TTL", calendarRefresh)
+	line("REFRESH-INTERVAL;VALUE=DURATION", calendarRefresh)
+	for _, ev := range c.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", escapeICalText(ev.UID))
+		line("DTSTAMP", ev.Modified.UTC().Format("20060102T150405Z"))
+		line("LAST-MODIFIED", ev.Modified.UTC().Format("20060102T150405Z"))
+		line("DTSTART;VALUE=DATE", ev.Date.Format("20060102"))
+		line("DTEND;VALUE=DATE", ev.Date.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY", escapeICalText(ev.Summary))
+		if ev.Description != "" {
+			line("DESCRIPTION", escapeICalText(ev.Description))
+		}
+		if ev.URL != "" {
+			line("URL;VALUE=URI", ev.URL)
+		}
+		if len(ev.Categories) > 0 {
+			cats := make([]string, len(ev.Categories))
+			for i, c := range ev.Categories {
+				cats[i] = escapeICalText(c)
+			}
+			line("CATEGORIES", strings.Join(cats, ","))
+		}
+		// 하루 종일 일정이 바쁨으로 표시되지 않게 한다
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+var icalTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escapeICalText는 TEXT 값의 특수 문자를 이스케이프한다 (RFC 5545 3.3.11).
+func escapeICalText(s string) string {
+	return icalTextEscaper.Replace(s)
+}
+
+// writeICalLine은 한 줄을 CRLF로 끝내고, 75옥텟을 넘으면 UTF-8 문자 중간이 아닌 곳에서
+// 접어 다음 줄을 공백으로 시작한다 (RFC 5545 3.1).
+func writeICalLine(w *bufio.Writer, s string) {
+	const max = 75
+	width := max
+	for len(s) > width {
+		cut := width
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		fmt.Fprintf(w, "%s\r\n ", s[:cut])
+		s = s[cut:]
+		// 이어지는 줄은 맨 앞 공백도 75옥텟에 들어간다
+		width = max - 1
+	}
+	fmt.Fprintf(w, "%s\r\n", s)
+}
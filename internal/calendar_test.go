@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendar(t *testing.T) {
+	f := testFeed(t)
+	f.Items = append(f.Items, WhatsNews{Id: 11, Title: "No date"})
+	f.Items[0].Title = "RDS; now with commas, backslashes \\ and a very long title that has to be folded — 긴 제목"
+	seoul, err := time.LoadLocation("Asia/Seoul")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := (Calendar{Name: feedTitle, Events: announcementEvents(f, seoul)}).Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	out := buf.String()
+	for _, line := range strings.SplitAfter(out, "\r\n") {
+		if len(line) > 75+2 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+		if strings.ContainsRune(strings.TrimSuffix(line, "\r\n"), '\n') {
+			t.Errorf("bare newline: %q", line)
+		}
+	}
+	// 접힌 줄을 펴면 원래 값이 나와야 한다
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+
+	// 발표 시각이 없는 레코드는 빠진다
+	if n := strings.Count(unfolded, "BEGIN:VEVENT"); n != 2 {
+		t.Fatalf("events: got %d\n%s", n, unfolded)
+	}
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"X-WR-CALNAME:AWS 뉴스\r\n",
+		"UID:urn:noti-aws-update:aws-api:whats-new-v2%23rds\r\n",
+		// 2024-06-01T20:00Z는 서울 기준 6월 2일
+		"DTSTART;VALUE=DATE:20240602\r\nDTEND;VALUE=DATE:20240603\r\n",
+		"DTSTAMP:20240603T093000Z\r\n",
+		`SUMMARY:RDS\; now with commas\, backslashes \\ and a very long title that has to be folded — 긴 제목` + "\r\n",
+		`DESCRIPTION:RDS & more\n\nhttps://news.example.com/whatsnews/7` + "\r\n",
+		"URL;VALUE=URI:https://aws.amazon.com/about-aws/whats-new/2024/06/rds/\r\n",
+		"CATEGORIES:Amazon RDS\r\n",
+		// 원문 URL이 없으면 퍼머링크
+		"URL;VALUE=URI:https://news.example.com/whatsnews/9\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(unfolded, want) {
+			t.Errorf("missing %q in\n%s", want, unfolded)
+		}
+	}
+}
@@ -0,0 +1,485 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+/*
+출처 간 중복 제거
+-----------------
+같은 발표가 AWS API, 주간 메일의 What's New 표, RSS 피드로 각각 들어온다.
+새 항목이 들어오면 아래 순서로 기존 whatsnews 레코드와 같은 발표인지 판단한다.
+
+  1. 정규화한 URL(canonical_url)이 같으면 병합 (method=url)
+  2. 발표일 ±canonicalTitleWindow 안에서 제목 유사도가 canonicalTitleThreshold 이상이고
+     서로 다른 단어가 titleFillerWords뿐이면 병합 (method=title)
+
+리전이나 인스턴스 타입만 다른 발표("... in Seoul" / "... in Sydney")는 제목 유사도가 높으므로
+2번에서 다른 단어 검사로 걸러낸다.
+
+같은 출처끼리는 병합하지 않는다. API 항목 두 개는 id가 다르면 서로 다른 발표다.
+API 항목이 메일/RSS로 먼저 만들어진 레코드에 병합되면 레코드 내용을 API 값으로 바꾸고,
+기존 출처를 병합된 출처로 옮긴다. 병합 결정은 whatsnews_merges에 남고 되돌릴 수 있다.
+*/
+
+// 출처 종류
+const (
+	SourceAwsApi = "aws-api"
+	SourceMail   = "mail"
+	SourceRSS    = "rss"
+)
+
+// 병합 판단 방식
+const (
+	MergeByURL   = "url"
+	MergeByTitle = "title"
+)
+
+const (
+	canonicalTitleThreshold = 0.85
+	canonicalTitleWindow    = 3 * 24 * time.Hour
+)
+
+// SourceItem은 출처와 무관하게 정규화 단계에 들어오는 발표 하나.
+type SourceItem struct {
+	Type        string
+	SourceId    string // 출처 안에서의 식별자 (API item id, RSS guid, 메일은 정규화 URL)
+	Title       string
+	Content     string
+	Url         string
+	PublishedAt *time.Time
+}
+
+// recordSourceId는 whatsnews.source_id에 저장할 값. API 항목은 기존과 같이 id 그대로,
+// 나머지는 출처 종류를 접두어로 붙여 API id와 겹치지 않게 한다.
+func recordSourceId(sourceType, sourceId string) string {
+	if sourceType == SourceAwsApi || sourceType == "" {
+		return sourceId
+	}
+	return sourceType + ":" + sourceId
+}
+
+// NewsSource는 whatsnews 레코드를 구성하는 출처 하나.
+type NewsSource struct {
+	Type     string `json:"type"`
+	SourceId string `json:"source_id"`
+	Url      string `json:"url,omitempty"`
+}
+
+// primaryNewsSource는 whatsnews 행 자체의 출처를 NewsSource로 만든다.
+func primaryNewsSource(sourceType, recordId, url string) NewsSource {
+	if sourceType == "" {
+		sourceType = SourceAwsApi
+	}
+	return NewsSource{
+		Type:     sourceType,
+		SourceId: strings.TrimPrefix(recordId, sourceType+":"),
+		Url:      url,
+	}
+}
+
+// MergeDecision은 whatsnews_merges 한 행. 병합된 출처의 원래 값을 보관해 되돌릴 때 쓴다.
+type MergeDecision struct {
+	Id              int        `json:"id"`
+	WhatsnewId      int        `json:"whatsnew_id"`
+	SourceType      string     `json:"source_type"`
+	SourceId        string     `json:"source_id"`
+	Title           string     `json:"title"`
+	Url             string     `json:"url"`
+	SourceCreatedAt *time.Time `json:"source_created_at"`
+	Method          string     `json:"method"`
+	Score           float64    `json:"score"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UndoneAt        *time.Time `json:"undone_at,omitempty"`
+	SplitWhatsnewId *int       `json:"split_whatsnew_id,omitempty"` // 되돌린 뒤 분리된 레코드
+}
+
+// mergeWithContent는 되돌릴 때 분리 레코드의 본문으로 쓸 content까지 읽은 병합 결정.
+type mergeWithContent struct {
+	MergeDecision
+	Content string
+}
+
+// MergesQuery는 ListMerges의 조건.
+type MergesQuery struct {
+	Limit         int
+	Offset        int
+	WhatsnewId    int  // 0이면 전체
+	IncludeUndone bool // 되돌린 결정도 포함
+}
+
+// IngestResult는 Store.IngestSourceItem의 결과.
+type IngestResult struct {
+	WhatsnewId int    `json:"whatsnew_id"`
+	Created    bool   `json:"created"`          // 새 레코드를 만들었음
+	Merged     bool   `json:"merged"`           // 기존 레코드에 병합했음
+	Method     string `json:"method,omitempty"` // 병합 방식
+	Skipped    bool   `json:"skipped"`          // 이미 들어온 출처
+}
+
+// canonCandidate는 제목 비교 대상 레코드.
+type canonCandidate struct {
+	Id         int
+	Title      string
+	SourceType string
+}
+
+// pickByTitle은 출처 종류가 다른 후보 중 제목이 가장 비슷한 것을 고른다. 기준 미달이면 id=0.
+func pickByTitle(title, sourceType string, cands []canonCandidate) (id int, score float64) {
+	for _, c := range cands {
+		if c.SourceType == sourceType {
+			continue
+		}
+		s := titleSimilarity(title, c.Title)
+		if s >= canonicalTitleThreshold && s > score && onlyFillerDiffers(title, c.Title) {
+			id, score = c.Id, s
+		}
+	}
+	return id, score
+}
+
+// titleFillerWords는 같은 발표의 제목이 출처마다 다르게 쓰일 때 흔히 더하거나 빠지는 단어.
+var titleFillerWords = map[string]bool{
+	"a": true, "an": true, "the": true, "is": true, "are": true, "now": true, "in": true,
+	"of": true, "for": true, "and": true, "with": true, "aws": true, "amazon": true,
+	"region": true, "regions": true, "generally": true, "available": true,
+}
+
+// onlyFillerDiffers는 두 제목에서 한쪽에만 있는 단어가 모두 titleFillerWords인지 확인한다.
+func onlyFillerDiffers(a, b string) bool {
+	wa, wb := map[string]bool{}, map[string]bool{}
+	for _, w := range searchWords(a) {
+		wa[w] = true
+	}
+	for _, w := range searchWords(b) {
+		wb[w] = true
+	}
+	for w := range wa {
+		if !wb[w] && !titleFillerWords[w] {
+			return false
+		}
+	}
+	for w := range wb {
+		if !wa[w] && !titleFillerWords[w] {
+			return false
+		}
+	}
+	return true
+}
+
+// trackingParams는 URL 비교 시 무시하는 추적용 쿼리 파라미터.
+var trackingParams = map[string]bool{
+	"trk": true, "trkcampaign": true, "ref": true, "ref_": true, "nc1": true, "nc2": true,
+	"gclid": true, "fbclid": true, "mc_cid": true, "mc_eid": true,
+}
+
+// localePathPattern은 /ko/, /jp/, /pt_br/ 같은 언어 경로.
+var localePathPattern = regexp.MustCompile(`^([a-z]{2}|[a-z]{2}[-_][a-z]{2,4})$`)
+
+// normalizeURL은 같은 발표를 가리키는 URL이 같은 문자열이 되도록 정규화한다.
+// 스킴·www·언어 경로·추적 파라미터·fragment·끝 슬래시를 없애고 남은 쿼리는 정렬한다.
+// 호스트가 없는 상대 경로는 aws.amazon.com 기준으로 본다.
+func normalizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	if strings.HasPrefix(raw, "/") && !strings.HasPrefix(raw, "//") {
+		raw = "https://aws.amazon.com" + raw
+	} else if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+
+	segs := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segs) > 1 && localePathPattern.MatchString(strings.ToLower(segs[0])) {
+		segs = segs[1:]
+	}
+	path := strings.Join(segs, "/")
+	path = strings.TrimSuffix(path, "/index.html")
+
+	q := u.Query()
+	for k := range q {
+		lk := strings.ToLower(k)
+		if trackingParams[lk] || strings.HasPrefix(lk, "utm_") || strings.HasPrefix(lk, "sc_") {
+			q.Del(k)
+		}
+	}
+	out := host
+	if path != "" {
+		out += "/" + path
+	}
+	if len(q) > 0 {
+		out += "?" + q.Encode() // Encode는 키 순으로 정렬한다
+	}
+	return out
+}
+
+// titleSimilarity는 pg_trgm의 similarity()와 같은 방식(단어별 3-gram 자카드 계수)으로
+// 두 제목의 유사도를 0~1로 계산한다.
+func titleSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	inter := 0
+	for g := range ta {
+		if tb[g] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(ta)+len(tb)-inter)
+}
+
+func trigrams(s string) map[string]bool {
+	out := map[string]bool{}
+	for _, w := range searchWords(s) {
+		r := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			out[string(r[i:i+3])] = true
+		}
+	}
+	return out
+}
+
+// mailTimezone은 주간 메일의 날짜 기준 시간대.
+var mailTimezone = time.FixedZone("KST", 9*60*60)
+
+// mailSourceItem은 메일 What's New 표의 한 줄을 SourceItem으로 만든다.
+// 메일 항목에는 id가 없으므로 정규화한 URL을 식별자로 쓴다.
+func mailSourceItem(n NewsItem) SourceItem {
+	item := SourceItem{
+		Type:     SourceMail,
+		SourceId: normalizeURL(n.Link),
+		Title:    strings.TrimSpace(n.Title),
+		Url:      n.Link,
+	}
+	if t, err := time.ParseInLocation(DateLayout, n.Date, mailTimezone); err == nil {
+		item.PublishedAt = &t
+	}
+	return item
+}
+
+// validate는 저장할 수 없는 SourceItem을 걸러낸다.
+func (item SourceItem) validate() error {
+	switch {
+	case item.Type == "" || item.Type == SourceAwsApi:
+		return fmt.Errorf("source type %q cannot be ingested directly", item.Type)
+	case item.SourceId == "":
+		return errors.New("source id is empty")
+	case item.Title == "":
+		return errors.New("title is empty")
+	}
+	return nil
+}
+
+// pgSourceExists는 출처 항목이 이미 레코드이거나 병합된 출처로 들어와 있는지 확인한다.
+func pgSourceExists(ctx context.Context, tx pgx.Tx, sourceType, sourceId string) (bool, error) {
+	var exists bool
+	err := tx.QueryRow(ctx, `
+SELECT EXISTS (SELECT 1 FROM whatsnews WHERE source_id = $1)
+    OR EXISTS (SELECT 1 FROM whatsnews_merges
+               WHERE source_type = $2 AND source_id = $3 AND undone_at IS NULL)`,
+		recordSourceId(sourceType, sourceId), sourceType, sourceId).Scan(&exists)
+	return exists, err
+}
+
+// pgFindCanonical은 item과 같은 발표로 보이는 레코드를 찾는다. 없으면 id=0.
+func pgFindCanonical(ctx context.Context, tx pgx.Tx, item SourceItem, canonicalURL string) (id int, method string, score float64, err error) {
+	if canonicalURL != "" {
+		err = tx.QueryRow(ctx,
+			`SELECT id FROM whatsnews
+             WHERE  canonical_url = $1 AND source_type <> $2
+             ORDER  BY id LIMIT 1`, canonicalURL, item.Type).Scan(&id)
+		if err == nil {
+			return id, MergeByURL, 1, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return 0, "", 0, err
+		}
+	}
+	if item.PublishedAt == nil {
+		return 0, "", 0, nil
+	}
+	rows, err := tx.Query(ctx,
+		`SELECT id, title, source_type FROM whatsnews
+         WHERE  source_created_at BETWEEN $1 AND $2`,
+		item.PublishedAt.Add(-canonicalTitleWindow).UTC(), item.PublishedAt.Add(canonicalTitleWindow).UTC())
+	if err != nil {
+		return 0, "", 0, err
+	}
+	cands, err := pgx.CollectRows(rows, pgx.RowToStructByPos[canonCandidate])
+	if err != nil {
+		return 0, "", 0, err
+	}
+	if id, score = pickByTitle(item.Title, item.Type, cands); id > 0 {
+		method = MergeByTitle
+	}
+	return id, method, score, nil
+}
+
+// pgDemotePrimary는 메일/RSS로 만들어진 레코드의 출처를 병합된 출처로 옮긴다.
+// API 항목이 그 레코드를 대신 차지하기 직전에 호출한다.
+func pgDemotePrimary(ctx context.Context, tx pgx.Tx, whatsnewID int, method string, score float64) error {
+	_, err := tx.Exec(ctx, `
+INSERT INTO whatsnews_merges(whatsnew_id, source_type, source_id, title, content, url, source_created_at, method, score)
+SELECT id, source_type, substr(source_id, length(source_type) + 2), title, content, source_url, source_created_at, $2, $3
+FROM   whatsnews
+WHERE  id = $1`, whatsnewID, method, score)
+	return err
+}
+
+func (s *PgStore) IngestSourceItem(ctx context.Context, item SourceItem) (IngestResult, error) {
+	if err := item.validate(); err != nil {
+		return IngestResult{}, err
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return IngestResult{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	exists, err := pgSourceExists(ctx, tx, item.Type, item.SourceId)
+	if err != nil || exists {
+		return IngestResult{Skipped: exists}, err
+	}
+
+	canonicalURL := normalizeURL(item.Url)
+	id, method, score, err := pgFindCanonical(ctx, tx, item, canonicalURL)
+	if err != nil {
+		return IngestResult{}, fmt.Errorf("find canonical: %w", err)
+	}
+
+	// source_created_at은 TIMESTAMP라 pgx가 UTC가 아닌 시각은 벽시계 값 그대로 쓴다
+	published := utcTime(item.PublishedAt)
+	res := IngestResult{WhatsnewId: id, Method: method}
+	if id > 0 {
+		res.Merged = true
+		_, err = tx.Exec(ctx,
+			`INSERT INTO whatsnews_merges(whatsnew_id, source_type, source_id, title, content, url, source_created_at, method, score)
+             VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			id, item.Type, item.SourceId, item.Title, item.Content, item.Url, published, method, score)
+		if err != nil {
+			return IngestResult{}, fmt.Errorf("insert whatsnews_merges: %w", err)
+		}
+	} else {
+		res.Created = true
+		err = tx.QueryRow(ctx,
+			`INSERT INTO whatsnews(title, content, source_id, source_type, source_url, canonical_url, source_created_at, created_at, updated_at)
+             VALUES($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+             RETURNING id`,
+			item.Title, item.Content, recordSourceId(item.Type, item.SourceId), item.Type, item.Url,
+			canonicalURL, published).Scan(&res.WhatsnewId)
+		if err != nil {
+			return IngestResult{}, fmt.Errorf("insert whatsnews: %w", err)
+		}
+		if _, err := linkDimensions(ctx, tx, res.WhatsnewId, item.Title, item.Content, nil); err != nil {
+			return IngestResult{}, err
+		}
+		if err := pgNotifyNew(ctx, tx, res.WhatsnewId); err != nil {
+			return IngestResult{}, fmt.Errorf("notify: %w", err)
+		}
+	}
+	return res, tx.Commit(ctx)
+}
+
+const pgMergeColumns = `id, whatsnew_id, source_type, source_id, title, COALESCE(url, ''), source_created_at,
+       method, score, created_at, undone_at, split_whatsnew_id`
+
+func (s *PgStore) ListMerges(ctx context.Context, q MergesQuery) ([]MergeDecision, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT `+pgMergeColumns+`
+FROM   whatsnews_merges
+WHERE  ($1 = 0 OR whatsnew_id = $1)
+AND    ($2 OR undone_at IS NULL)
+ORDER  BY id DESC
+LIMIT  $3 OFFSET $4`, q.WhatsnewId, q.IncludeUndone, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[MergeDecision])
+}
+
+func (s *PgStore) UndoMerge(ctx context.Context, id int) (MergeDecision, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return MergeDecision{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT `+pgMergeColumns+`, COALESCE(content, '')
+FROM whatsnews_merges WHERE id = $1 FOR UPDATE`, id)
+	if err != nil {
+		return MergeDecision{}, err
+	}
+	m, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByPos[mergeWithContent])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return MergeDecision{}, ErrNotFound
+	}
+	if err != nil {
+		return MergeDecision{}, err
+	}
+	if m.UndoneAt != nil {
+		return m.MergeDecision, ErrMergeUndone
+	}
+
+	// 병합된 출처를 원래 값으로 별도 레코드로 분리한다
+	var splitID int
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO whatsnews(title, content, source_id, source_type, source_url, canonical_url, source_created_at, created_at, updated_at)
+         VALUES($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+         RETURNING id`,
+		m.Title, m.Content, recordSourceId(m.SourceType, m.SourceId), m.SourceType, m.Url,
+		normalizeURL(m.Url), m.SourceCreatedAt).Scan(&splitID); err != nil {
+		return MergeDecision{}, fmt.Errorf("insert whatsnews: %w", err)
+	}
+	if _, err := linkDimensions(ctx, tx, splitID, m.Title, m.Content, nil); err != nil {
+		return MergeDecision{}, err
+	}
+	if err := tx.QueryRow(ctx,
+		`UPDATE whatsnews_merges SET undone_at = NOW(), split_whatsnew_id = $2
+         WHERE  id = $1
+         RETURNING undone_at`, id, splitID).Scan(&m.UndoneAt); err != nil {
+		return MergeDecision{}, fmt.Errorf("update whatsnews_merges: %w", err)
+	}
+	m.SplitWhatsnewId = &splitID
+	return m.MergeDecision, tx.Commit(ctx)
+}
+
+func (s *PgStore) BackfillCanonicalURLs(ctx context.Context) (int, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT id, COALESCE(source_url, '') FROM whatsnews WHERE canonical_url IS NULL`)
+	if err != nil {
+		return 0, err
+	}
+	type pending struct {
+		Id  int
+		Url string
+	}
+	todo, err := pgx.CollectRows(rows, pgx.RowToStructByPos[pending])
+	if err != nil || len(todo) == 0 {
+		return 0, err
+	}
+
+	batch := &pgx.Batch{}
+	for _, p := range todo {
+		batch.Queue("UPDATE whatsnews SET canonical_url = $2 WHERE id = $1", p.Id, normalizeURL(p.Url))
+	}
+	if err := s.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return 0, err
+	}
+	return len(todo), nil
+}
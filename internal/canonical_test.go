@@ -0,0 +1,51 @@
+package internal
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	const want = "aws.amazon.com/about-aws/whats-new/2025/04/amazon-ec2-c8g-instances-additional-regions"
+	same := []string{
+		"https://aws.amazon.com/about-aws/whats-new/2025/04/amazon-ec2-c8g-instances-additional-regions/",
+		"https://aws.amazon.com/ko/about-aws/whats-new/2025/04/amazon-ec2-c8g-instances-additional-regions",
+		"http://www.aws.amazon.com/about-aws/whats-new/2025/04/amazon-ec2-c8g-instances-additional-regions/?sc_channel=em&utm_source=mail#top",
+		"/about-aws/whats-new/2025/04/amazon-ec2-c8g-instances-additional-regions/index.html",
+		"https://AWS.amazon.com/pt_br/about-aws/whats-new/2025/04/amazon-ec2-c8g-instances-additional-regions?trk=abc",
+	}
+	for _, in := range same {
+		if got := normalizeURL(in); got != want {
+			t.Errorf("normalizeURL(%q): got %q, want %q", in, got, want)
+		}
+	}
+
+	if got := normalizeURL("https://example.com/a?b=2&a=1&utm_medium=x"); got != "example.com/a?a=1&b=2" {
+		t.Errorf("query params: got %q", got)
+	}
+	if got := normalizeURL(""); got != "" {
+		t.Errorf("empty: got %q", got)
+	}
+}
+
+func TestPickByTitle(t *testing.T) {
+	cands := []canonCandidate{
+		{Id: 1, Title: "Amazon EC2 I4g instances are now available in AWS Asia Pacific (Seoul) Region", SourceType: SourceAwsApi},
+		{Id: 2, Title: "Amazon EC2 C8g instances now available in additional regions", SourceType: SourceAwsApi},
+		{Id: 3, Title: "Amazon EC2 High Memory instances now available in the US East (Ohio) Region", SourceType: SourceAwsApi},
+		{Id: 4, Title: "Amazon EC2 High Memory instances now available in US East (Ohio) Region", SourceType: SourceMail},
+	}
+	cases := []struct {
+		title string
+		want  int
+	}{
+		// 리전만 다른 발표
+		{"Amazon EC2 I4g instances are now available in AWS Asia Pacific (Sydney) Region", 0},
+		// 인스턴스 타입만 다른 발표
+		{"Amazon EC2 M8g instances now available in additional AWS regions", 0},
+		// 같은 발표, 관사만 다름. 같은 출처(id=4)는 후보에서 제외
+		{"Amazon EC2 High Memory instances now available in US East (Ohio) Region", 3},
+	}
+	for _, c := range cases {
+		if got, _ := pickByTitle(c.title, SourceMail, cands); got != c.want {
+			t.Errorf("pickByTitle(%q): got %d, want %d", c.title, got, c.want)
+		}
+	}
+}
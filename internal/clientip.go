@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type (
+	clientIPKey     struct{}
+	trustedProxyKey struct{}
+)
+
+// ClientIPMiddleware는 요청을 보낸 클라이언트 주소를 정해 context에 넣는다. X-Forwarded-For와
+// Forwarded 헤더는 연결한 주소가 trusted에 있을 때만 믿고, 오른쪽(가까운 홉)부터 믿는 프록시를
+// 건너뛴 첫 주소를 클라이언트로 본다. 믿지 않는 곳에서 온 헤더는 무시하므로 로그와 IP별
+// 요청 제한을 헤더로 속일 수 없다. 믿는 프록시에서 왔는지도 함께 넣는다 (requestBaseURL).
+func ClientIPMiddleware(trusted []netip.Prefix, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPKey{}, clientIP(r, trusted))
+		if addr, ok := parseForwardedAddr(r.RemoteAddr); ok && isTrustedProxy(addr, trusted) {
+			ctx = context.WithValue(ctx, trustedProxyKey{}, true)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// viaTrustedProxy는 연결한 주소가 TRUSTED_PROXIES에 있는지. 미들웨어를 거치지 않았으면 false.
+func viaTrustedProxy(r *http.Request) bool {
+	v, _ := r.Context().Value(trustedProxyKey{}).(bool)
+	return v
+}
+
+// getRealIP는 ClientIPMiddleware가 정한 클라이언트 주소. 미들웨어를 거치지 않았으면 연결한 주소.
+func getRealIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return clientIP(r, nil)
+}
+
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	addr, ok := parseForwardedAddr(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if !isTrustedProxy(addr, trusted) {
+		return addr.String()
+	}
+	hops := forwardedHops(r.Header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		a, ok := parseForwardedAddr(hops[i])
+		if !ok {
+			// "unknown"이나 가린 이름은 믿는 프록시가 적은 것이므로 그 프록시를 클라이언트로 본다
+			break
+		}
+		addr = a
+		if !isTrustedProxy(a, trusted) {
+			break
+		}
+	}
+	return addr.String()
+}
+
+// forwardedHops는 X-Forwarded-For(없으면 Forwarded의 for=)에 적힌 주소를 왼쪽(클라이언트)부터 돌려준다.
+func forwardedHops(h http.Header) []string {
+	var hops []string
+	for _, v := range h.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(v, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	if len(hops) > 0 {
+		return hops
+	}
+	for _, v := range h.Values("Forwarded") {
+		for _, elem := range strings.Split(v, ",") {
+			hop := ""
+			for _, pair := range strings.Split(elem, ";") {
+				k, val, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				if strings.EqualFold(k, "for") {
+					hop = val
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// parseForwardedAddr는 "1.2.3.4", "1.2.3.4:80", "[2001:db8::1]:80", "\"[2001:db8::1]\"" 같은
+// 주소를 읽는다.
+func parseForwardedAddr(s string) (netip.Addr, bool) {
+	s = strings.Trim(strings.TrimSpace(s), `"`)
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+	a, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return a.Unmap(), true
+}
+
+func isTrustedProxy(a netip.Addr, trusted []netip.Prefix) bool {
+	for _, p := range trusted {
+		if p.Contains(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies는 쉼표로 구분한 CIDR 목록을 읽는다. 주소만 적으면 그 주소 하나.
+func parseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if !strings.Contains(v, "/") {
+			a, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, fmt.Errorf("trusted proxy %q: %w", v, err)
+			}
+			out = append(out, netip.PrefixFrom(a.Unmap(), a.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", v, err)
+		}
+		out = append(out, p.Masked())
+	}
+	return out, nil
+}
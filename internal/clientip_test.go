@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := parseTrustedProxies("10.0.0.0/8, 192.0.2.10")
+	if err != nil {
+		t.Fatalf("parseTrustedProxies: %v", err)
+	}
+	for _, c := range []struct {
+		remote, xff, forwarded, want string
+	}{
+		// 믿지 않는 곳에서 온 헤더는 무시한다
+		{"203.0.113.5:4000", "198.51.100.1", "", "203.0.113.5"},
+		{"10.0.0.1:4000", "", "", "10.0.0.1"},
+		{"10.0.0.1:4000", "198.51.100.1", "", "198.51.100.1"},
+		// 클라이언트가 앞에 붙인 값은 믿는 프록시 다음 홉에서 멈춘다
+		{"10.0.0.1:4000", "1.1.1.1, 198.51.100.1, 192.0.2.10", "", "198.51.100.1"},
+		{"10.0.0.1:4000", "10.0.0.3, 10.0.0.2", "", "10.0.0.3"},
+		{"10.0.0.1:4000", "garbage, 10.0.0.2", "", "10.0.0.2"},
+		{"10.0.0.1:4000", "", `for=198.51.100.1;proto=https, for="[2001:db8::1]:8080"`, "2001:db8::1"},
+		{"10.0.0.1:4000", "", "for=unknown", "10.0.0.1"},
+		{"[::ffff:10.0.0.1]:4000", "198.51.100.1", "", "198.51.100.1"},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = c.remote
+		if c.xff != "" {
+			r.Header.Set("X-Forwarded-For", c.xff)
+		}
+		if c.forwarded != "" {
+			r.Header.Set("Forwarded", c.forwarded)
+		}
+		if got := clientIP(r, trusted); got != c.want {
+			t.Errorf("remote=%s xff=%q forwarded=%q: got %s, want %s", c.remote, c.xff, c.forwarded, got, c.want)
+		}
+	}
+
+	if _, err := parseTrustedProxies("10.0.0.0/8,nope"); err == nil {
+		t.Error("parseTrustedProxies accepted an invalid entry")
+	}
+}
+
+func TestRequestBaseURL(t *testing.T) {
+	trusted, err := parseTrustedProxies("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got string
+	h := ClientIPMiddleware(trusted, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = requestBaseURL(r)
+	}))
+	for _, c := range []struct{ remote, want string }{
+		// 믿지 않는 곳에서 보낸 X-Forwarded-Host는 무시한다
+		{"203.0.113.5:4000", "http://news.example.com"},
+		{"10.0.0.1:4000", "https://evil.example"},
+	} {
+		r := httptest.NewRequest("GET", "http://news.example.com/feed.atom", nil)
+		r.RemoteAddr = c.remote
+		r.Header.Set("X-Forwarded-Proto", "https")
+		r.Header.Set("X-Forwarded-Host", "evil.example")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		if got != c.want {
+			t.Errorf("remote=%s: got %s, want %s", c.remote, got, c.want)
+		}
+	}
+
+	// 응답 캐시에도 위조한 host의 링크가 들어가지 않는다
+	s := newTestStore(t)
+	if err := s.InsertAwsItem(context.Background(), awsTestItem("a1", "Amazon RDS news", "2024-06-01T00:00:00Z")); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	srv := NewHTTPServer(s, Config{Auth: AuthConfig{TrustedProxies: trusted}})
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest("GET", "http://news.example.com/feed.atom", nil)
+		r.RemoteAddr = "203.0.113.5:4000"
+		r.Header.Set("X-Forwarded-Host", "evil.example")
+		rec := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rec, r)
+		if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "evil.example") {
+			t.Fatalf("feed %d: %d %s", i, rec.Code, rec.Body)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package internal
 
 import (
 	"log"
+	"net/netip"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -20,6 +23,8 @@ type Config struct {
 	ImapUser        string
 	ImapPassword    string
 	TestdataDir     string
+	DBDriver        string
+	DBPath          string
 	DBUser          string
 	DBPassword      string
 	DBHost          string
@@ -27,11 +32,153 @@ type Config struct {
 	DBName          string
 	AppPort         string
 	SlackWebHookUrl string
+	ServicesCatalog string // 비어 있으면 내장 카탈로그(internal/catalog/services.json)
+	StaticDir       string // 비어 있지 않으면 웹 UI 파일을 바이너리 대신 이 디렉터리에서 읽는다 (-dev)
+	HTTP            HTTPConfig
+	Auth            AuthConfig
+}
+
+// HTTPConfig는 http.Server 설정. 0인 값은 defaultHTTPConfig를 쓴다.
+type HTTPConfig struct {
+	ReadHeaderTimeout time.Duration // HTTP_READ_HEADER_TIMEOUT
+	ReadTimeout       time.Duration // HTTP_READ_TIMEOUT, 요청 본문까지
+	WriteTimeout      time.Duration // HTTP_WRITE_TIMEOUT, SSE와 export는 쓸 때마다 늦춘다
+	IdleTimeout       time.Duration // HTTP_IDLE_TIMEOUT, keep-alive 연결
+	MaxHeaderBytes    int           // HTTP_MAX_HEADER_BYTES
+	ShutdownTimeout   time.Duration // HTTP_SHUTDOWN_TIMEOUT, 종료 시 처리 중인 요청을 기다리는 시간
+}
+
+var defaultHTTPConfig = HTTPConfig{
+	ReadHeaderTimeout: 5 * time.Second,
+	ReadTimeout:       30 * time.Second,
+	WriteTimeout:      60 * time.Second,
+	IdleTimeout:       2 * time.Minute,
+	MaxHeaderBytes:    64 << 10,
+	ShutdownTimeout:   20 * time.Second,
+}
+
+// withDefaults는 0인 값을 defaultHTTPConfig로 채운다.
+func (c HTTPConfig) withDefaults() HTTPConfig {
+	d := defaultHTTPConfig
+	if c.ReadHeaderTimeout > 0 {
+		d.ReadHeaderTimeout = c.ReadHeaderTimeout
+	}
+	if c.ReadTimeout > 0 {
+		d.ReadTimeout = c.ReadTimeout
+	}
+	if c.WriteTimeout > 0 {
+		d.WriteTimeout = c.WriteTimeout
+	}
+	if c.IdleTimeout > 0 {
+		d.IdleTimeout = c.IdleTimeout
+	}
+	if c.MaxHeaderBytes > 0 {
+		d.MaxHeaderBytes = c.MaxHeaderBytes
+	}
+	if c.ShutdownTimeout > 0 {
+		d.ShutdownTimeout = c.ShutdownTimeout
+	}
+	return d
+}
+
+// AuthConfig는 API 키 인증과 요청 제한 설정. auth.go 참고. 0인 RateLimit은 제한하지 않는다.
+type AuthConfig struct {
+	RequireKey     bool           // API_REQUIRE_KEY, 읽기에도 키를 요구한다
+	TrustedProxies []netip.Prefix // TRUSTED_PROXIES, 이 주소에서 온 요청만 X-Forwarded-For/Forwarded를 믿는다
+	IPLimit        RateLimit      // RATE_LIMIT_IP_PER_MINUTE, RATE_LIMIT_IP_BURST: 키 없는 요청
+	KeyLimit       RateLimit      // RATE_LIMIT_KEY_PER_MINUTE, RATE_LIMIT_KEY_BURST
+}
+
+var defaultAuthConfig = AuthConfig{
+	IPLimit:  RateLimit{PerMinute: 600, Burst: 60},
+	KeyLimit: RateLimit{PerMinute: 3000, Burst: 300},
+}
+
+// loadAuthConfig는 API_REQUIRE_KEY, TRUSTED_PROXIES, RATE_LIMIT_* 환경 변수를 읽는다. 읽을 수 없는
+// 값은 경고하고 기본값을 쓰되, API_REQUIRE_KEY는 잘못 적었으면 키를 요구하는 쪽으로 둔다.
+// RATE_LIMIT_*_PER_MINUTE=0이면 그 제한을 끈다.
+func loadAuthConfig() AuthConfig {
+	c := defaultAuthConfig
+	if v := os.Getenv("API_REQUIRE_KEY"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Printf("API_REQUIRE_KEY=%q is not a boolean (true/false); keys are required", v)
+			b = true
+		}
+		c.RequireKey = b
+	}
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		p, err := parseTrustedProxies(v)
+		if err != nil {
+			log.Printf("TRUSTED_PROXIES: %v; forwarding headers are ignored", err)
+		}
+		c.TrustedProxies = p
+	}
+	count := func(name string, def int) int {
+		v := os.Getenv(name)
+		if v == "" {
+			return def
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Printf("%s=%q is not a non-negative integer; using the default", name, v)
+			return def
+		}
+		return n
+	}
+	c.IPLimit = RateLimit{
+		PerMinute: count("RATE_LIMIT_IP_PER_MINUTE", c.IPLimit.PerMinute),
+		Burst:     count("RATE_LIMIT_IP_BURST", c.IPLimit.Burst),
+	}
+	c.KeyLimit = RateLimit{
+		PerMinute: count("RATE_LIMIT_KEY_PER_MINUTE", c.KeyLimit.PerMinute),
+		Burst:     count("RATE_LIMIT_KEY_BURST", c.KeyLimit.Burst),
+	}
+	return c
+}
+
+// loadHTTPConfig는 HTTP_* 환경 변수를 읽는다. 읽을 수 없는 값은 경고하고 기본값을 쓴다.
+func loadHTTPConfig() HTTPConfig {
+	duration := func(name string) time.Duration {
+		v := os.Getenv(name)
+		if v == "" {
+			return 0
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Printf("%s=%q is not a positive duration (e.g. 30s); using the default", name, v)
+			return 0
+		}
+		return d
+	}
+	var maxHeader int
+	if v := os.Getenv("HTTP_MAX_HEADER_BYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Printf("HTTP_MAX_HEADER_BYTES=%q is not a positive integer; using the default", v)
+		} else {
+			maxHeader = n
+		}
+	}
+	return HTTPConfig{
+		ReadHeaderTimeout: duration("HTTP_READ_HEADER_TIMEOUT"),
+		ReadTimeout:       duration("HTTP_READ_TIMEOUT"),
+		WriteTimeout:      duration("HTTP_WRITE_TIMEOUT"),
+		IdleTimeout:       duration("HTTP_IDLE_TIMEOUT"),
+		MaxHeaderBytes:    maxHeader,
+		ShutdownTimeout:   duration("HTTP_SHUTDOWN_TIMEOUT"),
+	}.withDefaults()
 }
 
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 const (
 	envFile         = ".env"
 	defaultTestdata = "./testdata"
+	defaultDBPath   = "./noti-aws-update.db"
 )
 
 func LoadConfig() Config {
@@ -41,18 +188,30 @@ func LoadConfig() Config {
 		return Config{
 			Mode:        ModeTestdata,
 			TestdataDir: defaultTestdata,
+			HTTP:        defaultHTTPConfig,
+			Auth:        defaultAuthConfig,
 		}
 	}
 	appPort := os.Getenv("APP_PORT")
 	if appPort == "" {
 		appPort = "8000" // 환경 변수 미설정 시 디폴트 포트번호 지정
 	}
+	dbDriver := os.Getenv("DATABASE_DRIVER")
+	if dbDriver == "" {
+		dbDriver = DriverPostgres
+	}
+	dbPath := os.Getenv("DATABASE_PATH")
+	if dbPath == "" {
+		dbPath = defaultDBPath // sqlite 드라이버에서만 사용
+	}
 	return Config{
 		Mode:            ModeIMAP,
 		ImapServer:      os.Getenv("IMAP_SERVER"),
 		ImapUser:        os.Getenv("IMAP_USER"),
 		ImapPassword:    os.Getenv("IMAP_PASSWORD"),
 		TestdataDir:     defaultTestdata,
+		DBDriver:        dbDriver,
+		DBPath:          dbPath,
 		DBUser:          os.Getenv("DATABASE_USER"),
 		DBPassword:      os.Getenv("DATABASE_PASSWORD"),
 		DBHost:          os.Getenv("DATABASE_HOST"),
@@ -60,5 +219,8 @@ func LoadConfig() Config {
 		DBName:          os.Getenv("DATABASE_DB"),
 		AppPort:         appPort,
 		SlackWebHookUrl: os.Getenv("SLACK_WEBHOOK_URL"),
+		ServicesCatalog: os.Getenv("SERVICES_CATALOG"),
+		HTTP:            loadHTTPConfig(),
+		Auth:            loadAuthConfig(),
 	}
 }
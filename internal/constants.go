@@ -8,6 +8,7 @@ const (
 	TableHeaderTitle          = "제목"
 	MIMETextPlain             = "text/plain"
 	DateFormatPattern         = `^\d{4}년 \d{2}월 \d{2}일$`
+	DateLayout                = "2006년 01월 02일"
 	URLPattern                = `^(.*?)<(https?://[^>]+)>`
 	MIMEFileExtension         = ".mime"
 	TestdataDirectoryFallback = "./testdata"
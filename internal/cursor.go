@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrCursorSort는 커서를 newest 외의 정렬과 함께 쓴 경우. 커서는 (source_created_at, id)
+	// 순서의 위치라 점수순 정렬에는 쓸 수 없다.
+	ErrCursorSort = errors.New("cursor requires sort=newest")
+)
+
+// WhatsNewsCursor는 newest 정렬(source_created_at DESC, id)에서 마지막으로 받은 항목의 위치.
+// 클라이언트에는 Encode한 불투명 문자열로만 보인다.
+type WhatsNewsCursor struct {
+	SourceCreatedAt *time.Time
+	Id              int
+}
+
+func cursorAfter(it WhatsNews) *WhatsNewsCursor {
+	return &WhatsNewsCursor{SourceCreatedAt: utcTime(it.SourceCreatedAt), Id: it.Id}
+}
+
+// Encode는 "<unix nano 또는 ->:<id>"를 URL-safe base64로 감싼다.
+func (c WhatsNewsCursor) Encode() string {
+	ts := "-"
+	if c.SourceCreatedAt != nil {
+		ts = strconv.FormatInt(c.SourceCreatedAt.UnixNano(), 10)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(ts + ":" + strconv.Itoa(c.Id)))
+}
+
+func DecodeWhatsNewsCursor(s string) (WhatsNewsCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return WhatsNewsCursor{}, ErrInvalidCursor
+	}
+	ts, idStr, ok := strings.Cut(string(b), ":")
+	if !ok {
+		return WhatsNewsCursor{}, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		return WhatsNewsCursor{}, ErrInvalidCursor
+	}
+	c := WhatsNewsCursor{Id: id}
+	if ts != "-" {
+		ns, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return WhatsNewsCursor{}, ErrInvalidCursor
+		}
+		t := time.Unix(0, ns).UTC()
+		c.SourceCreatedAt = &t
+	}
+	return c, nil
+}
@@ -0,0 +1,26 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWhatsNewsCursorRoundTrip(t *testing.T) {
+	ts := time.Date(2024, 6, 1, 10, 0, 0, 123456000, time.UTC)
+	for _, c := range []WhatsNewsCursor{{SourceCreatedAt: &ts, Id: 42}, {Id: 7}} {
+		got, err := DecodeWhatsNewsCursor(c.Encode())
+		if err != nil {
+			t.Fatalf("Decode(%v): %v", c, err)
+		}
+		if got.Id != c.Id || (got.SourceCreatedAt == nil) != (c.SourceCreatedAt == nil) ||
+			(got.SourceCreatedAt != nil && !got.SourceCreatedAt.Equal(*c.SourceCreatedAt)) {
+			t.Errorf("round trip: got %+v, want %+v", got, c)
+		}
+	}
+	for _, bad := range []string{"", "!!", "bm9wZQ", "LToweA"} {
+		if _, err := DecodeWhatsNewsCursor(bad); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Decode(%q): got %v, want ErrInvalidCursor", bad, err)
+		}
+	}
+}
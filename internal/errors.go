@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+)
+
+// requestIDHeader로 요청 id를 주고받는다. 프록시가 붙여 보낸 값이 requestIDPattern에 맞으면
+// 그대로 이어 쓴다.
+const requestIDHeader = "X-Request-ID"
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+type requestIDKey struct{}
+
+// RequestID는 RequestIDMiddleware가 요청 context에 넣은 id. 없으면 빈 문자열.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDMiddleware는 요청마다 id를 정해 context와 X-Request-ID 응답 헤더에 넣는다.
+// 오류 응답의 request_id와 로그를 이 id로 맞춰 본다.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			var b [8]byte
+			_, _ = rand.Read(b[:])
+			id = hex.EncodeToString(b[:])
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// APIError는 모든 오류 응답의 본문. 서버 오류의 자세한 내용은 본문에 넣지 않고
+// request_id와 함께 로그에만 남긴다.
+type APIError struct {
+	// invalid_parameter, invalid_request, not_found, method_not_allowed, payload_too_large,
+	// timeout, internal_error
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []ParamError `json:"errors,omitempty"`
+}
+
+func writeAPIError(w http.ResponseWriter, r *http.Request, status int, e APIError) {
+	e.RequestID = RequestID(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(e)
+}
+
+// writeBadRequest는 err를 400 APIError로 쓴다. ParamError면 어느 파라미터인지 함께 알린다.
+// err의 메시지는 그대로 클라이언트에 나가므로 요청 검증 오류에만 쓴다.
+func writeBadRequest(w http.ResponseWriter, r *http.Request, err error) {
+	var perr *ParamError
+	if errors.As(err, &perr) {
+		writeAPIError(w, r, http.StatusBadRequest, APIError{
+			Code:    "invalid_parameter",
+			Message: perr.Error(),
+			Errors:  []ParamError{*perr},
+		})
+		return
+	}
+	writeAPIError(w, r, http.StatusBadRequest, APIError{Code: "invalid_request", Message: err.Error()})
+}
+
+func writeNotFound(w http.ResponseWriter, r *http.Request, message string) {
+	writeAPIError(w, r, http.StatusNotFound, APIError{Code: "not_found", Message: message})
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeAPIError(w, r, http.StatusMethodNotAllowed, APIError{Code: "method_not_allowed", Message: "method not allowed"})
+}
+
+// writeServerError는 err를 요청 id와 함께 로그에 남기고, 클라이언트에는 안전한 메시지만 보낸다.
+// DB가 제시간에 답하지 않은 경우는 504로 구별한다.
+func writeServerError(w http.ResponseWriter, r *http.Request, err error) {
+	logRequestError(r, err)
+	if errors.Is(err, context.DeadlineExceeded) {
+		writeAPIError(w, r, http.StatusGatewayTimeout, APIError{Code: "timeout", Message: "request timed out"})
+		return
+	}
+	writeAPIError(w, r, http.StatusInternalServerError, APIError{Code: "internal_error", Message: "internal server error"})
+}
+
+// logRequestError는 응답에 담을 수 없는 오류(본문을 쓰기 시작한 뒤의 오류 포함)를 로그에 남긴다.
+func logRequestError(r *http.Request, err error) {
+	log.Printf("error: %s %s: %v request_id=%s", r.Method, r.URL.Path, err, RequestID(r.Context()))
+}
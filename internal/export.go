@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 내보내기 형식
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+)
+
+var ErrExportFormat = errors.New("unknown export format (csv, ndjson)")
+
+// exportBatchSize는 내보내기에서 한 번에 읽는 행 수. 전체를 메모리에 올리지 않고
+// 커서로 이어 읽는다.
+var exportBatchSize = 500
+
+// exportCSVHeader는 CSV 열. 목록이 들어가는 열은 "; "로 잇는다.
+var exportCSVHeader = []string{
+	"id", "source", "title", "source_url", "source_created_at", "ingested_at", "updated_at",
+	"tags", "regions", "services", "sources", "relevant", "content",
+}
+
+// ExportContentTypes는 형식별 Content-Type.
+var ExportContentTypes = map[string]string{
+	ExportCSV:    "text/csv; charset=utf-8",
+	ExportNDJSON: "application/x-ndjson",
+}
+
+type exportWriter interface {
+	write(it WhatsNews) error
+	flush() error
+}
+
+func newExportWriter(w io.Writer, format string) (exportWriter, error) {
+	switch format {
+	case ExportCSV:
+		// 엑셀이 UTF-8로 읽도록 BOM을 붙인다
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return nil, err
+		}
+		cw := csv.NewWriter(w)
+		if err := cw.Write(exportCSVHeader); err != nil {
+			return nil, err
+		}
+		return &csvExportWriter{cw}, nil
+	case ExportNDJSON:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		return ndjsonExportWriter{enc}, nil
+	}
+	return nil, ErrExportFormat
+}
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func (e *csvExportWriter) write(it WhatsNews) error {
+	var tags, sources []string
+	for _, t := range it.Tags {
+		tags = append(tags, t.Name)
+	}
+	for _, s := range it.Sources {
+		sources = append(sources, s.Type)
+	}
+	source := ""
+	if len(it.Sources) > 0 {
+		source = it.Sources[0].Type + ":" + it.Sources[0].SourceId
+	}
+	row := []string{
+		strconv.Itoa(it.Id),
+		source,
+		it.Title,
+		absoluteSourceURL(it.SourceUrl),
+		exportTime(it.SourceCreatedAt),
+		exportTime(it.IngestedAt),
+		exportTime(it.UpdatedAt),
+		strings.Join(tags, "; "),
+		strings.Join(it.Regions, "; "),
+		strings.Join(it.Services, "; "),
+		strings.Join(sources, "; "),
+		strconv.FormatBool(it.Relevant),
+		stripHTML(it.Content),
+	}
+	for i, v := range row {
+		row[i] = escapeSpreadsheetCell(v)
+	}
+	return e.w.Write(row)
+}
+
+func (e *csvExportWriter) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonExportWriter struct {
+	enc *json.Encoder
+}
+
+func (e ndjsonExportWriter) write(it WhatsNews) error { return e.enc.Encode(it) }
+func (e ndjsonExportWriter) flush() error             { return nil }
+
+func exportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// escapeSpreadsheetCell은 스프레드시트가 수식으로 해석할 수 있는 값 앞에 '를 붙인다.
+func escapeSpreadsheetCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// ExportWhatsNews는 q의 필터에 맞는 뉴스 전체를 newest 순서로 format 형식으로 쓰고 쓴 건수를
+// 돌려준다. q의 Limit/Offset/After는 무시하고 exportBatchSize개씩 커서로 이어 읽으며,
+// 배치마다 flush를 부른다 (nil이면 생략). newest 외의 정렬은 ErrCursorSort.
+func ExportWhatsNews(ctx context.Context, store Store, q WhatsNewsQuery, format string, w io.Writer, flush func()) (int, error) {
+	q.normalize()
+	if q.Sort != SortNewest {
+		return 0, ErrCursorSort
+	}
+	ew, err := newExportWriter(w, format)
+	if err != nil {
+		return 0, err
+	}
+
+	q.Limit, q.Offset, q.After, q.SkipTotal = exportBatchSize, 0, nil, true
+	n := 0
+	for {
+		res, err := store.GetWhatsnews(ctx, q)
+		if err != nil {
+			return n, err
+		}
+		for _, it := range res.Items {
+			if err := ew.write(it); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if err := ew.flush(); err != nil {
+			return n, err
+		}
+		if flush != nil {
+			flush()
+		}
+		if !res.HasMore || len(res.Items) == 0 {
+			return n, nil
+		}
+		q.After = cursorAfter(res.Items[len(res.Items)-1])
+	}
+}
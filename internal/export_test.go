@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExportWhatsNews(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	for _, it := range []AwsApiItem{
+		awsTestItem("a", "=Amazon EC2 adds instances", "2024-06-01T00:00:00Z", "Amazon EC2"),
+		awsTestItem("b", "Amazon RDS supports engine", "2024-06-02T00:00:00Z", "Amazon RDS"),
+		awsTestItem("c", "Amazon EC2 in Seoul", "2024-06-03T00:00:00Z", "Amazon EC2"),
+	} {
+		if err := s.InsertAwsItem(ctx, it); err != nil {
+			t.Fatalf("InsertAwsItem: %v", err)
+		}
+	}
+
+	// 배치 경계를 넘겨 커서로 이어 읽는지 본다
+	defer func(n int) { exportBatchSize = n }(exportBatchSize)
+	exportBatchSize = 2
+
+	var buf bytes.Buffer
+	flushes := 0
+	n, err := ExportWhatsNews(ctx, s, WhatsNewsQuery{Limit: 1}, ExportCSV, &buf, func() { flushes++ })
+	if err != nil || n != 3 {
+		t.Fatalf("ExportWhatsNews(csv): got %d, %v", n, err)
+	}
+	if flushes != 2 {
+		t.Errorf("flushes: got %d, want 2", flushes)
+	}
+	if !strings.HasPrefix(buf.String(), "\ufeff") {
+		t.Errorf("csv: missing BOM")
+	}
+	rows, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\ufeff"))).ReadAll()
+	if err != nil {
+		t.Fatalf("csv: %v", err)
+	}
+	if len(rows) != 4 || strings.Join(rows[0], ",") != strings.Join(exportCSVHeader, ",") {
+		t.Fatalf("csv: got %q", rows)
+	}
+	if rows[1][2] != "Amazon EC2 in Seoul" || rows[1][1] != "aws-api:c" || rows[1][4] != "2024-06-03T00:00:00Z" || rows[1][7] != "Amazon EC2" {
+		t.Errorf("csv first row: got %q", rows[1])
+	}
+	if rows[3][2] != "'=Amazon EC2 adds instances" {
+		t.Errorf("csv formula cell: got %q", rows[3][2])
+	}
+
+	// 필터와 NDJSON
+	buf.Reset()
+	q, err := ParseWhatsNewsQuery(map[string][]string{"search": {"ec2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := ExportWhatsNews(ctx, s, q, ExportNDJSON, &buf, nil); err != nil || n != 2 {
+		t.Fatalf("ExportWhatsNews(ndjson): got %d, %v", n, err)
+	}
+	sc := bufio.NewScanner(&buf)
+	var ids []int
+	for sc.Scan() {
+		var it WhatsNews
+		if err := json.Unmarshal(sc.Bytes(), &it); err != nil {
+			t.Fatalf("ndjson line %q: %v", sc.Text(), err)
+		}
+		ids = append(ids, it.Id)
+	}
+	if len(ids) != 2 || ids[0] != 3 || ids[1] != 1 {
+		t.Errorf("ndjson ids: got %v, want [3 1]", ids)
+	}
+
+	if _, err := ExportWhatsNews(ctx, s, WhatsNewsQuery{Sort: SortOldest}, ExportCSV, &buf, nil); !errors.Is(err, ErrCursorSort) {
+		t.Errorf("sort=oldest: got %v", err)
+	}
+	if _, err := ExportWhatsNews(ctx, s, WhatsNewsQuery{}, "xlsx", &buf, nil); !errors.Is(err, ErrExportFormat) {
+		t.Errorf("format=xlsx: got %v", err)
+	}
+}
@@ -0,0 +1,305 @@
+package internal
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// 내보내는 피드 형식
+const (
+	FeedAtom = "atom"
+	FeedRSS  = "rss"
+	FeedJSON = "json"
+)
+
+const (
+	// feedDefaultLimit는 limit 파라미터가 없을 때 피드에 넣는 항목 수
+	feedDefaultLimit = 50
+	feedTitle        = "AWS 뉴스"
+	// feedEntryIDPrefix 뒤에 "<출처 종류>:<출처 id>"를 붙여 항목 id를 만든다
+	feedEntryIDPrefix = "urn:noti-aws-update:"
+)
+
+// feedContentTypes는 형식별 Content-Type.
+var feedContentTypes = map[string]string{
+	FeedAtom: "application/atom+xml; charset=utf-8",
+	FeedRSS:  "application/rss+xml; charset=utf-8",
+	FeedJSON: "application/feed+json; charset=utf-8",
+}
+
+// Feed는 필터를 적용한 whatsnews 목록을 피드 형식으로 쓰기 위한 값.
+// BaseURL은 "https://host" 형태로, 상대 경로인 퍼머링크를 절대 URL로 만들 때 쓴다.
+type Feed struct {
+	BaseURL string
+	SelfURL string
+	Items   []WhatsNews
+}
+
+// feedEntryID는 레코드 자체 출처의 source_id로 만든 항목 id. 같은 발표는 다시 수집되거나
+// 다른 출처가 병합되어도 id가 바뀌지 않는다.
+func feedEntryID(it WhatsNews) string {
+	if len(it.Sources) == 0 {
+		return feedEntryIDPrefix + "whatsnews:" + strconv.Itoa(it.Id)
+	}
+	src := it.Sources[0]
+	return feedEntryIDPrefix + src.Type + ":" + url.PathEscape(src.SourceId)
+}
+
+// feedEntryUpdated는 항목의 마지막 변경 시각. 오래된 레코드는 updated_at이 없을 수 있다.
+func feedEntryUpdated(it WhatsNews) time.Time {
+	for _, t := range []*time.Time{it.UpdatedAt, it.SourceCreatedAt, it.IngestedAt} {
+		if t != nil {
+			return t.UTC()
+		}
+	}
+	return time.Unix(0, 0).UTC()
+}
+
+// updated는 피드 전체의 마지막 변경 시각. 항목이 없으면 지금.
+func (f Feed) updated() time.Time {
+	if len(f.Items) == 0 {
+		return time.Now().UTC()
+	}
+	var latest time.Time
+	for _, it := range f.Items {
+		if t := feedEntryUpdated(it); t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+func (f Feed) permalinkURL(id int) string {
+	return f.BaseURL + permalinkPath(id)
+}
+
+// entryURL은 항목의 대표 링크. AWS 원문이 있으면 원문, 없으면 퍼머링크.
+func (f Feed) entryURL(it WhatsNews) string {
+	if u := absoluteSourceURL(it.SourceUrl); u != "" {
+		return u
+	}
+	return f.permalinkURL(it.Id)
+}
+
+func entrySummary(it WhatsNews) string {
+	return truncateSummary(stripHTML(it.Content))
+}
+
+// Write는 format 형식으로 피드를 쓴다.
+func (f Feed) Write(w io.Writer, format string) error {
+	switch format {
+	case FeedRSS:
+		return f.writeXML(w, f.rss())
+	case FeedJSON:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(f.jsonFeed())
+	default:
+		return f.writeXML(w, f.atom())
+	}
+}
+
+func (f Feed) writeXML(w io.Writer, doc any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Atom 1.0 (RFC 4287)
+
+type atomFeedDoc struct {
+	XMLName xml.Name      `xml:"http://www.w3.org/2005/Atom feed"`
+	Id      string        `xml:"id"`
+	Title   string        `xml:"title"`
+	Updated string        `xml:"updated"`
+	Author  atomAuthor    `xml:"author"`
+	Links   []atomLink    `xml:"link"`
+	Entries []atomEntryEl `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomCategory struct {
+	Term   string `xml:"term,attr"`
+	Scheme string `xml:"scheme,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntryEl struct {
+	Id         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published,omitempty"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Summary    atomText       `xml:"summary"`
+	Content    atomText       `xml:"content"`
+}
+
+func (f Feed) atom() atomFeedDoc {
+	doc := atomFeedDoc{
+		// 같은 필터의 피드는 같은 주소이므로 주소를 피드 id로 쓴다
+		Id:      f.SelfURL,
+		Title:   feedTitle,
+		Updated: f.updated().Format(time.RFC3339),
+		Author:  atomAuthor{Name: "Amazon Web Services"},
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: f.SelfURL},
+			{Rel: "alternate", Type: "text/html", Href: f.BaseURL + "/"},
+		},
+	}
+	for _, it := range f.Items {
+		e := atomEntryEl{
+			Id:      feedEntryID(it),
+			Title:   it.Title,
+			Updated: feedEntryUpdated(it).Format(time.RFC3339),
+			Links: []atomLink{
+				{Rel: "alternate", Type: "text/html", Href: f.entryURL(it)},
+				{Rel: "related", Type: "text/html", Href: f.permalinkURL(it.Id)},
+			},
+			Summary: atomText{Type: "text", Body: entrySummary(it)},
+			Content: atomText{Type: "html", Body: it.Content},
+		}
+		if it.SourceCreatedAt != nil {
+			e.Published = it.SourceCreatedAt.UTC().Format(time.RFC3339)
+		}
+		for _, t := range it.Tags {
+			e.Categories = append(e.Categories, atomCategory{Term: t.Name, Scheme: t.Namespace})
+		}
+		doc.Entries = append(doc.Entries, e)
+	}
+	return doc
+}
+
+// RSS 2.0
+
+type rssFeedDoc struct {
+	XMLName xml.Name      `xml:"rss"`
+	Version string        `xml:"version,attr"`
+	Channel rssChannelDoc `xml:"channel"`
+}
+
+type rssChannelDoc struct {
+	Title         string        `xml:"title"`
+	Link          string        `xml:"link"`
+	Description   string        `xml:"description"`
+	LastBuildDate string        `xml:"lastBuildDate"`
+	Self          atomLink      `xml:"http://www.w3.org/2005/Atom link"`
+	Items         []rssFeedItem `xml:"item"`
+}
+
+type rssGuid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssFeedItem struct {
+	Guid        rssGuid  `xml:"guid"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+	Categories  []string `xml:"category"`
+}
+
+func (f Feed) rss() rssFeedDoc {
+	doc := rssFeedDoc{
+		Version: "2.0",
+		Channel: rssChannelDoc{
+			Title:         feedTitle,
+			Link:          f.BaseURL + "/",
+			Description:   "AWS What's New 발표",
+			LastBuildDate: f.updated().Format(time.RFC1123Z),
+			Self:          atomLink{Rel: "self", Type: "application/rss+xml", Href: f.SelfURL},
+		},
+	}
+	for _, it := range f.Items {
+		item := rssFeedItem{
+			Guid:        rssGuid{Value: feedEntryID(it)},
+			Title:       it.Title,
+			Link:        f.entryURL(it),
+			Description: it.Content,
+		}
+		// RSS에는 수정 시각 필드가 없어 발표 시각을 쓴다
+		if it.SourceCreatedAt != nil {
+			item.PubDate = it.SourceCreatedAt.UTC().Format(time.RFC1123Z)
+		}
+		for _, t := range it.Tags {
+			item.Categories = append(item.Categories, t.Name)
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	return doc
+}
+
+// JSON Feed 1.1 (https://jsonfeed.org/version/1.1)
+
+type jsonFeedDoc struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	Id            string     `json:"id"`
+	URL           string     `json:"url"`
+	ExternalURL   string     `json:"external_url,omitempty"`
+	Title         string     `json:"title"`
+	ContentHTML   string     `json:"content_html"`
+	Summary       string     `json:"summary,omitempty"`
+	DatePublished *time.Time `json:"date_published,omitempty"`
+	DateModified  time.Time  `json:"date_modified"`
+	Tags          []string   `json:"tags,omitempty"`
+}
+
+func (f Feed) jsonFeed() jsonFeedDoc {
+	doc := jsonFeedDoc{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       feedTitle,
+		HomePageURL: f.BaseURL + "/",
+		FeedURL:     f.SelfURL,
+		Items:       []jsonFeedItem{},
+	}
+	for _, it := range f.Items {
+		item := jsonFeedItem{
+			Id:            feedEntryID(it),
+			URL:           f.permalinkURL(it.Id),
+			ExternalURL:   absoluteSourceURL(it.SourceUrl),
+			Title:         it.Title,
+			ContentHTML:   it.Content,
+			Summary:       entrySummary(it),
+			DatePublished: utcTime(it.SourceCreatedAt),
+			DateModified:  feedEntryUpdated(it),
+		}
+		for _, t := range it.Tags {
+			item.Tags = append(item.Tags, t.Name)
+		}
+		doc.Items = append(doc.Items, item)
+	}
+	return doc
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func testFeed(t *testing.T) Feed {
+	published := mustTime(t, "2024-06-01T20:00:00Z")
+	updated := mustTime(t, "2024-06-03T09:30:00Z")
+	return Feed{
+		BaseURL: "https://news.example.com",
+		SelfURL: "https://news.example.com/feed.atom?tags=3",
+		Items: []WhatsNews{
+			{
+				Id: 7, Title: "Amazon RDS supports new engine", Content: "<p>RDS &amp; more</p>",
+				SourceUrl: "/about-aws/whats-new/2024/06/rds/", SourceCreatedAt: &published, UpdatedAt: &updated,
+				Tags:    []Tag{{Name: "Amazon RDS", Namespace: "general-products"}},
+				Sources: []NewsSource{{Type: SourceAwsApi, SourceId: "whats-new-v2#rds"}, {Type: SourceRSS, SourceId: "guid-1"}},
+			},
+			{
+				Id: 9, Title: "Mail only", Content: "mail body", SourceCreatedAt: &published,
+				Sources: []NewsSource{{Type: SourceMail, SourceId: "aws.amazon.com/about-aws/whats-new/x"}},
+			},
+		},
+	}
+}
+
+func TestFeedAtom(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testFeed(t).Write(&buf, FeedAtom); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	var doc atomFeedDoc
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Unmarshal: %v\n%s", err, buf.String())
+	}
+	if doc.Updated != "2024-06-03T09:30:00Z" || doc.Id != "https://news.example.com/feed.atom?tags=3" {
+		t.Errorf("feed: got id=%q updated=%q", doc.Id, doc.Updated)
+	}
+	if len(doc.Entries) != 2 {
+		t.Fatalf("entries: got %d", len(doc.Entries))
+	}
+	e := doc.Entries[0]
+	if e.Id != "urn:noti-aws-update:aws-api:whats-new-v2%23rds" {
+		t.Errorf("entry id: got %q", e.Id)
+	}
+	if e.Updated != "2024-06-03T09:30:00Z" || e.Published != "2024-06-01T20:00:00Z" {
+		t.Errorf("entry times: got updated=%q published=%q", e.Updated, e.Published)
+	}
+	if e.Links[0].Href != "https://aws.amazon.com/about-aws/whats-new/2024/06/rds/" || e.Links[1].Href != "https://news.example.com/whatsnews/7" {
+		t.Errorf("entry links: got %+v", e.Links)
+	}
+	if e.Content.Body != "<p>RDS &amp; more</p>" || e.Summary.Body != "RDS & more" {
+		t.Errorf("entry content: got %+v / %+v", e.Content, e.Summary)
+	}
+	// updated_at이 없으면 발표 시각, 원문 URL이 없으면 퍼머링크
+	if e := doc.Entries[1]; e.Updated != "2024-06-01T20:00:00Z" || e.Links[0].Href != "https://news.example.com/whatsnews/9" {
+		t.Errorf("mail entry: got %+v", e)
+	}
+}
+
+func TestFeedRSSAndJSON(t *testing.T) {
+	f := testFeed(t)
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf, FeedRSS); err != nil {
+		t.Fatalf("Write(rss): %v", err)
+	}
+	var rss rssFeedDoc
+	if err := xml.Unmarshal(buf.Bytes(), &rss); err != nil {
+		t.Fatalf("Unmarshal(rss): %v", err)
+	}
+	if len(rss.Channel.Items) != 2 || rss.Channel.Items[1].Guid.Value != "urn:noti-aws-update:mail:aws.amazon.com%2Fabout-aws%2Fwhats-new%2Fx" || rss.Channel.Items[1].Guid.IsPermaLink {
+		t.Errorf("rss items: got %+v", rss.Channel.Items)
+	}
+	if rss.Channel.Items[0].PubDate != "Sat, 01 Jun 2024 20:00:00 +0000" {
+		t.Errorf("rss pubDate: got %q", rss.Channel.Items[0].PubDate)
+	}
+
+	buf.Reset()
+	if err := f.Write(&buf, FeedJSON); err != nil {
+		t.Fatalf("Write(json): %v", err)
+	}
+	var doc jsonFeedDoc
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Unmarshal(json): %v", err)
+	}
+	it := doc.Items[0]
+	if it.Id != feedEntryID(f.Items[0]) || it.URL != "https://news.example.com/whatsnews/7" ||
+		!it.DateModified.Equal(time.Date(2024, 6, 3, 9, 30, 0, 0, time.UTC)) || len(it.Tags) != 1 {
+		t.Errorf("json item: got %+v", it)
+	}
+}
@@ -3,34 +3,138 @@ package internal
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"math"
 	"net"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/jackc/pgx/v5/pgxpool"
+	"unicode/utf8"
 )
 
-func StartHTTPServer(pool *pgxpool.Pool, port string) {
-	mux := http.NewServeMux()
+// maxInventoryBytes는 인벤토리 업로드 크기 상한. Terraform state는 수십 MB가 되기도 한다.
+const maxInventoryBytes = 64 << 20
 
-	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+// StartHTTPServer는 ctx가 끝날 때까지 cfg.AppPort에서 HTTP 서버를 돌린다. ctx가 끝나면 새 연결을
+// 받지 않고 처리 중인 요청을 cfg.HTTP.ShutdownTimeout까지 기다린 뒤 nil을 돌려준다.
+func StartHTTPServer(ctx context.Context, store Store, cfg Config) error {
+	srv := NewHTTPServer(store, cfg)
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("Start Server: http://localhost%s", srv.Addr)
+	return serveUntilDone(ctx, srv, ln, cfg.HTTP.withDefaults().ShutdownTimeout)
+}
 
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		indexPath := filepath.Join("static", "index.html")
-		data, err := os.ReadFile(indexPath)
-		if err != nil {
-			http.Error(w, "index.html not found", http.StatusInternalServerError)
-			return
-		}
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		w.WriteHeader(http.StatusOK)
-		w.Write(data)
-	})
+// NewHTTPServer는 타임아웃과 헤더 크기 제한을 설정한 http.Server를 만든다.
+func NewHTTPServer(store Store, cfg Config) *http.Server {
+	hc := cfg.HTTP.withDefaults()
+	srv := &http.Server{
+		Addr:              ":" + cfg.AppPort,
+		Handler:           ClientIPMiddleware(cfg.Auth.TrustedProxies, LoggingMiddleware(NewHTTPHandler(store, cfg))),
+		ReadHeaderTimeout: hc.ReadHeaderTimeout,
+		ReadTimeout:       hc.ReadTimeout,
+		WriteTimeout:      hc.WriteTimeout,
+		IdleTimeout:       hc.IdleTimeout,
+		MaxHeaderBytes:    hc.MaxHeaderBytes,
+	}
+	// Shutdown은 유휴 상태가 되지 않는 SSE 연결을 기다리지 않으므로 따로 끝낸다
+	shuttingDown := make(chan struct{})
+	srv.RegisterOnShutdown(func() { close(shuttingDown) })
+	srv.BaseContext = func(net.Listener) context.Context {
+		return context.WithValue(context.Background(), serverShutdownKey{}, shuttingDown)
+	}
+	return srv
+}
+
+// serveUntilDone은 ln에서 srv를 돌리다가 ctx가 끝나면 shutdownTimeout 안에 정리한다.
+func serveUntilDone(ctx context.Context, srv *http.Server, ln net.Listener, shutdownTimeout time.Duration) error {
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	log.Printf("Shutting down; waiting up to %s for in-flight requests", shutdownTimeout)
+	sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(sctx); err != nil {
+		srv.Close()
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	return nil
+}
+
+type serverShutdownKey struct{}
+
+// serverShuttingDown은 서버가 종료를 시작하면 닫히는 채널. 서버 밖(테스트)에서는 nil.
+func serverShuttingDown(ctx context.Context) <-chan struct{} {
+	ch, _ := ctx.Value(serverShutdownKey{}).(chan struct{})
+	return ch
+}
+
+// extendWriteDeadline은 오래 가는 응답(SSE, export)이 서버의 WriteTimeout에 끊기지 않도록
+// 쓰기 전마다 마감을 WriteTimeout만큼 늦춘다. 읽을 본문이 없으니 읽기 마감은 없앤다.
+func extendWriteDeadline(r *http.Request, rc *http.ResponseController) {
+	srv, _ := r.Context().Value(http.ServerContextKey).(*http.Server)
+	if srv == nil {
+		return
+	}
+	_ = rc.SetReadDeadline(time.Time{})
+	if srv.WriteTimeout > 0 {
+		_ = rc.SetWriteDeadline(time.Now().Add(srv.WriteTimeout))
+	}
+}
+
+// cacheablePatterns는 응답 캐시에 넣는 읽기 전용 라우트 (mux 패턴). 데이터가 바뀌면
+// data_version이 올라가 함께 무효화된다.
+var cacheablePatterns = map[string]bool{
+	"/api/tags":                      true,
+	"/api/tags/namespaces":           true,
+	"/api/regions":                   true,
+	"/api/regions/matrix":            true,
+	"/api/whatsnews":                 true,
+	"/api/whatsnews/{id}":            true,
+	"/api/whatsnews/lookup":          true,
+	"/api/services":                  true,
+	"/api/services/{code}/whatsnews": true,
+	"/api/stats/timeseries":          true,
+	"/api/suggest":                   true,
+	"/whatsnews/{id}":                true,
+	"/feed.atom":                     true,
+	"/feed.rss":                      true,
+	"/feed.json":                     true,
+	"/calendar.ics":                  true,
+}
+
+// NewHTTPHandler는 모든 라우트를 등록한 핸들러. 요청마다 id를 붙이고, API 키와 요청 한도를
+// 확인한 뒤, openapi.json에 있는 요청은 먼저 검증하고, cacheablePatterns의 응답은 캐시한다.
+func NewHTTPHandler(store Store, cfg Config) http.Handler {
+	cache := NewResponseCache(store, defaultCacheEntries)
+	mux := newMux(store, cache, NewStreamBroker(store), NewAssets(cfg.StaticDir))
+	cacheable := func(r *http.Request) bool {
+		_, pattern := mux.Handler(r)
+		return cacheablePatterns[pattern]
+	}
+	return RequestIDMiddleware(AuthMiddleware(store, cfg.Auth, ValidateRequests(cache.Handler(mux, cacheable))))
+}
+
+// newMux는 라우트만 등록한다. 핸들러도 파라미터를 직접 검사하므로 명세 검증 없이도
+// 같은 요청을 거부해야 한다 (openapi_test.go). cache는 /api/cache/stats에만 쓴다.
+func newMux(store Store, cache *ResponseCache, stream *StreamBroker, assets *Assets) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// 웹 UI. 파일은 바이너리에 들어 있다 (assets.go)
+	mux.HandleFunc("/static/{name...}", assets.ServeStatic)
+	mux.HandleFunc("/", assets.ServeIndex)
 
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
@@ -38,7 +142,7 @@ func StartHTTPServer(pool *pgxpool.Pool, port string) {
 
 		status := "ok"
 		dbStatus := "ok"
-		if err := pool.Ping(ctx); err != nil {
+		if err := store.Ping(ctx); err != nil {
 			status = "fail"
 			dbStatus = "fail"
 		}
@@ -55,81 +159,512 @@ func StartHTTPServer(pool *pgxpool.Pool, port string) {
 		})
 	})
 
+	mux.HandleFunc("/api/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPIDocument)
+	})
+
+	mux.HandleFunc("/api/cache/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(cache.Stats())
+	})
+
 	mux.HandleFunc("/api/tags", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			writeMethodNotAllowed(w, r)
 			return
 		}
 
-		limit := 20
-		offset := 0
-		if l := r.URL.Query().Get("limit"); l != "" {
-			if n, err := strconv.Atoi(l); err == nil && n > 0 && n <= 100 {
-				limit = n
-			}
+		limit, err := intQueryParam(r.URL.Query(), "limit", 20, 1, 100)
+		if err != nil {
+			writeBadRequest(w, r, err)
+			return
 		}
-		if o := r.URL.Query().Get("offset"); o != "" {
-			if n, err := strconv.Atoi(o); err == nil && n >= 0 {
-				offset = n
-			}
+		offset, err := intQueryParam(r.URL.Query(), "offset", 0, 0, math.MaxInt)
+		if err != nil {
+			writeBadRequest(w, r, err)
+			return
 		}
 		nameFilter := r.URL.Query().Get("name")
+		namespace := r.URL.Query().Get("namespace")
 
-		tags, err := GetTags(r.Context(), pool, limit, offset, nameFilter)
+		tags, err := store.GetTags(r.Context(), TagsQuery{
+			Limit:     limit,
+			Offset:    offset,
+			Name:      nameFilter,
+			Namespace: namespace,
+		})
 		if err != nil {
-			http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+			writeServerError(w, r, err)
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(tags); err != nil {
-			http.Error(w, "Encoding error", http.StatusInternalServerError)
+			logRequestError(r, err)
+		}
+	})
+
+	mux.HandleFunc("/api/tags/namespaces", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		namespaces, err := store.GetTagNamespaces(r.Context())
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"items": namespaces})
+	})
+
+	mux.HandleFunc("/api/regions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		regions, err := store.GetRegions(r.Context())
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"items": regions})
+	})
+
+	// 리전 × 서비스(서비스 카탈로그, whatsnews_services)별 발표 건수
+	mux.HandleFunc("/api/regions/matrix", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		regions, err := resolveRegions(splitCSV(r.URL.Query().Get("regions")))
+		if err != nil {
+			writeBadRequest(w, r, queryParamError("regions", "%v", err))
+			return
+		}
+		matrix, err := store.GetRegionMatrix(r.Context(), RegionMatrixQuery{Regions: regions})
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(matrix)
+	})
+
+	// 출처 간 병합 결정. 되돌리기는 cli unmerge로 한다
+	mux.HandleFunc("/api/merges", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		query := r.URL.Query()
+		var q MergesQuery
+		var err error
+		if q.Limit, err = intQueryParam(query, "limit", 50, 1, 100); err != nil {
+			writeBadRequest(w, r, err)
+			return
+		}
+		if q.Offset, err = intQueryParam(query, "offset", 0, 0, math.MaxInt); err != nil {
+			writeBadRequest(w, r, err)
+			return
+		}
+		if q.WhatsnewId, err = intQueryParam(query, "whatsnew_id", 0, 1, math.MaxInt); err != nil {
+			writeBadRequest(w, r, err)
+			return
+		}
+		if q.IncludeUndone, err = boolQueryParam(query, "include_undone", false); err != nil {
+			writeBadRequest(w, r, err)
+			return
 		}
+
+		merges, err := store.ListMerges(r.Context(), q)
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"items": merges})
 	})
 
 	mux.HandleFunc("/api/whatsnews", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			writeMethodNotAllowed(w, r)
 			return
 		}
 
-		limit := 20
-		offset := 0
-		if l := r.URL.Query().Get("limit"); l != "" {
-			if n, err := strconv.Atoi(l); err == nil && n > 0 && n <= 100 {
-				limit = n
-			}
+		q, err := parseWhatsNewsQuery(r)
+		if err != nil {
+			writeBadRequest(w, r, err)
+			return
 		}
-		if o := r.URL.Query().Get("offset"); o != "" {
-			if n, err := strconv.Atoi(o); err == nil && n >= 0 {
-				offset = n
-			}
+		result, err := store.GetWhatsnews(r.Context(), q)
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(result)
+	})
+
+	// 필터에 맞는 뉴스 전체를 CSV/NDJSON으로 내려받기. 커서로 나눠 읽으며 바로 흘려보낸다
+	mux.HandleFunc("/api/whatsnews/export", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = ExportCSV
+		}
+		if _, ok := ExportContentTypes[format]; !ok {
+			writeBadRequest(w, r, queryParamError("format", "must be one of csv, ndjson"))
+			return
+		}
+		if sort := r.URL.Query().Get("sort"); sort != "" && sort != SortNewest {
+			writeBadRequest(w, r, queryParamError("sort", "must be newest (export is always newest first)"))
+			return
+		}
+		q, err := parseWhatsNewsQuery(r)
+		if err != nil {
+			writeBadRequest(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", ExportContentTypes[format])
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="whatsnews-%s.%s"`, time.Now().UTC().Format("20060102"), format))
+		rc := http.NewResponseController(w)
+		extendWriteDeadline(r, rc)
+		n, err := ExportWhatsNews(r.Context(), store, q, format, w, func() {
+			rc.Flush()
+			extendWriteDeadline(r, rc)
+		})
+		// 본문을 쓰기 시작한 뒤라 상태 코드를 바꿀 수 없다
+		if err != nil {
+			logRequestError(r, fmt.Errorf("export %s: stopped after %d rows: %w", format, n, err))
+		}
+	})
+
+	// 발표 하나와 관련 발표. ?related=<개수> (0~20, 기본 5)
+	mux.HandleFunc("/api/whatsnews/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil || id <= 0 {
+			writeBadRequest(w, r, &ParamError{In: "path", Name: "id", Message: "must be a positive integer"})
+			return
+		}
+		writeWhatsNewsDetail(w, r, store, id)
+	})
+
+	// 출처 식별자로 찾기: ?source_id=<AWS 항목 id 또는 mail:/rss: 식별자>
+	mux.HandleFunc("/api/whatsnews/lookup", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		sourceID := r.URL.Query().Get("source_id")
+		if sourceID == "" {
+			writeBadRequest(w, r, queryParamError("source_id", "is required"))
+			return
+		}
+		id, err := store.FindWhatsnewsBySource(r.Context(), sourceID)
+		if errors.Is(err, ErrNotFound) {
+			writeNotFound(w, r, "no announcement with this source id")
+			return
+		}
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		writeWhatsNewsDetail(w, r, store, id)
+	})
+
+	// 검색어 자동 완성: ?q=<입력 중인 검색어>&limit=<종류별 개수>
+	mux.HandleFunc("/api/suggest", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		query := r.URL.Query()
+		q := strings.TrimSpace(query.Get("q"))
+		if q == "" {
+			writeBadRequest(w, r, queryParamError("q", "is required"))
+			return
+		}
+		if utf8.RuneCountInString(q) > maxSuggestQueryLen {
+			writeBadRequest(w, r, queryParamError("q", "must be at most %d characters", maxSuggestQueryLen))
+			return
+		}
+		limit, err := intQueryParam(query, "limit", defaultSuggestLimit, 1, maxSuggestLimit)
+		if err != nil {
+			writeBadRequest(w, r, err)
+			return
+		}
+		res, err := GetSuggestions(r.Context(), store, q, limit)
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(res)
+	})
+
+	// 새로 수집된 뉴스를 Server-Sent Events로 보낸다. 필터는 /api/whatsnews와 같다
+	mux.HandleFunc("/api/stream", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
 		}
 
-		tagIDs := []int{}
-		if ids := r.URL.Query().Get("tags"); ids != "" {
-			for _, p := range strings.Split(ids, ",") {
-				if id, err := strconv.Atoi(strings.TrimSpace(p)); err == nil {
-					tagIDs = append(tagIDs, id)
-				}
+		q, err := parseWhatsNewsQuery(r)
+		if err != nil {
+			writeBadRequest(w, r, err)
+			return
+		}
+		// EventSource가 다시 연결할 때 보내는 마지막 이벤트 id. 읽을 수 없으면 처음 연결로 본다
+		lastEventID, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+		stream.ServeStream(w, r, q, lastEventID)
+	})
+
+	// 공유용 퍼머링크 페이지
+	mux.HandleFunc("/whatsnews/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeNotFound(w, r, "announcement not found")
+			return
+		}
+		detail, err := loadWhatsNewsDetail(r, store, id)
+		if errors.Is(err, ErrNotFound) {
+			writeNotFound(w, r, "announcement not found")
+			return
+		}
+		var perr *ParamError
+		if errors.As(err, &perr) {
+			writeBadRequest(w, r, err)
+			return
+		}
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := permalinkTemplate.Execute(w, newPermalinkPage(detail)); err != nil {
+			logRequestError(r, fmt.Errorf("render permalink %d: %w", id, err))
+		}
+	})
+
+	// 필터를 구독 가능한 주소로 만드는 피드. 파라미터는 /api/whatsnews와 같다
+	for path, format := range map[string]string{"/feed.atom": FeedAtom, "/feed.rss": FeedRSS, "/feed.json": FeedJSON} {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				writeMethodNotAllowed(w, r)
+				return
 			}
+			writeFeed(w, r, store, format)
+		})
+	}
+	mux.HandleFunc("/calendar.ics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
 		}
+		writeCalendar(w, r, store)
+	})
 
-		search := r.URL.Query().Get("search")
+	mux.HandleFunc("/api/services", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
 
-		result, err := GetWhatsnews(r.Context(), pool, limit, offset, tagIDs, search)
+		services, err := store.GetServices(r.Context())
 		if err != nil {
-			http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+			writeServerError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"items": services})
+	})
+
+	// 서비스별 타임라인. /api/whatsnews와 같은 파라미터를 받는다
+	mux.HandleFunc("/api/services/{code}/whatsnews", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		svc, err := store.GetService(r.Context(), r.PathValue("code"))
+		if errors.Is(err, ErrNotFound) {
+			writeNotFound(w, r, "unknown service")
+			return
+		}
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		q, err := parseWhatsNewsQuery(r)
+		if err != nil {
+			writeBadRequest(w, r, err)
+			return
+		}
+		q.Services = []string{svc.Code}
+
+		result, err := store.GetWhatsnews(r.Context(), q)
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"service": svc, "whatsnews": result})
+	})
+
+	// 발표 건수 시계열. 필터는 /api/whatsnews와 같다
+	mux.HandleFunc("/api/stats/timeseries", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		query := r.URL.Query()
+		q, err := parseStatsQuery(query, GroupNone, 10, 50)
+		if err != nil {
+			writeBadRequest(w, r, err)
+			return
+		}
+		q.Bucket = query.Get("bucket")
+		switch q.Bucket {
+		case "":
+			q.Bucket = BucketWeek
+		case BucketDay, BucketWeek, BucketMonth:
+		default:
+			writeBadRequest(w, r, queryParamError("bucket", "must be one of day, week, month"))
+			return
+		}
+
+		result, err := GetStatsTimeseries(r.Context(), store, q)
+		if errors.Is(err, ErrStatsRange) {
+			writeBadRequest(w, r, queryParamError("bucket", "%v", err))
+			return
+		}
+		if err != nil {
+			writeServerError(w, r, err)
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(result)
 	})
 
-	addr := ":" + port
-	log.Printf("Start Server: http://localhost%s", addr)
-	if err := http.ListenAndServe(addr, LoggingMiddleware(mux)); err != nil {
-		log.Fatal(err)
-	}
+	// 최근 기간에 발표가 많은 태그/서비스/출처와 바로 앞 기간 대비 증감
+	mux.HandleFunc("/api/stats/top", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		query := r.URL.Query()
+		q, err := parseStatsQuery(query, GroupService, 10, 100)
+		if err != nil {
+			writeBadRequest(w, r, err)
+			return
+		}
+		if q.GroupBy == GroupNone {
+			writeBadRequest(w, r, queryParamError("group_by", "must be one of tag, service, source"))
+			return
+		}
+		period := query.Get("period")
+		if period == "" {
+			period = "30d"
+		}
+		if !statsPeriodPattern.MatchString(period) {
+			writeBadRequest(w, r, queryParamError("period", "must be a number followed by d, w, m or y (e.g. 30d)"))
+			return
+		}
+		result, err := GetStatsTop(r.Context(), store, q, period, time.Now())
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(result)
+	})
+
+	// 인벤토리: GET은 현재 목록, PUT은 업로드한 문서로 교체, DELETE는 비우기.
+	// 형식은 ParseInventory 참고
+	mux.HandleFunc("/api/inventory", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInventoryBytes))
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				writeAPIError(w, r, http.StatusRequestEntityTooLarge, APIError{
+					Code:    "payload_too_large",
+					Message: fmt.Sprintf("request body must be at most %d bytes", maxErr.Limit),
+				})
+				return
+			}
+			if err != nil {
+				writeBadRequest(w, r, errors.New("could not read request body"))
+				return
+			}
+			inv, err := ParseInventory(body)
+			if err != nil {
+				writeBadRequest(w, r, err)
+				return
+			}
+			if err := store.ReplaceInventory(r.Context(), inv); err != nil {
+				writeServerError(w, r, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(inv)
+			return
+		case http.MethodDelete:
+			if err := store.ReplaceInventory(r.Context(), Inventory{}); err != nil {
+				writeServerError(w, r, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		inv, err := store.GetInventory(r.Context())
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(inv)
+	})
+
+	return mux
 }
 
 // CustomResponseWriter는 응답 상태 코드를 기록
@@ -143,32 +678,405 @@ func (w *CustomResponseWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
-func getRealIP(r *http.Request) string {
-	xff := r.Header.Get("X-Forwarded-For")
-	if xff != "" {
-		ips := strings.Split(xff, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
-		}
-	}
-	fwd := r.Header.Get("Forwarded")
-	if fwd != "" {
-		for _, field := range strings.Split(fwd, ";") {
-			field = strings.TrimSpace(field)
-			if strings.HasPrefix(field, "for=") {
-				ip := strings.TrimPrefix(field, "for=")
-				ip = strings.Trim(ip, "\"")
-				if ip != "" {
-					return ip
-				}
-			}
+// Unwrap은 http.ResponseController가 Flush 등을 원래 ResponseWriter에 전달하게 한다.
+func (w *CustomResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// parseStatsQuery는 /api/stats/*의 파라미터를 읽는다. 필터는 ParseWhatsNewsQuery와 같고,
+// limit은 남길 그룹 수다.
+func parseStatsQuery(query url.Values, defGroup string, defLimit, maxLimit int) (StatsQuery, error) {
+	filter, err := ParseWhatsNewsQuery(query)
+	if err != nil {
+		return StatsQuery{}, err
+	}
+	q := StatsQuery{Filter: filter, GroupBy: query.Get("group_by"), Namespace: query.Get("namespace")}
+	switch q.GroupBy {
+	case "":
+		q.GroupBy = defGroup
+	case GroupNone, GroupTag, GroupService, GroupSource:
+	default:
+		return StatsQuery{}, queryParamError("group_by", "must be one of none, tag, service, source")
+	}
+	if q.Limit, err = intQueryParam(query, "limit", defLimit, 1, maxLimit); err != nil {
+		return StatsQuery{}, err
+	}
+	if q.Location, err = tzQueryParam(query); err != nil {
+		return StatsQuery{}, err
+	}
+	return q, nil
+}
+
+// parseWhatsNewsQuery는 /api/whatsnews 계열 엔드포인트의 쿼리 파라미터를 읽는다.
+func parseWhatsNewsQuery(r *http.Request) (WhatsNewsQuery, error) {
+	return ParseWhatsNewsQuery(r.URL.Query())
+}
+
+// ParseWhatsNewsQuery는 /api/whatsnews의 쿼리 문자열 형식 필터를 읽는다. cli export도 쓴다.
+func ParseWhatsNewsQuery(query url.Values) (WhatsNewsQuery, error) {
+
+	limit, err := intQueryParam(query, "limit", 20, 1, 100)
+	if err != nil {
+		return WhatsNewsQuery{}, err
+	}
+	offset, err := intQueryParam(query, "offset", 0, 0, math.MaxInt)
+	if err != nil {
+		return WhatsNewsQuery{}, err
+	}
+
+	tagIDs, err := parseIDList(query, "tags")
+	if err != nil {
+		return WhatsNewsQuery{}, err
+	}
+	excludeTagIDs, err := parseIDList(query, "exclude_tags")
+	if err != nil {
+		return WhatsNewsQuery{}, err
+	}
+	// tags_mode=all(기본)|any
+	tagMode := query.Get("tags_mode")
+	if tagMode == "" {
+		tagMode = TagModeAll
+	}
+	if tagMode != TagModeAll && tagMode != TagModeAny {
+		return WhatsNewsQuery{}, queryParamError("tags_mode", "must be one of all, any")
+	}
+
+	// facet=<namespace>:<태그 이름> (반복 가능)
+	var facets map[string][]string
+	for _, f := range query["facet"] {
+		ns, name, ok := strings.Cut(f, ":")
+		ns, name = strings.TrimSpace(ns), strings.TrimSpace(name)
+		if f == "" {
+			continue
+		}
+		if !ok || ns == "" || name == "" {
+			return WhatsNewsQuery{}, queryParamError("facet", "must be <namespace>:<tag name>")
+		}
+		if facets == nil {
+			facets = map[string][]string{}
+		}
+		facets[ns] = append(facets[ns], name)
+	}
+
+	// regions=<코드 또는 이름>,... (예: ap-northeast-2,tokyo)
+	regions, err := resolveRegions(splitCSV(query.Get("regions")))
+	if err != nil {
+		return WhatsNewsQuery{}, queryParamError("regions", "%v", err)
+	}
+
+	// sources=aws-api,mail,rss
+	sourceTypes := splitCSV(query.Get("sources"))
+	for _, t := range sourceTypes {
+		if t != SourceAwsApi && t != SourceMail && t != SourceRSS {
+			return WhatsNewsQuery{}, queryParamError("sources", "item %q must be one of aws-api, mail, rss", t)
+		}
+	}
+
+	relevantOnly, err := boolQueryParam(query, "relevant_only", false)
+	if err != nil {
+		return WhatsNewsQuery{}, err
+	}
+
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = SortNewest
+	}
+	if !validSort(sort) {
+		return WhatsNewsQuery{}, queryParamError("sort", "must be one of newest, oldest, updated, relevance")
+	}
+	search := query.Get("search")
+	if sort == SortRelevance && strings.TrimSpace(search) == "" {
+		sort = SortNewest
+	}
+
+	// from/to는 발표 시각, since는 저장 시각. 날짜만 주면 tz(기본 UTC)의 그 날짜로 보고
+	// to는 그 날짜 끝까지 포함한다
+	loc, err := tzQueryParam(query)
+	if err != nil {
+		return WhatsNewsQuery{}, err
+	}
+	from, err := parseTimeParam(query, "from", loc, false)
+	if err != nil {
+		return WhatsNewsQuery{}, err
+	}
+	to, err := parseTimeParam(query, "to", loc, true)
+	if err != nil {
+		return WhatsNewsQuery{}, err
+	}
+	since, err := parseTimeParam(query, "since", loc, false)
+	if err != nil {
+		return WhatsNewsQuery{}, err
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		return WhatsNewsQuery{}, queryParamError("to", "must be after from")
+	}
+
+	// cursor=<이전 응답의 next_cursor>: offset 대신 쓰는 키셋 페이지네이션
+	var after *WhatsNewsCursor
+	if v := query.Get("cursor"); v != "" {
+		c, err := DecodeWhatsNewsCursor(v)
+		if err != nil {
+			return WhatsNewsQuery{}, queryParamError("cursor", "%v", err)
+		}
+		if query.Get("offset") != "" {
+			return WhatsNewsQuery{}, queryParamError("cursor", "cannot be combined with offset")
+		}
+		if sort != SortNewest {
+			return WhatsNewsQuery{}, queryParamError("cursor", "%v", ErrCursorSort)
+		}
+		after = &c
+	}
+
+	// total=false이면 전체 건수를 세지 않는다
+	total, err := boolQueryParam(query, "total", true)
+	if err != nil {
+		return WhatsNewsQuery{}, err
+	}
+
+	return WhatsNewsQuery{
+		Limit:         limit,
+		Offset:        offset,
+		TagIDs:        tagIDs,
+		TagMode:       tagMode,
+		ExcludeTagIDs: excludeTagIDs,
+		Search:        search,
+		Sort:          sort,
+		From:          from,
+		To:            to,
+		Since:         since,
+		Facets:        facets,
+		Regions:       regions,
+		Services:      splitCSV(query.Get("services")),
+		SourceTypes:   sourceTypes,
+		RelevantOnly:  relevantOnly,
+		After:         after,
+		SkipTotal:     !total,
+	}, nil
+}
+
+// loadWhatsNewsDetail은 발표와 관련 발표를 읽는다. 관련 발표 개수는 ?related로 정한다.
+func loadWhatsNewsDetail(r *http.Request, store Store, id int) (WhatsNewsDetail, error) {
+	related, err := intQueryParam(r.URL.Query(), "related", 5, 0, 20)
+	if err != nil {
+		return WhatsNewsDetail{}, err
+	}
+
+	item, err := store.GetWhatsnewsItem(r.Context(), id)
+	if err != nil {
+		return WhatsNewsDetail{}, err
+	}
+	detail := WhatsNewsDetail{Item: item, Related: []RelatedWhatsNews{}, Permalink: permalinkPath(id)}
+	if related > 0 {
+		if detail.Related, err = store.GetRelatedWhatsnews(r.Context(), id, related); err != nil {
+			return WhatsNewsDetail{}, err
+		}
+	}
+	return detail, nil
+}
+
+func writeWhatsNewsDetail(w http.ResponseWriter, r *http.Request, store Store, id int) {
+	detail, err := loadWhatsNewsDetail(r, store, id)
+	if errors.Is(err, ErrNotFound) {
+		writeNotFound(w, r, "announcement not found")
+		return
+	}
+	var perr *ParamError
+	if errors.As(err, &perr) {
+		writeBadRequest(w, r, err)
+		return
+	}
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(detail)
+}
+
+// tzQueryParam은 tz 파라미터의 시간대를 읽는다. 없으면 UTC.
+func tzQueryParam(query url.Values) (*time.Location, error) {
+	v := query.Get("tz")
+	if v == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(v)
+	if err != nil {
+		return nil, queryParamError("tz", "unknown time zone %q", v)
+	}
+	return loc, nil
+}
+
+// timeParamLayouts는 from/to/since가 받는 형식. 오프셋이 없는 형식은 tz 기준으로 읽는다.
+var timeParamLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04", time.DateOnly}
+
+// parseTimeParam은 시각 파라미터를 읽는다. endOfDay이면 날짜만 준 값을 다음 날 0시로 바꿔
+// 그 날짜 전체를 포함하는 배타적 상한으로 만든다.
+func parseTimeParam(query url.Values, name string, loc *time.Location, endOfDay bool) (*time.Time, error) {
+	v := strings.TrimSpace(query.Get(name))
+	if v == "" {
+		return nil, nil
+	}
+	for _, layout := range timeParamLayouts {
+		t, err := time.ParseInLocation(layout, v, loc)
+		if err != nil {
+			continue
 		}
+		if endOfDay && layout == time.DateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		t = t.UTC()
+		return &t, nil
+	}
+	return nil, queryParamError(name, "invalid time %q (use YYYY-MM-DD or RFC 3339)", v)
+}
+
+// parseIDList는 쉼표로 구분된 양의 정수 id 목록을 읽는다.
+func parseIDList(query url.Values, name string) ([]int, error) {
+	ids := []int{}
+	for _, p := range splitCSV(query.Get(name)) {
+		id, err := strconv.Atoi(p)
+		if err != nil || id <= 0 {
+			return nil, queryParamError(name, "item %q must be a positive integer", p)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// intQueryParam은 정수 파라미터를 읽는다. 없으면 def, [min, max]를 벗어나면 오류.
+func intQueryParam(query url.Values, name string, def, min, max int) (int, error) {
+	v := query.Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, queryParamError(name, "must be an integer")
 	}
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err == nil {
-		return ip
+	if n < min || n > max {
+		lo, hi := float64(min), float64(max)
+		if max == math.MaxInt {
+			return 0, queryParamError(name, "%s", rangeMessage(&lo, nil, float64(n)))
+		}
+		return 0, queryParamError(name, "%s", rangeMessage(&lo, &hi, float64(n)))
+	}
+	return n, nil
+}
+
+func boolQueryParam(query url.Values, name string, def bool) (bool, error) {
+	v := query.Get(name)
+	if v == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, queryParamError(name, "must be true or false")
+	}
+	return b, nil
+}
+
+// ParamError는 잘못된 요청 파라미터 하나.
+type ParamError struct {
+	In      string `json:"in"` // query, path
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+func (e *ParamError) Error() string {
+	return e.Name + " " + e.Message
+}
+
+func queryParamError(name, format string, args ...any) *ParamError {
+	return &ParamError{In: "query", Name: name, Message: fmt.Sprintf(format, args...)}
+}
+
+// requestBaseURL은 요청이 들어온 scheme과 host로 "https://host" 형태의 주소를 만든다.
+// TRUSTED_PROXIES에서 온 요청만 X-Forwarded-Proto/X-Forwarded-Host를 따른다. 아무나 보낸
+// 헤더를 믿으면 다른 host를 가리키는 링크가 응답 캐시에 들어간다.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if !viaTrustedProxy(r) {
+		return scheme + "://" + r.Host
+	}
+	if p, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ","); strings.TrimSpace(p) == "http" || strings.TrimSpace(p) == "https" {
+		scheme = strings.TrimSpace(p)
+	}
+	host := r.Host
+	if h, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Host"), ","); strings.TrimSpace(h) != "" {
+		host = strings.TrimSpace(h)
+	}
+	return scheme + "://" + host
+}
+
+// writeFeed는 /api/whatsnews와 같은 필터로 읽은 목록을 format 형식의 피드로 쓴다.
+// limit을 주지 않으면 feedDefaultLimit개를 넣는다.
+func writeFeed(w http.ResponseWriter, r *http.Request, store Store, format string) {
+	q, err := parseWhatsNewsQuery(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	if r.URL.Query().Get("limit") == "" {
+		q.Limit = feedDefaultLimit
+	}
+	q.SkipTotal = true
+	result, err := store.GetWhatsnews(r.Context(), q)
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	base := requestBaseURL(r)
+	feed := Feed{BaseURL: base, SelfURL: base + r.URL.RequestURI(), Items: result.Items}
+	w.Header().Set("Content-Type", feedContentTypes[format])
+	if err := feed.Write(w, format); err != nil {
+		logRequestError(r, fmt.Errorf("feed %s: %w", format, err))
+	}
+}
+
+// writeCalendar는 /api/whatsnews와 같은 필터로 읽은 발표를 tz 기준 날짜의 하루 종일 일정으로
+// 쓴다. limit을 주지 않으면 calendarDefaultLimit개를 넣는다.
+func writeCalendar(w http.ResponseWriter, r *http.Request, store Store) {
+	q, err := parseWhatsNewsQuery(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	loc, err := tzQueryParam(r.URL.Query())
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	if r.URL.Query().Get("limit") == "" {
+		q.Limit = calendarDefaultLimit
+	}
+	q.SkipTotal = true
+	result, err := store.GetWhatsnews(r.Context(), q)
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	feed := Feed{BaseURL: requestBaseURL(r), Items: result.Items}
+	cal := Calendar{Name: feedTitle, Events: announcementEvents(feed, loc)}
+	w.Header().Set("Content-Type", calendarContentType)
+	if err := cal.Write(w); err != nil {
+		logRequestError(r, fmt.Errorf("calendar: %w", err))
+	}
+}
+
+// splitCSV는 쉼표로 구분된 쿼리 값을 나누고 빈 항목을 버린다.
+func splitCSV(v string) []string {
+	var out []string
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
 	}
-	return r.RemoteAddr
+	return out
 }
 
 func LoggingMiddleware(next http.Handler) http.Handler {
@@ -185,7 +1093,7 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 		}
 		ip := getRealIP(r)
 		log.Printf(
-			"[%s] \"%s %s%s\" %d \"%s\" (duration: %s)",
+			"[%s] \"%s %s%s\" %d \"%s\" (duration: %s) request_id=%s",
 			ip,
 			r.Method,
 			r.URL.Path,
@@ -193,6 +1101,7 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 			crw.statusCode,
 			r.UserAgent(),
 			duration,
+			crw.Header().Get(requestIDHeader),
 		)
 
 	})
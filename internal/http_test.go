@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWhatsNewsQueryDates(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/whatsnews?from=2024-03-01&to=2024-06-30&tz=Asia/Seoul&since=2024-07-01T09:30:00%2B09:00&sort=oldest", nil)
+	q, err := parseWhatsNewsQuery(r)
+	if err != nil {
+		t.Fatalf("parseWhatsNewsQuery: %v", err)
+	}
+	want := map[string]time.Time{
+		"from":  time.Date(2024, 2, 29, 15, 0, 0, 0, time.UTC), // 3/1 00:00 KST
+		"to":    time.Date(2024, 6, 30, 15, 0, 0, 0, time.UTC), // 6/30 하루 전체를 포함
+		"since": time.Date(2024, 7, 1, 0, 30, 0, 0, time.UTC),
+	}
+	for name, got := range map[string]*time.Time{"from": q.From, "to": q.To, "since": q.Since} {
+		if got == nil || !got.Equal(want[name]) {
+			t.Errorf("%s: got %v, want %v", name, got, want[name])
+		}
+	}
+	if q.Sort != SortOldest {
+		t.Errorf("sort: got %q", q.Sort)
+	}
+
+	for _, bad := range []string{
+		"from=2024-13-01",
+		"from=2024-06-01&to=2024-05-01",
+		"tz=Mars/Olympus",
+		"sort=popular",
+		"sort=oldest&cursor=" + (WhatsNewsCursor{Id: 1}).Encode(),
+	} {
+		if _, err := parseWhatsNewsQuery(httptest.NewRequest("GET", "/api/whatsnews?"+bad, nil)); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	s := newTestStore(t)
+	h := NewHTTPHandler(s, Config{})
+
+	get := func(target string, header http.Header) (*httptest.ResponseRecorder, APIError) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, vs := range header {
+			req.Header[k] = vs
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		var e APIError
+		if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil {
+			t.Fatalf("GET %s: body is not JSON: %q", target, rec.Body)
+		}
+		if e.RequestID == "" || e.RequestID != rec.Header().Get("X-Request-ID") {
+			t.Errorf("GET %s: request_id %q, header %q", target, e.RequestID, rec.Header().Get("X-Request-ID"))
+		}
+		return rec, e
+	}
+
+	rec, e := get("/api/whatsnews/999", nil)
+	if rec.Code != http.StatusNotFound || e.Code != "not_found" {
+		t.Errorf("404: %d %+v", rec.Code, e)
+	}
+	// 프록시가 붙인 요청 id는 이어 쓰고, 형식에 맞지 않으면 새로 만든다
+	if _, e := get("/api/whatsnews/999", http.Header{"X-Request-Id": {"edge-42"}}); e.RequestID != "edge-42" {
+		t.Errorf("forwarded request id: got %q", e.RequestID)
+	}
+	if _, e := get("/api/whatsnews/999", http.Header{"X-Request-Id": {"bad id\n"}}); e.RequestID == "bad id\n" {
+		t.Error("malformed request id was echoed")
+	}
+
+	// 쓰기 요청은 인증을 먼저 확인하므로 admin 키로 보낸다
+	secret, _, err := IssueAPIKey(context.Background(), s, "test", []string{ScopeAdmin})
+	if err != nil {
+		t.Fatalf("IssueAPIKey: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/tags", nil)
+	req.Header.Set("Authorization", "Bearer "+secret)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed || !strings.Contains(rec.Body.String(), `"method_not_allowed"`) {
+		t.Errorf("405: %d %s", rec.Code, rec.Body)
+	}
+
+	// DB 오류는 자세한 내용 없이 응답하고, 로그에 요청 id와 함께 남긴다
+	var logs bytes.Buffer
+	orig := log.Writer()
+	log.SetOutput(&logs)
+	s.Close()
+	rec, e = get("/api/tags", nil)
+	log.SetOutput(orig)
+	if rec.Code != http.StatusInternalServerError || e.Code != "internal_error" || e.Message != "internal server error" {
+		t.Errorf("500: %d %+v", rec.Code, e)
+	}
+	if strings.Contains(rec.Body.String(), "sql") {
+		t.Errorf("500 body leaks the error: %s", rec.Body)
+	}
+	if line := logs.String(); !strings.Contains(line, "request_id="+e.RequestID) || !strings.Contains(line, "database is closed") {
+		t.Errorf("log: %q", line)
+	}
+}
+
+func TestHTTPServerShutdown(t *testing.T) {
+	defer func(hb time.Duration) { streamHeartbeatInterval = hb }(streamHeartbeatInterval)
+	streamHeartbeatInterval = 50 * time.Millisecond
+
+	s := newTestStore(t)
+	srv := NewHTTPServer(s, Config{HTTP: HTTPConfig{ReadTimeout: 200 * time.Millisecond, WriteTimeout: 200 * time.Millisecond}})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- serveUntilDone(ctx, srv, ln, 2*time.Second) }()
+
+	base := "http://" + ln.Addr().String()
+	resp, err := http.Get(base + "/api/stream")
+	if err != nil {
+		t.Fatalf("GET /api/stream: %v", err)
+	}
+	defer resp.Body.Close()
+	events := readSSE(bufio.NewReader(resp.Body))
+
+	// SSE는 WriteTimeout보다 오래 열려 있어야 한다
+	time.Sleep(500 * time.Millisecond)
+	for len(events) > 0 {
+		<-events
+	}
+	select {
+	case ev, ok := <-events:
+		if !ok || !ev.comment {
+			t.Fatalf("stream after write timeout: %+v, open=%v", ev, ok)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no heartbeat after write timeout")
+	}
+
+	// 종료하면 SSE를 끝내고 처리 중인 요청 없이 nil을 돌려준다
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("serveUntilDone: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("shutdown did not finish")
+	}
+	for range events {
+	}
+	if _, err := http.Get(base + "/health"); err == nil {
+		t.Error("server still accepting connections after shutdown")
+	}
+}
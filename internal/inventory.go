@@ -0,0 +1,292 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// 인벤토리 형식
+const (
+	InventoryTerraform = "terraform" // Terraform state 또는 plan JSON
+	InventoryList      = "list"      // 서비스 코드 목록
+)
+
+// InventoryService는 인벤토리에 있는 서비스 하나. Terraform 인벤토리면 이 서비스로 매핑된
+// 리소스 타입과 리소스 수가 채워진다.
+type InventoryService struct {
+	Code          string   `json:"code"`
+	Name          string   `json:"name"`
+	ResourceTypes []string `json:"resource_types"`
+	ResourceCount int      `json:"resource_count"`
+}
+
+// Inventory는 팀이 실제로 쓰는 서비스 목록. 여기 있는 서비스와 연결된 발표가 relevant다.
+type Inventory struct {
+	Source   string             `json:"source"`
+	Services []InventoryService `json:"services"`
+	// Unmapped는 어느 서비스에도 매핑하지 못한 AWS 리소스 타입. 업로드 응답에만 있다.
+	Unmapped   []string   `json:"unmapped,omitempty"`
+	UploadedAt *time.Time `json:"uploaded_at,omitempty"`
+}
+
+// relevantExpr은 뉴스(wn)가 인벤토리의 서비스와 연결돼 있는지. 두 저장소에서 같이 쓴다.
+const relevantExpr = `EXISTS (
+    SELECT 1 FROM whatsnews_services rs
+    JOIN   inventory_services inv ON inv.service_code = rs.service_code
+    WHERE  rs.whatsnew_id = wn.id)`
+
+// tfModule은 terraform show -json의 values/planned_values 모듈.
+type tfModule struct {
+	Resources []struct {
+		Mode string `json:"mode"`
+		Type string `json:"type"`
+	} `json:"resources"`
+	ChildModules []tfModule `json:"child_modules"`
+}
+
+func (m tfModule) collect(counts map[string]int) {
+	for _, r := range m.Resources {
+		if r.Mode == "managed" {
+			counts[r.Type]++
+		}
+	}
+	for _, c := range m.ChildModules {
+		c.collect(counts)
+	}
+}
+
+// inventoryDocument는 업로드할 수 있는 JSON 문서들의 합집합.
+//   - {"services": ["rds", ...]}                  서비스 코드 목록
+//   - terraform.tfstate (version 4)               resources[].instances
+//   - terraform show -json <planfile>             resource_changes
+//   - terraform show -json (state)                values.root_module
+type inventoryDocument struct {
+	Services  *[]string `json:"services"`
+	Resources []struct {
+		Mode      string            `json:"mode"`
+		Type      string            `json:"type"`
+		Instances []json.RawMessage `json:"instances"`
+	} `json:"resources"`
+	ResourceChanges []struct {
+		Mode   string `json:"mode"`
+		Type   string `json:"type"`
+		Change struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+	Values *struct {
+		RootModule tfModule `json:"root_module"`
+	} `json:"values"`
+}
+
+// ParseInventory는 업로드된 인벤토리를 서비스 목록으로 바꾼다. JSON 문서(inventoryDocument 참고),
+// 서비스 코드 JSON 배열, 또는 줄/쉼표로 구분한 서비스 코드 텍스트('#' 이후는 주석)를 받는다.
+func ParseInventory(b []byte) (Inventory, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return Inventory{}, errors.New("empty inventory")
+	}
+
+	switch b[0] {
+	case '[':
+		var codes []string
+		if err := json.Unmarshal(b, &codes); err != nil {
+			return Inventory{}, fmt.Errorf("inventory: %w", err)
+		}
+		return inventoryFromList(codes)
+	case '{':
+		var doc inventoryDocument
+		if err := json.Unmarshal(b, &doc); err != nil {
+			return Inventory{}, fmt.Errorf("inventory: %w", err)
+		}
+		return inventoryFromDocument(doc)
+	}
+
+	var codes []string
+	for _, line := range strings.Split(string(b), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		codes = append(codes, splitCSV(line)...)
+	}
+	return inventoryFromList(codes)
+}
+
+func inventoryFromDocument(doc inventoryDocument) (Inventory, error) {
+	if doc.Services != nil {
+		return inventoryFromList(*doc.Services)
+	}
+
+	counts := map[string]int{}
+	switch {
+	case doc.ResourceChanges != nil:
+		for _, rc := range doc.ResourceChanges {
+			// 삭제만 예정된 리소스는 적용 후 남지 않는다
+			if rc.Mode != "managed" || (len(rc.Change.Actions) == 1 && rc.Change.Actions[0] == "delete") {
+				continue
+			}
+			counts[rc.Type]++
+		}
+	case doc.Resources != nil:
+		for _, r := range doc.Resources {
+			if r.Mode == "managed" && len(r.Instances) > 0 {
+				counts[r.Type] += len(r.Instances)
+			}
+		}
+	case doc.Values != nil:
+		doc.Values.RootModule.collect(counts)
+	default:
+		return Inventory{}, errors.New("inventory: expected a services list, Terraform state or plan JSON")
+	}
+	return inventoryFromTerraform(counts), nil
+}
+
+// inventoryFromList는 서비스 코드(또는 카탈로그 이름/별칭, 대소문자 무시) 목록을 검증한다.
+func inventoryFromList(values []string) (Inventory, error) {
+	inv := Inventory{Source: InventoryList, Services: []InventoryService{}}
+	seen := map[string]bool{}
+	var unknown []string
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		svc, ok := lookupCatalogService(v)
+		if !ok {
+			unknown = append(unknown, v)
+			continue
+		}
+		if !seen[svc.Code] {
+			seen[svc.Code] = true
+			inv.Services = append(inv.Services, InventoryService{Code: svc.Code, Name: svc.Name, ResourceTypes: []string{}})
+		}
+	}
+	if len(unknown) > 0 {
+		return Inventory{}, fmt.Errorf("unknown services: %s", strings.Join(unknown, ", "))
+	}
+	return inv, nil
+}
+
+func lookupCatalogService(v string) (Service, bool) {
+	for _, svc := range serviceCatalog.services {
+		if strings.EqualFold(v, svc.Code) || strings.EqualFold(v, svc.Name) {
+			return svc, true
+		}
+		for _, a := range svc.Aliases {
+			if strings.EqualFold(v, a) {
+				return svc, true
+			}
+		}
+	}
+	return Service{}, false
+}
+
+// inventoryFromTerraform은 리소스 타입별 개수를 서비스로 묶는다. AWS가 아닌 프로바이더의
+// 리소스는 무시하고, 매핑하지 못한 aws_ 리소스 타입은 Unmapped에 남긴다.
+func inventoryFromTerraform(counts map[string]int) Inventory {
+	byCode := map[string]*InventoryService{}
+	var unmapped []string
+	for typ, n := range counts {
+		if !strings.HasPrefix(typ, "aws_") {
+			continue
+		}
+		svc, ok := terraformService(typ)
+		if !ok {
+			unmapped = append(unmapped, typ)
+			continue
+		}
+		is := byCode[svc.Code]
+		if is == nil {
+			is = &InventoryService{Code: svc.Code, Name: svc.Name}
+			byCode[svc.Code] = is
+		}
+		is.ResourceTypes = append(is.ResourceTypes, typ)
+		is.ResourceCount += n
+	}
+
+	inv := Inventory{Source: InventoryTerraform, Services: []InventoryService{}, Unmapped: unmapped}
+	for _, svc := range serviceCatalog.services {
+		if is := byCode[svc.Code]; is != nil {
+			sort.Strings(is.ResourceTypes)
+			inv.Services = append(inv.Services, *is)
+		}
+	}
+	sort.Strings(inv.Unmapped)
+	return inv
+}
+
+// terraformService는 리소스 타입을 가장 길게 일치하는 카탈로그 접두사의 서비스로 매핑한다.
+// 예: aws_rds_cluster는 aurora("aws_rds_cluster"), aws_rds_global_cluster는 rds("aws_rds_").
+func terraformService(typ string) (Service, bool) {
+	var (
+		best    Service
+		bestLen int
+	)
+	for _, svc := range serviceCatalog.services {
+		for _, prefix := range svc.Terraform {
+			if len(prefix) > bestLen && strings.HasPrefix(typ, prefix) {
+				best, bestLen = svc, len(prefix)
+			}
+		}
+	}
+	return best, bestLen > 0
+}
+
+// ReplaceInventory는 저장된 인벤토리를 inv로 바꾼다. 서비스가 없으면 인벤토리를 비운다.
+func (s *PgStore) ReplaceInventory(ctx context.Context, inv Inventory) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM inventory_services`); err != nil {
+		return fmt.Errorf("clear inventory: %w", err)
+	}
+	now := time.Now()
+	for _, is := range inv.Services {
+		types := is.ResourceTypes
+		if types == nil {
+			types = []string{}
+		}
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO inventory_services(service_code, source, resource_types, resource_count, uploaded_at)
+             VALUES($1, $2, $3, $4, $5)`,
+			is.Code, inv.Source, types, is.ResourceCount, now); err != nil {
+			return fmt.Errorf("insert inventory %s: %w", is.Code, err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *PgStore) GetInventory(ctx context.Context) (Inventory, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT i.service_code, s.name, i.resource_types, i.resource_count, i.source, i.uploaded_at
+FROM   inventory_services i
+JOIN   services s ON s.code = i.service_code
+ORDER  BY s.name`)
+	if err != nil {
+		return Inventory{}, err
+	}
+	inv := Inventory{Services: []InventoryService{}}
+	var (
+		is       InventoryService
+		source   string
+		uploaded time.Time
+	)
+	if _, err := pgx.ForEachRow(rows, []any{&is.Code, &is.Name, &is.ResourceTypes, &is.ResourceCount, &source, &uploaded}, func() error {
+		inv.Services = append(inv.Services, is)
+		inv.Source = source
+		inv.UploadedAt = &uploaded
+		return nil
+	}); err != nil {
+		return Inventory{}, err
+	}
+	return inv, nil
+}
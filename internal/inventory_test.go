@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseInventoryTerraformState(t *testing.T) {
+	state := `{
+  "version": 4,
+  "resources": [
+    {"mode": "managed", "type": "aws_db_instance", "name": "main", "instances": [{}, {}]},
+    {"mode": "managed", "type": "aws_rds_cluster", "name": "aurora", "instances": [{}]},
+    {"mode": "managed", "type": "aws_s3_bucket", "name": "logs", "instances": [{}]},
+    {"mode": "data", "type": "aws_iam_policy_document", "name": "p", "instances": [{}]},
+    {"mode": "managed", "type": "random_id", "name": "suffix", "instances": [{}]},
+    {"mode": "managed", "type": "aws_made_up_thing", "name": "x", "instances": [{}]}
+  ]
+}`
+	inv, err := ParseInventory([]byte(state))
+	if err != nil {
+		t.Fatalf("ParseInventory: %v", err)
+	}
+	if inv.Source != InventoryTerraform {
+		t.Errorf("source: got %q", inv.Source)
+	}
+	got := map[string]InventoryService{}
+	for _, is := range inv.Services {
+		got[is.Code] = is
+	}
+	if len(got) != 3 || got["rds"].ResourceCount != 2 || got["aurora"].ResourceCount != 1 || got["s3"].ResourceCount != 1 {
+		t.Errorf("services: got %+v", inv.Services)
+	}
+	if !reflect.DeepEqual(inv.Unmapped, []string{"aws_made_up_thing"}) {
+		t.Errorf("unmapped: got %v", inv.Unmapped)
+	}
+}
+
+func TestParseInventoryTerraformPlan(t *testing.T) {
+	plan := `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"mode": "managed", "type": "aws_lambda_function", "change": {"actions": ["create"]}},
+    {"mode": "managed", "type": "aws_instance", "change": {"actions": ["no-op"]}},
+    {"mode": "managed", "type": "aws_sqs_queue", "change": {"actions": ["delete"]}}
+  ]
+}`
+	inv, err := ParseInventory([]byte(plan))
+	if err != nil {
+		t.Fatalf("ParseInventory: %v", err)
+	}
+	var codes []string
+	for _, is := range inv.Services {
+		codes = append(codes, is.Code)
+	}
+	if !reflect.DeepEqual(codes, []string{"ec2", "lambda"}) {
+		t.Errorf("services: got %v", codes)
+	}
+}
+
+func TestParseInventoryList(t *testing.T) {
+	for _, body := range []string{
+		`{"services": ["rds", "Amazon S3", "rds"]}`,
+		`["rds", "s3"]`,
+		"# prod\nrds\ns3, rds\n",
+	} {
+		inv, err := ParseInventory([]byte(body))
+		if err != nil {
+			t.Fatalf("ParseInventory(%q): %v", body, err)
+		}
+		var codes []string
+		for _, is := range inv.Services {
+			codes = append(codes, is.Code)
+		}
+		if inv.Source != InventoryList || !reflect.DeepEqual(codes, []string{"rds", "s3"}) {
+			t.Errorf("ParseInventory(%q): got %s %v", body, inv.Source, codes)
+		}
+	}
+
+	if _, err := ParseInventory([]byte(`["rds", "nope"]`)); err == nil {
+		t.Error("expected unknown service error")
+	}
+	if _, err := ParseInventory([]byte(`{"foo": 1}`)); err == nil {
+		t.Error("expected unrecognized document error")
+	}
+}
@@ -0,0 +1,296 @@
+package internal
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// openAPIDocument는 /api/openapi.json으로 내보내는 API 명세. 요청 검증도 이 문서를 따른다.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+var apiSpec = mustParseOpenAPI(openAPIDocument)
+
+// openAPISchema는 파라미터 검증에 쓰는 JSON Schema의 일부.
+type openAPISchema struct {
+	Type      string         `json:"type"`
+	Minimum   *float64       `json:"minimum"`
+	Maximum   *float64       `json:"maximum"`
+	MinLength int            `json:"minLength"`
+	MaxLength *int           `json:"maxLength"`
+	Enum      []string       `json:"enum"`
+	Pattern   string         `json:"pattern"`
+	Items     *openAPISchema `json:"items"`
+
+	pattern *regexp.Regexp
+}
+
+type openAPIParameter struct {
+	Ref      string        `json:"$ref"`
+	Name     string        `json:"name"`
+	In       string        `json:"in"`
+	Required bool          `json:"required"`
+	Explode  *bool         `json:"explode"`
+	Schema   openAPISchema `json:"schema"`
+}
+
+type openAPIOperation struct {
+	OperationId string             `json:"operationId"`
+	Parameters  []openAPIParameter `json:"parameters"`
+}
+
+// openAPIRoute는 paths의 한 항목. segments의 "{name}"은 경로 파라미터.
+type openAPIRoute struct {
+	path       string
+	segments   []string
+	operations map[string]*openAPIOperation // 대문자 HTTP 메서드 -> 오퍼레이션
+}
+
+type openAPISpec struct {
+	routes []openAPIRoute
+}
+
+func mustParseOpenAPI(b []byte) *openAPISpec {
+	spec, err := parseOpenAPI(b)
+	if err != nil {
+		panic("openapi.json: " + err.Error())
+	}
+	return spec
+}
+
+// parseOpenAPI는 문서에서 경로와 파라미터 정의를 읽고 $ref를 풀어 둔다.
+func parseOpenAPI(b []byte) (*openAPISpec, error) {
+	var doc struct {
+		Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+		Components struct {
+			Parameters map[string]openAPIParameter `json:"parameters"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	spec := &openAPISpec{}
+	for path, methods := range doc.Paths {
+		route := openAPIRoute{path: path, segments: strings.Split(path, "/"), operations: map[string]*openAPIOperation{}}
+		for method, op := range methods {
+			for i, p := range op.Parameters {
+				if p.Ref != "" {
+					name := strings.TrimPrefix(p.Ref, "#/components/parameters/")
+					ref, ok := doc.Components.Parameters[name]
+					if !ok {
+						return nil, fmt.Errorf("%s %s: unknown parameter %s", method, path, p.Ref)
+					}
+					p = ref
+				}
+				if err := p.Schema.compile(); err != nil {
+					return nil, fmt.Errorf("%s %s: parameter %s: %w", method, path, p.Name, err)
+				}
+				op.Parameters[i] = p
+			}
+			route.operations[strings.ToUpper(method)] = op
+		}
+		spec.routes = append(spec.routes, route)
+	}
+	return spec, nil
+}
+
+func (s *openAPISchema) compile() error {
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.pattern = re
+	}
+	if s.Items != nil {
+		return s.Items.compile()
+	}
+	return nil
+}
+
+// match는 요청 경로에 맞는 route와 경로 파라미터 값을 찾는다. 여럿이 맞으면
+// 고정 세그먼트가 많은 쪽(/api/whatsnews/lookup이 /api/whatsnews/{id}보다 우선)을 고른다.
+func (s *openAPISpec) match(path string) (*openAPIRoute, map[string]string) {
+	segments := strings.Split(path, "/")
+	var (
+		best       *openAPIRoute
+		bestValues map[string]string
+		bestFixed  = -1
+	)
+	for i := range s.routes {
+		route := &s.routes[i]
+		if len(route.segments) != len(segments) {
+			continue
+		}
+		values := map[string]string{}
+		fixed := 0
+		ok := true
+		for j, seg := range route.segments {
+			if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+				if segments[j] == "" {
+					ok = false
+					break
+				}
+				v, err := url.PathUnescape(segments[j])
+				if err != nil {
+					v = segments[j]
+				}
+				values[seg[1:len(seg)-1]] = v
+				continue
+			}
+			if seg != segments[j] {
+				ok = false
+				break
+			}
+			fixed++
+		}
+		if ok && fixed > bestFixed {
+			best, bestValues, bestFixed = route, values, fixed
+		}
+	}
+	return best, bestValues
+}
+
+// validate는 요청의 경로/쿼리 파라미터를 오퍼레이션 정의와 맞춰 본다.
+// 문서에 없는 쿼리 파라미터는 검사하지 않는다.
+func (op *openAPIOperation) validate(query url.Values, pathValues map[string]string) []ParamError {
+	var errs []ParamError
+	for _, p := range op.Parameters {
+		var values []string
+		switch p.In {
+		case "path":
+			if v := pathValues[p.Name]; v != "" {
+				values = []string{v}
+			}
+		case "query":
+			values = query[p.Name]
+		default:
+			continue
+		}
+		if msg := p.check(values); msg != "" {
+			errs = append(errs, ParamError{In: p.In, Name: p.Name, Message: msg})
+		}
+	}
+	return errs
+}
+
+// check는 파라미터 값들이 정의에 맞는지 보고, 맞지 않으면 이유를 돌려준다.
+// 빈 값은 주지 않은 것으로 본다 (핸들러도 그렇게 읽는다).
+func (p openAPIParameter) check(values []string) string {
+	present := false
+	for _, v := range values {
+		if v != "" {
+			present = true
+		}
+	}
+	if !present {
+		if p.Required {
+			return "is required"
+		}
+		return ""
+	}
+
+	if p.Schema.Type != "array" {
+		return p.Schema.check(values[0])
+	}
+	// style=form: explode=false는 "a,b", explode=true(기본)는 ?x=a&x=b
+	items := values
+	if p.Explode != nil && !*p.Explode {
+		items = splitCSV(values[0])
+	}
+	if p.Schema.Items == nil {
+		return ""
+	}
+	for _, v := range items {
+		if v == "" {
+			continue
+		}
+		if msg := p.Schema.Items.check(v); msg != "" {
+			return fmt.Sprintf("item %q %s", v, msg)
+		}
+	}
+	return ""
+}
+
+func (s openAPISchema) check(v string) string {
+	switch s.Type {
+	case "integer":
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return "must be an integer"
+		}
+		if msg := rangeMessage(s.Minimum, s.Maximum, float64(n)); msg != "" {
+			return msg
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(v); err != nil {
+			return "must be true or false"
+		}
+	case "string":
+		if len(v) < s.MinLength {
+			return fmt.Sprintf("must be at least %d characters", s.MinLength)
+		}
+		if s.MaxLength != nil && utf8.RuneCountInString(v) > *s.MaxLength {
+			return fmt.Sprintf("must be at most %d characters", *s.MaxLength)
+		}
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if v == e {
+				found = true
+			}
+		}
+		if !found {
+			return "must be one of " + strings.Join(s.Enum, ", ")
+		}
+	}
+	if s.pattern != nil && !s.pattern.MatchString(v) {
+		return "must match " + s.Pattern
+	}
+	return ""
+}
+
+// rangeMessage는 범위를 벗어난 값에 대한 메시지. 핸들러의 intQueryParam과 같은 문구를 쓴다.
+func rangeMessage(min, max *float64, n float64) string {
+	switch {
+	case min != nil && max != nil && (n < *min || n > *max):
+		return fmt.Sprintf("must be between %g and %g", *min, *max)
+	case min != nil && n < *min:
+		return fmt.Sprintf("must be >= %g", *min)
+	case max != nil && n > *max:
+		return fmt.Sprintf("must be <= %g", *max)
+	}
+	return ""
+}
+
+// ValidateRequests는 openapi.json에 정의된 오퍼레이션의 경로/쿼리 파라미터를 검사해
+// 맞지 않으면 400과 APIError 본문으로 응답한다. 문서에 없는 경로나 메서드는 그대로 넘겨
+// 핸들러가 404/405를 내게 한다.
+func ValidateRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathValues := apiSpec.match(r.URL.Path)
+		if route != nil {
+			if op := route.operations[r.Method]; op != nil {
+				if errs := op.validate(r.URL.Query(), pathValues); len(errs) > 0 {
+					writeAPIError(w, r, http.StatusBadRequest, APIError{
+						Code:    "invalid_parameter",
+						Message: errs[0].Error(),
+						Errors:  errs,
+					})
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
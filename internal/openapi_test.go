@@ -0,0 +1,392 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openAPIDoc은 테스트에서 명세를 통째로 다루기 위한 일반 JSON 값.
+type openAPIDoc map[string]any
+
+func loadOpenAPIDoc(t *testing.T) openAPIDoc {
+	t.Helper()
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPIDocument, &doc); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+	return doc
+}
+
+// resolve는 "#/a/b" 형태의 $ref를 따라간다. $ref가 아니면 그대로 돌려준다.
+func (d openAPIDoc) resolve(t *testing.T, v map[string]any) map[string]any {
+	t.Helper()
+	ref, ok := v["$ref"].(string)
+	if !ok {
+		return v
+	}
+	var cur any = map[string]any(d)
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			t.Fatalf("$ref %s does not resolve", ref)
+		}
+		cur = m[part]
+	}
+	m, ok := cur.(map[string]any)
+	if !ok {
+		t.Fatalf("$ref %s does not resolve", ref)
+	}
+	return m
+}
+
+// checkSchema는 응답 값이 스키마(type, nullable, required, properties, items, enum)에 맞는지 본다.
+func (d openAPIDoc) checkSchema(t *testing.T, where string, schema map[string]any, v any) {
+	t.Helper()
+	schema = d.resolve(t, schema)
+	if v == nil {
+		if schema["nullable"] != true {
+			t.Errorf("%s: null but schema is not nullable", where)
+		}
+		return
+	}
+	switch schema["type"] {
+	case "object":
+		m, ok := v.(map[string]any)
+		if !ok {
+			t.Errorf("%s: got %T, want object", where, v)
+			return
+		}
+		for _, name := range schema["required"].([]any) {
+			if _, ok := m[name.(string)]; !ok {
+				t.Errorf("%s: missing required property %q", where, name)
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for name, ps := range props {
+			if pv, ok := m[name]; ok {
+				d.checkSchema(t, where+"."+name, ps.(map[string]any), pv)
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			t.Errorf("%s: got %T, want array", where, v)
+			return
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, it := range arr {
+				d.checkSchema(t, fmt.Sprintf("%s[%d]", where, i), items, it)
+			}
+		}
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			t.Errorf("%s: got %T, want string", where, v)
+			return
+		}
+		if enum, ok := schema["enum"].([]any); ok {
+			found := false
+			for _, e := range enum {
+				found = found || e == s
+			}
+			if !found {
+				t.Errorf("%s: %q not in %v", where, s, enum)
+			}
+		}
+	case "integer":
+		if n, ok := v.(float64); !ok || n != math.Trunc(n) {
+			t.Errorf("%s: got %v, want integer", where, v)
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			t.Errorf("%s: got %T, want number", where, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			t.Errorf("%s: got %T, want boolean", where, v)
+		}
+	}
+}
+
+type contractOperation struct {
+	method, path string
+	op           map[string]any
+	params       []map[string]any
+}
+
+func (d openAPIDoc) operations(t *testing.T) []contractOperation {
+	var out []contractOperation
+	for path, item := range d["paths"].(map[string]any) {
+		for method, op := range item.(map[string]any) {
+			o := contractOperation{method: strings.ToUpper(method), path: path, op: op.(map[string]any)}
+			params, _ := o.op["parameters"].([]any)
+			for _, p := range params {
+				o.params = append(o.params, d.resolve(t, p.(map[string]any)))
+			}
+			out = append(out, o)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].method+out[i].path < out[j].method+out[j].path })
+	return out
+}
+
+// url은 경로 파라미터와 필수 쿼리 파라미터를 example로 채운 요청 주소. override의 값이 우선하고,
+// 값이 nil이면 그 파라미터를 뺀다.
+func (o contractOperation) url(t *testing.T, override map[string]*string) string {
+	t.Helper()
+	path := o.path
+	query := url.Values{}
+	for _, p := range o.params {
+		name, in := p["name"].(string), p["in"].(string)
+		var value *string
+		if ex, ok := p["example"]; ok && (in == "path" || p["required"] == true) {
+			s := fmt.Sprint(ex)
+			value = &s
+		}
+		if v, ok := override[name]; ok {
+			value = v
+		}
+		if value == nil {
+			if in == "path" {
+				t.Fatalf("%s %s: path parameter %s has no example", o.method, o.path, name)
+			}
+			continue
+		}
+		switch in {
+		case "path":
+			path = strings.Replace(path, "{"+name+"}", url.PathEscape(*value), 1)
+		case "query":
+			query.Set(name, *value)
+		}
+	}
+	if len(query) > 0 {
+		return path + "?" + query.Encode()
+	}
+	return path
+}
+
+// boundaryValues는 정수 파라미터의 최소/최대 값. 명세가 핸들러보다 느슨하면 이 값에서 드러난다.
+func boundaryValues(p map[string]any) []string {
+	schema := p["schema"].(map[string]any)
+	if schema["type"] != "integer" {
+		return nil
+	}
+	var out []string
+	for _, k := range []string{"minimum", "maximum"} {
+		if v, ok := schema[k].(float64); ok {
+			out = append(out, fmt.Sprint(v))
+		}
+	}
+	return out
+}
+
+// invalidValues는 파라미터 정의에 어긋나는 값들. nil은 필수 파라미터를 뺀 경우.
+func invalidValues(p map[string]any) []*string {
+	str := func(s string) *string { return &s }
+	var out []*string
+	if p["required"] == true && p["in"] == "query" {
+		out = append(out, nil)
+	}
+	schema := p["schema"].(map[string]any)
+	itemPrefix := ""
+	if schema["type"] == "array" {
+		schema = schema["items"].(map[string]any)
+		itemPrefix = "1,"
+		if p["explode"] != false {
+			itemPrefix = ""
+		}
+	}
+	switch schema["type"] {
+	case "integer":
+		out = append(out, str(itemPrefix+"x"))
+		if min, ok := schema["minimum"].(float64); ok {
+			out = append(out, str(itemPrefix+fmt.Sprint(min-1)))
+		}
+		if max, ok := schema["maximum"].(float64); ok {
+			out = append(out, str(itemPrefix+fmt.Sprint(max+1)))
+		}
+	case "boolean":
+		out = append(out, str("maybe"))
+	case "string":
+		if _, ok := schema["enum"]; ok {
+			out = append(out, str(itemPrefix+"bogus"))
+		}
+		if _, ok := schema["pattern"]; ok {
+			out = append(out, str(itemPrefix+"bogus"))
+		}
+		if max, ok := schema["maxLength"].(float64); ok {
+			out = append(out, str(itemPrefix+strings.Repeat("a", int(max)+1)))
+		}
+	}
+	return out
+}
+
+func contractStore(t *testing.T) Store {
+	ctx := context.Background()
+	s := newTestStore(t)
+	if err := s.InsertAwsItem(ctx, awsTestItem("rds", "Amazon RDS supports new engine in Seoul", "2024-06-01T20:00:00Z", "Amazon RDS")); err != nil {
+		t.Fatalf("InsertAwsItem: %v", err)
+	}
+	published := mustTime(t, "2024-06-01T21:00:00Z")
+	if _, err := s.IngestSourceItem(ctx, SourceItem{
+		Type: SourceRSS, SourceId: "guid-1", Title: "Amazon RDS now supports new engine in Seoul",
+		Url: "https://aws.amazon.com/blogs/rds-engine", PublishedAt: &published,
+	}); err != nil {
+		t.Fatalf("IngestSourceItem: %v", err)
+	}
+	return s
+}
+
+// serve는 요청 하나를 처리한다. 끝나지 않는 응답(/api/stream)도 돌아오도록 시한을 둔다.
+func serve(h http.Handler, method, target string, body []byte) *httptest.ResponseRecorder {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequestWithContext(ctx, method, target, bytes.NewReader(body)))
+	return rec
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	doc := loadOpenAPIDoc(t)
+	if v, _ := doc["openapi"].(string); !strings.HasPrefix(v, "3.") {
+		t.Fatalf("openapi version: got %q", v)
+	}
+
+	ids := map[string]string{}
+	for _, o := range doc.operations(t) {
+		id, _ := o.op["operationId"].(string)
+		if id == "" {
+			t.Errorf("%s %s: no operationId", o.method, o.path)
+		} else if prev, dup := ids[id]; dup {
+			t.Errorf("operationId %s used by %s and %s %s", id, prev, o.method, o.path)
+		}
+		ids[id] = o.method + " " + o.path
+		if len(o.op["responses"].(map[string]any)) == 0 {
+			t.Errorf("%s %s: no responses", o.method, o.path)
+		}
+		for _, m := range regexp.MustCompile(`\{(\w+)\}`).FindAllStringSubmatch(o.path, -1) {
+			found := false
+			for _, p := range o.params {
+				found = found || (p["in"] == "path" && p["name"] == m[1] && p["required"] == true)
+			}
+			if !found {
+				t.Errorf("%s %s: path parameter %s is not declared as required", o.method, o.path, m[1])
+			}
+		}
+	}
+
+	// http.go에 등록한 API 라우트는 모두 문서에 있어야 한다
+	src, err := os.ReadFile("http.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := doc["paths"].(map[string]any)
+	for _, m := range regexp.MustCompile(`"(/(?:api|feed|calendar|health)[^"]*)"`).FindAllStringSubmatch(string(src), -1) {
+		if _, ok := paths[m[1]]; !ok {
+			t.Errorf("route %s is not in openapi.json", m[1])
+		}
+	}
+}
+
+func TestOpenAPIContract(t *testing.T) {
+	doc := loadOpenAPIDoc(t)
+	store := contractStore(t)
+	raw := newMux(store, nil, NewStreamBroker(store), NewAssets(""))
+	// 인벤토리 변경도 다루도록 admin 키로 보낸다
+	secret, _, err := IssueAPIKey(context.Background(), store, "contract", []string{ScopeAdmin})
+	if err != nil {
+		t.Fatalf("IssueAPIKey: %v", err)
+	}
+	handler := NewHTTPHandler(store, Config{})
+	validated := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+secret)
+		handler.ServeHTTP(w, r)
+	})
+
+	for _, o := range doc.operations(t) {
+		responses := o.op["responses"].(map[string]any)
+
+		// 예시 값으로 보낸 요청은 문서에 있는 2xx 응답과 스키마를 돌려줘야 한다
+		var body []byte
+		if rb, ok := o.op["requestBody"].(map[string]any); ok {
+			ex := rb["content"].(map[string]any)["application/json"].(map[string]any)["example"]
+			body, _ = json.Marshal(ex)
+		}
+		target := o.url(t, nil)
+		rec := serve(validated, o.method, target, body)
+		resp, ok := responses[fmt.Sprint(rec.Code)].(map[string]any)
+		if !ok || rec.Code >= 300 {
+			t.Errorf("%s %s: got %d, want a documented 2xx\n%s", o.method, target, rec.Code, rec.Body)
+			continue
+		}
+		resp = doc.resolve(t, resp)
+		content, _ := resp["content"].(map[string]any)
+		got := rec.Header().Get("Content-Type")
+		matched := len(content) == 0
+		for ctype, media := range content {
+			if !strings.HasPrefix(got, ctype) {
+				continue
+			}
+			matched = true
+			if !strings.HasSuffix(ctype, "json") {
+				continue
+			}
+			var v any
+			if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+				t.Errorf("%s %s: invalid JSON: %v", o.method, target, err)
+				continue
+			}
+			doc.checkSchema(t, o.method+" "+o.path, media.(map[string]any)["schema"].(map[string]any), v)
+		}
+		if !matched {
+			t.Errorf("%s %s: Content-Type %q is not documented", o.method, target, got)
+		}
+
+		// 명세가 허용하는 경계 값은 핸들러도 받아야 한다 (path의 id는 없는 발표라 404일 수 있다)
+		for _, p := range o.params {
+			name := p["name"].(string)
+			for _, v := range boundaryValues(p) {
+				target := o.url(t, map[string]*string{name: &v})
+				for handlerName, h := range map[string]http.Handler{"validated": validated, "handler": raw} {
+					if rec := serve(h, o.method, target, body); rec.Code == http.StatusBadRequest {
+						t.Errorf("%s %s (%s): got 400 for a documented value\n%s", o.method, target, handlerName, rec.Body)
+					}
+				}
+			}
+		}
+
+		// 정의에 어긋나는 파라미터는 명세 검증과 핸들러 모두 400 + Error 본문으로 거부해야 한다
+		for _, p := range o.params {
+			name := p["name"].(string)
+			for _, bad := range invalidValues(p) {
+				target := o.url(t, map[string]*string{name: bad})
+				for handlerName, h := range map[string]http.Handler{"validated": validated, "handler": raw} {
+					rec := serve(h, o.method, target, body)
+					if rec.Code != http.StatusBadRequest {
+						t.Errorf("%s %s (%s): got %d, want 400", o.method, target, handlerName, rec.Code)
+						continue
+					}
+					var e APIError
+					if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil || e.Code != "invalid_parameter" || len(e.Errors) == 0 || e.Errors[0].Name != name {
+						t.Errorf("%s %s (%s): got body %s", o.method, target, handlerName, rec.Body)
+					}
+					var v any
+					_ = json.Unmarshal(rec.Body.Bytes(), &v)
+					doc.checkSchema(t, o.method+" "+o.path+" 400", map[string]any{"$ref": "#/components/schemas/Error"}, v)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"html/template"
+	"strings"
+)
+
+// permalinkSummaryRunes는 미리보기(og:description)에 쓰는 본문 길이.
+const permalinkSummaryRunes = 300
+
+// permalinkTemplate은 /whatsnews/{id} 페이지. 메신저 미리보기를 위해 Open Graph 태그를 넣고,
+// 본문은 출처마다 마크업 신뢰도가 달라 평문으로만 보여준다.
+var permalinkTemplate = template.Must(template.New("permalink").Funcs(template.FuncMap{
+	"permalink": permalinkPath,
+	"sourceURL": absoluteSourceURL,
+}).Parse(`<!doctype html>
+<html lang="ko">
+  <head>
+    <meta charset="UTF-8" />
+    <title>{{.Item.Title}}</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1.0" />
+    <meta property="og:type" content="article" />
+    <meta property="og:title" content="{{.Item.Title}}" />
+    <meta property="og:description" content="{{.Summary}}" />
+    {{- with .Item.SourceCreatedAt}}
+    <meta property="article:published_time" content="{{.Format "2006-01-02T15:04:05Z07:00"}}" />
+    {{- end}}
+    <script src="https://cdn.jsdelivr.net/npm/@tailwindcss/browser@4"></script>
+  </head>
+  <body class="bg-gray-50 text-gray-800">
+    <main class="max-w-3xl mx-auto px-6 py-10">
+      <a href="/" class="text-sm text-blue-700 hover:underline">← AWS 뉴스 목록</a>
+      <h1 class="text-2xl font-semibold mt-4 mb-2 leading-tight">{{.Item.Title}}</h1>
+      <div class="text-gray-500 text-sm mb-3">
+        {{- with .Item.SourceCreatedAt}}{{.Format "2006-01-02"}}{{end -}}
+      </div>
+      <div class="flex flex-wrap gap-2 mb-5">
+        {{- range .Item.Tags}}
+        <span class="px-3 py-0.5 rounded-2xl border text-blue-700 bg-blue-50 border-blue-200 text-xs">{{.Name}}</span>
+        {{- end}}
+      </div>
+      {{- range .Paragraphs}}
+      <p class="mb-3 leading-relaxed">{{.}}</p>
+      {{- end}}
+      <ul class="mt-5 text-sm">
+        {{- range .Item.Sources}}{{if .Url}}
+        <li><a href="{{sourceURL .Url}}" target="_blank" rel="noopener" class="text-blue-700 hover:underline">{{.Type}} 원문</a></li>
+        {{- end}}{{end}}
+      </ul>
+      {{- if .Related}}
+      <h2 class="text-lg font-semibold mt-10 mb-3">관련 발표</h2>
+      <ul class="space-y-2">
+        {{- range .Related}}
+        <li>
+          <a href="{{permalink .Id}}" class="text-blue-700 hover:underline">{{.Title}}</a>
+          <span class="text-gray-500 text-sm">{{with .SourceCreatedAt}}{{.Format "2006-01-02"}}{{end}}</span>
+        </li>
+        {{- end}}
+      </ul>
+      {{- end}}
+    </main>
+  </body>
+</html>
+`))
+
+// permalinkPage는 permalinkTemplate에 넘기는 값.
+type permalinkPage struct {
+	WhatsNewsDetail
+	Summary    string
+	Paragraphs []string
+}
+
+func newPermalinkPage(d WhatsNewsDetail) permalinkPage {
+	p := permalinkPage{WhatsNewsDetail: d}
+	// stripHTML은 줄바꿈을 남기지 않으므로 마크업의 문단 태그로 먼저 나눈다
+	for _, block := range strings.Split(d.Item.Content, "</p>") {
+		if text := stripHTML(block); text != "" {
+			p.Paragraphs = append(p.Paragraphs, text)
+		}
+	}
+	p.Summary = truncateSummary(strings.Join(p.Paragraphs, " "))
+	return p
+}
+
+// truncateSummary는 평문을 permalinkSummaryRunes 글자로 자른다.
+func truncateSummary(text string) string {
+	summary := []rune(text)
+	if len(summary) > permalinkSummaryRunes {
+		summary = append(summary[:permalinkSummaryRunes], '…')
+	}
+	return string(summary)
+}
+
+// absoluteSourceURL은 aws.amazon.com 기준 상대 경로로 저장된 출처 URL을 절대 URL로 만든다.
+func absoluteSourceURL(u string) string {
+	if u == "" || strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+		return u
+	}
+	if !strings.HasPrefix(u, "/") {
+		u = "/" + u
+	}
+	return "https://aws.amazon.com" + u
+}
@@ -0,0 +1,305 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgStore는 PostgreSQL 기반 Store 구현.
+type PgStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPgStore(pool *pgxpool.Pool) *PgStore {
+	return &PgStore{pool: pool}
+}
+
+func (s *PgStore) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+func (s *PgStore) Close() {
+	s.pool.Close()
+}
+
+func (s *PgStore) SourceIdExists(ctx context.Context, sourceId string) (bool, error) {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	var exists bool
+	err = conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM whatsnews WHERE source_id = $1)", sourceId).Scan(&exists)
+	return exists, err
+}
+
+func (s *PgStore) InsertAwsItem(ctx context.Context, el AwsApiItem) error {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	title, body, url, sourceTime := awsItemFields(el)
+	sourceTime = utcTime(sourceTime)
+	payload, err := el.payload()
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	canonicalURL := normalizeURL(url)
+
+	var whatsnewsID int
+	err = tx.QueryRow(ctx, "SELECT id FROM whatsnews WHERE source_id = $1", el.Item.Id).Scan(&whatsnewsID)
+	switch {
+	case err == nil:
+		// 원본 payload가 없던 기존 행은 이번에 받은 payload로 채운다
+		_, err = tx.Exec(ctx,
+			"UPDATE whatsnews SET raw_payload = $2 WHERE id = $1 AND raw_payload IS NULL", whatsnewsID, payload)
+	case errors.Is(err, pgx.ErrNoRows):
+		item := SourceItem{Type: SourceAwsApi, Title: title, Url: url, PublishedAt: sourceTime}
+		var (
+			method string
+			score  float64
+		)
+		whatsnewsID, method, score, err = pgFindCanonical(ctx, tx, item, canonicalURL)
+		if err != nil {
+			return fmt.Errorf("find canonical: %w", err)
+		}
+		if whatsnewsID > 0 {
+			// 메일/RSS로 먼저 만들어진 레코드를 API 항목으로 대체한다
+			if err := pgDemotePrimary(ctx, tx, whatsnewsID, method, score); err != nil {
+				return fmt.Errorf("insert whatsnews_merges: %w", err)
+			}
+			_, err = tx.Exec(ctx,
+				`UPDATE whatsnews
+                 SET    title = $2, content = $3, source_id = $4, source_type = $5, source_url = $6,
+                        canonical_url = $7, source_created_at = $8, raw_payload = $9, updated_at = NOW()
+                 WHERE  id = $1`,
+				whatsnewsID, title, body, el.Item.Id, SourceAwsApi, url, canonicalURL, sourceTime, payload)
+		} else {
+			err = tx.QueryRow(ctx,
+				`INSERT INTO whatsnews(title, content, source_id, source_type, source_url, canonical_url, source_created_at, raw_payload, created_at, updated_at)
+                 VALUES($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+                 ON CONFLICT (source_id) DO NOTHING
+                 RETURNING id`,
+				title, body, el.Item.Id, SourceAwsApi, url, canonicalURL, sourceTime, payload,
+			).Scan(&whatsnewsID)
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				// 그 사이에 다른 수집기(collect/index.js)가 같은 항목을 넣었다. 그 행에 이어 붙인다
+				err = tx.QueryRow(ctx, "SELECT id FROM whatsnews WHERE source_id = $1", el.Item.Id).Scan(&whatsnewsID)
+			case err == nil:
+				err = pgNotifyNew(ctx, tx, whatsnewsID)
+			}
+		}
+		if err == nil {
+			_, err = linkDimensions(ctx, tx, whatsnewsID, title, body, awsTagNames(el.Tags))
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("insert/select whatsnews: %w", err)
+	}
+
+	for _, tag := range el.Tags {
+		tagID, err := upsertTag(ctx, tx, tag)
+		if err != nil {
+			return fmt.Errorf("insert/select tag: %w (name=%s)", err, tag.Name)
+		}
+		if _, err := linkTag(ctx, tx, whatsnewsID, tagID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// linkTag는 뉴스와 태그를 연결하고, 새로 연결된 경우에만 태그 카운터를 올리고 true를 돌려준다.
+func linkTag(ctx context.Context, tx pgx.Tx, whatsnewsID, tagID int) (bool, error) {
+	res, err := tx.Exec(ctx,
+		`INSERT INTO whatsnews_tags(whatsnew_id, tag_id, created_at)
+         VALUES($1, $2, NOW()) ON CONFLICT DO NOTHING`, whatsnewsID, tagID)
+	if err != nil {
+		return false, fmt.Errorf("insert whatsnews_tags: %w", err)
+	}
+	if res.RowsAffected() != 1 {
+		return false, nil
+	}
+	if err := incrementTagStats(ctx, tx, tagID); err != nil {
+		return false, fmt.Errorf("update tag_stats: %w (tag_id=%d)", err, tagID)
+	}
+	return true, nil
+}
+
+func (s *PgStore) ReprocessAwsItems(ctx context.Context) (ReprocessResult, error) {
+	var res ReprocessResult
+	if err := s.pool.QueryRow(ctx,
+		"SELECT COUNT(*) FROM whatsnews WHERE raw_payload IS NULL").Scan(&res.Skipped); err != nil {
+		return res, err
+	}
+	relinked, err := s.relinkStoredText(ctx)
+	if err != nil {
+		return res, err
+	}
+	res.Relinked = relinked
+
+	lastID := 0
+	for {
+		rows, err := s.pool.Query(ctx,
+			`SELECT id, raw_payload FROM whatsnews
+             WHERE  id > $1 AND raw_payload IS NOT NULL
+             ORDER  BY id LIMIT $2`, lastID, reprocessBatchSize)
+		if err != nil {
+			return res, err
+		}
+		batch, err := pgx.CollectRows(rows, pgx.RowToStructByPos[storedPayload])
+		if err != nil {
+			return res, err
+		}
+		if len(batch) == 0 {
+			return res, nil
+		}
+		for _, p := range batch {
+			if err := s.reprocessOne(ctx, p); err != nil {
+				log.Printf("reprocess whatsnews %d: %v", p.Id, err)
+				res.Failed++
+			} else {
+				res.Updated++
+			}
+			lastID = p.Id
+		}
+	}
+}
+
+// relinkStoredText는 원본 payload가 없는 행의 리전·서비스 연결을 저장된 제목·본문·태그로
+// 다시 만들고, 연결이 바뀐 행 수를 돌려준다. 이 행들은 넣을 때 말고는 연결할 기회가 없다.
+func (s *PgStore) relinkStoredText(ctx context.Context) (int, error) {
+	relinked, lastID := 0, 0
+	for {
+		rows, err := s.pool.Query(ctx, `
+SELECT wn.id, wn.title, COALESCE(wn.content, ''),
+       ARRAY(SELECT t.name FROM whatsnews_tags wt JOIN tags t ON t.id = wt.tag_id
+             WHERE  wt.whatsnew_id = wn.id ORDER BY t.name)
+FROM   whatsnews wn
+WHERE  wn.id > $1 AND wn.raw_payload IS NULL
+ORDER  BY wn.id LIMIT $2`, lastID, reprocessBatchSize)
+		if err != nil {
+			return relinked, err
+		}
+		batch, err := pgx.CollectRows(rows, pgx.RowToStructByPos[storedText])
+		if err != nil {
+			return relinked, err
+		}
+		if len(batch) == 0 {
+			return relinked, nil
+		}
+		tx, err := s.pool.Begin(ctx)
+		if err != nil {
+			return relinked, err
+		}
+		n := 0
+		for _, r := range batch {
+			changed, err := linkDimensions(ctx, tx, r.Id, r.Title, r.Content, r.TagNames)
+			if err != nil {
+				tx.Rollback(ctx)
+				return relinked, fmt.Errorf("relink whatsnews %d: %w", r.Id, err)
+			}
+			if changed {
+				if _, err := tx.Exec(ctx, "UPDATE whatsnews SET updated_at = NOW() WHERE id = $1", r.Id); err != nil {
+					tx.Rollback(ctx)
+					return relinked, fmt.Errorf("update whatsnews: %w", err)
+				}
+				n++
+			}
+			lastID = r.Id
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return relinked, err
+		}
+		relinked += n
+	}
+}
+
+// reprocessOne은 payload에서 컬럼, 리전·서비스, 태그를 다시 뽑는다. updated_at은 피드의 갱신
+// 시각이므로 실제로 바뀐 것이 있을 때만 올린다.
+func (s *PgStore) reprocessOne(ctx context.Context, p storedPayload) error {
+	el, err := parseAwsPayload(p.Payload)
+	if err != nil {
+		return fmt.Errorf("parse payload: %w", err)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	title, body, url, sourceTime := awsItemFields(el)
+	sourceTime = utcTime(sourceTime)
+	updated, err := tx.Exec(ctx,
+		`UPDATE whatsnews
+         SET    title = $2, content = $3, source_url = $4, canonical_url = $5, source_created_at = $6
+         WHERE  id = $1
+           AND  (title, content, source_url, canonical_url, source_created_at)
+                IS DISTINCT FROM ($2, $3, $4, $5, $6::timestamp)`, p.Id, title, body, url, normalizeURL(url), sourceTime)
+	if err != nil {
+		return fmt.Errorf("update whatsnews: %w", err)
+	}
+	changed := updated.RowsAffected() > 0
+	dims, err := linkDimensions(ctx, tx, p.Id, title, body, awsTagNames(el.Tags))
+	if err != nil {
+		return err
+	}
+	changed = changed || dims
+
+	tagIDs := make([]int, 0, len(el.Tags))
+	for _, tag := range el.Tags {
+		tagID, err := upsertTag(ctx, tx, tag)
+		if err != nil {
+			return fmt.Errorf("insert/select tag: %w (name=%s)", err, tag.Name)
+		}
+		tagIDs = append(tagIDs, tagID)
+	}
+
+	// payload에 없는 태그 연결은 끊고 카운터도 되돌린다
+	var unlinked int
+	if err := tx.QueryRow(ctx, `
+WITH removed AS (
+  DELETE FROM whatsnews_tags
+  WHERE  whatsnew_id = $1 AND NOT (tag_id = ANY($2::int[]))
+  RETURNING tag_id
+), counted AS (
+  UPDATE tag_stats s SET news_cnt = s.news_cnt - 1
+  FROM   removed r
+  WHERE  s.tag_id = r.tag_id
+)
+SELECT COUNT(*) FROM removed;
+`, p.Id, tagIDs).Scan(&unlinked); err != nil {
+		return fmt.Errorf("unlink tags: %w", err)
+	}
+	changed = changed || unlinked > 0
+	for _, tagID := range tagIDs {
+		linked, err := linkTag(ctx, tx, p.Id, tagID)
+		if err != nil {
+			return err
+		}
+		changed = changed || linked
+	}
+	if changed {
+		if _, err := tx.Exec(ctx, "UPDATE whatsnews SET updated_at = NOW() WHERE id = $1", p.Id); err != nil {
+			return fmt.Errorf("update whatsnews: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimit은 토큰 버킷 한도. 분당 PerMinute개씩 채워지고 최대 Burst개까지 쌓인다.
+type RateLimit struct {
+	PerMinute int // 0이면 제한하지 않는다
+	Burst     int // 1보다 작으면 1
+}
+
+// rateLimitSweepInterval마다 가득 찬(오래 쉰) 버킷을 지운다. 가득 찬 버킷은 없는 것과 같다.
+const rateLimitSweepInterval = time.Minute
+
+// rateLimiter는 키(클라이언트 IP, API 키 id)마다 토큰 버킷을 둔다. nil이면 모두 허용한다.
+type rateLimiter struct {
+	rate  float64 // 초당 토큰
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newRateLimiter(l RateLimit) *rateLimiter {
+	if l.PerMinute <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:    float64(l.PerMinute) / 60,
+		burst:   float64(max(l.Burst, 1)),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// take는 key의 버킷에서 토큰 하나를 쓴다. 모자라면 false와 토큰 하나가 찰 때까지의 시간.
+func (l *rateLimiter) take(key string, now time.Time) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.swept) >= rateLimitSweepInterval {
+		for k, b := range l.buckets {
+			if b.level(now, l.rate, l.burst) >= l.burst {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = b.level(now, l.rate, l.burst)
+	b.updated = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// level은 now 시점에 버킷에 있는 토큰 수.
+func (b *tokenBucket) level(now time.Time, rate, burst float64) float64 {
+	return min(burst, b.tokens+now.Sub(b.updated).Seconds()*rate)
+}
@@ -0,0 +1,280 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// regionDef는 AWS 리전 하나와 발표문에서 그 리전을 가리키는 표현들.
+type regionDef struct {
+	Code    string
+	Name    string   // What's New에서 쓰는 표기 (예: Asia Pacific (Seoul))
+	Aliases []string // 도시/지역 이름. 제목이나 본문의 리전 문구에서만 리전으로 본다
+}
+
+// awsRegions는 리전 카탈로그. 새 리전이 열리면 여기에 추가하고 cli reprocess로 다시 추출한다.
+var awsRegions = []regionDef{
+	{"us-east-1", "US East (N. Virginia)", []string{"N. Virginia", "Northern Virginia", "Virginia"}},
+	{"us-east-2", "US East (Ohio)", []string{"Ohio"}},
+	{"us-west-1", "US West (N. California)", []string{"N. California", "Northern California"}},
+	{"us-west-2", "US West (Oregon)", []string{"Oregon"}},
+	{"us-gov-east-1", "AWS GovCloud (US-East)", []string{"GovCloud (US)"}},
+	{"us-gov-west-1", "AWS GovCloud (US-West)", []string{"GovCloud (US)"}},
+	{"ca-central-1", "Canada (Central)", nil},
+	{"ca-west-1", "Canada West (Calgary)", []string{"Calgary"}},
+	{"mx-central-1", "Mexico (Central)", nil},
+	{"sa-east-1", "South America (São Paulo)", []string{"São Paulo", "Sao Paulo", "South America (Sao Paulo)"}},
+	{"eu-central-1", "Europe (Frankfurt)", []string{"Frankfurt"}},
+	{"eu-central-2", "Europe (Zurich)", []string{"Zurich"}},
+	{"eu-west-1", "Europe (Ireland)", []string{"Ireland"}},
+	{"eu-west-2", "Europe (London)", []string{"London"}},
+	{"eu-west-3", "Europe (Paris)", []string{"Paris"}},
+	{"eu-south-1", "Europe (Milan)", []string{"Milan"}},
+	{"eu-south-2", "Europe (Spain)", []string{"Spain"}},
+	{"eu-north-1", "Europe (Stockholm)", []string{"Stockholm"}},
+	{"il-central-1", "Israel (Tel Aviv)", []string{"Tel Aviv"}},
+	{"me-south-1", "Middle East (Bahrain)", []string{"Bahrain"}},
+	{"me-central-1", "Middle East (UAE)", []string{"UAE"}},
+	{"af-south-1", "Africa (Cape Town)", []string{"Cape Town"}},
+	{"ap-east-1", "Asia Pacific (Hong Kong)", []string{"Hong Kong"}},
+	{"ap-east-2", "Asia Pacific (Taipei)", []string{"Taipei"}},
+	{"ap-northeast-1", "Asia Pacific (Tokyo)", []string{"Tokyo"}},
+	{"ap-northeast-2", "Asia Pacific (Seoul)", []string{"Seoul"}},
+	{"ap-northeast-3", "Asia Pacific (Osaka)", []string{"Osaka"}},
+	{"ap-south-1", "Asia Pacific (Mumbai)", []string{"Mumbai"}},
+	{"ap-south-2", "Asia Pacific (Hyderabad)", []string{"Hyderabad"}},
+	{"ap-southeast-1", "Asia Pacific (Singapore)", []string{"Singapore"}},
+	{"ap-southeast-2", "Asia Pacific (Sydney)", []string{"Sydney"}},
+	{"ap-southeast-3", "Asia Pacific (Jakarta)", []string{"Jakarta"}},
+	{"ap-southeast-4", "Asia Pacific (Melbourne)", []string{"Melbourne"}},
+	{"ap-southeast-5", "Asia Pacific (Malaysia)", []string{"Malaysia"}},
+	{"ap-southeast-6", "Asia Pacific (New Zealand)", []string{"New Zealand"}},
+	{"ap-southeast-7", "Asia Pacific (Thailand)", []string{"Thailand"}},
+	{"cn-north-1", "China (Beijing)", []string{"Beijing"}},
+	{"cn-northwest-1", "China (Ningxia)", []string{"Ningxia"}},
+}
+
+// regionPattern은 리전 하나를 찾는 정규식. full은 코드와 표기, alias는 별칭(없으면 nil).
+// 앞뒤가 문자/숫자/하이픈이 아니어야 한다 (ap-northeast-2가 ap-northeast-20에 걸리지 않도록).
+type regionPattern struct {
+	full, alias *regexp.Regexp
+}
+
+func wordAlternation(words []string) string {
+	alts := make([]string, len(words))
+	for i, w := range words {
+		alts[i] = regexp.QuoteMeta(w)
+	}
+	return `(?i)(?:^|[^\pL\pN-])(?:` + strings.Join(alts, "|") + `)(?:$|[^\pL\pN-])`
+}
+
+// regionPatterns는 awsRegions 순서대로 만든 정규식.
+var regionPatterns = func() []regionPattern {
+	out := make([]regionPattern, len(awsRegions))
+	for i, r := range awsRegions {
+		out[i].full = regexp.MustCompile(wordAlternation([]string{r.Code, r.Name}))
+		if len(r.Aliases) > 0 {
+			out[i].alias = regexp.MustCompile(wordAlternation(r.Aliases))
+		}
+	}
+	return out
+}()
+
+// regionPhrasePattern은 본문에서 별칭을 리전으로 볼 수 있는 문구: "London Region",
+// "Seoul, Tokyo, and Osaka Regions", "(London)". 별칭만 단독으로 나오면 행사("AWS Summit
+// London")나 고객 이야기("customers in Spain")일 때가 많아 본문에서는 이 문구 안에서만 찾는다.
+var regionPhrasePattern = func() *regexp.Regexp {
+	var aliases []string
+	for _, r := range awsRegions {
+		for _, a := range r.Aliases {
+			aliases = append(aliases, regexp.QuoteMeta(a))
+		}
+	}
+	// 긴 별칭(N. Virginia)을 짧은 별칭(Virginia)보다 먼저 맞춘다
+	sort.SliceStable(aliases, func(i, j int) bool { return len(aliases[i]) > len(aliases[j]) })
+	alias := `(?:` + strings.Join(aliases, "|") + `)`
+	sep := `(?:\s*,\s*(?:and\s+)?|\s+and\s+|\s*/\s*)`
+	return regexp.MustCompile(`(?i)\(` + alias + `\)|(?:^|[^\pL\pN-])` + alias + `(?:` + sep + alias + `)*\s+(?:AWS\s+)?Regions?(?:$|[^\pL\pN-])`)
+}()
+
+// extractRegions는 제목과 본문에서 언급된 리전 코드를 카탈로그 순서로 돌려준다. 코드와 표기는
+// 어디서든, 별칭은 제목과 본문의 리전 문구(regionPhrasePattern)에서만 찾는다.
+func extractRegions(title, content string) []string {
+	body := stripHTML(content)
+	text := title + "\n" + body
+	aliasText := title + "\n" + strings.Join(regionPhrasePattern.FindAllString(body, -1), "\n")
+	var out []string
+	for i, p := range regionPatterns {
+		if p.full.MatchString(text) || p.alias != nil && p.alias.MatchString(aliasText) {
+			out = append(out, awsRegions[i].Code)
+		}
+	}
+	return out
+}
+
+// resolveRegions는 API 파라미터 값(리전 코드, 표기, 별칭; 대소문자 무시)을 리전 코드로 바꾼다.
+// 알 수 없는 값이 있으면 에러.
+func resolveRegions(values []string) ([]string, error) {
+	var out []string
+	seen := map[string]bool{}
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		code := ""
+		for _, r := range awsRegions {
+			if strings.EqualFold(v, r.Code) || strings.EqualFold(v, r.Name) {
+				code = r.Code
+				break
+			}
+			for _, a := range r.Aliases {
+				if strings.EqualFold(v, a) {
+					code = r.Code
+					break
+				}
+			}
+			if code != "" {
+				break
+			}
+		}
+		if code == "" {
+			return nil, fmt.Errorf("unknown region %q", v)
+		}
+		if !seen[code] {
+			seen[code] = true
+			out = append(out, code)
+		}
+	}
+	return out, nil
+}
+
+// Region은 /api/regions 항목.
+type Region struct {
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	NewsCount int    `json:"news_count"`
+}
+
+// regionsWithCounts는 카탈로그 전체에 DB에서 센 건수를 붙여 건수 내림차순으로 정렬한다.
+func regionsWithCounts(counts map[string]int) []Region {
+	out := make([]Region, 0, len(awsRegions))
+	for _, r := range awsRegions {
+		out = append(out, Region{Code: r.Code, Name: r.Name, NewsCount: counts[r.Code]})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].NewsCount > out[j].NewsCount })
+	return out
+}
+
+// RegionMatrixQuery는 리전 × 서비스 매트릭스 조건. Regions가 비어 있으면 모든 리전.
+type RegionMatrixQuery struct {
+	Regions []string
+}
+
+// RegionMatrixCell은 한 서비스(서비스 코드)가 한 리전에 대해 발표된 건수와 가장 최근 발표 시각.
+type RegionMatrixCell struct {
+	Region   string     `json:"region"`
+	Service  string     `json:"service"`
+	Count    int        `json:"count"`
+	LatestAt *time.Time `json:"latest_at"`
+}
+
+// RegionMatrix는 /api/regions/matrix 응답.
+type RegionMatrix struct {
+	Regions  []string           `json:"regions"`
+	Services []string           `json:"services"`
+	Cells    []RegionMatrixCell `json:"cells"`
+}
+
+// newRegionMatrix는 셀 목록에서 행·열 목록을 만든다.
+func newRegionMatrix(cells []RegionMatrixCell) RegionMatrix {
+	m := RegionMatrix{Regions: []string{}, Services: []string{}, Cells: cells}
+	if m.Cells == nil {
+		m.Cells = []RegionMatrixCell{}
+	}
+	seenR, seenS := map[string]bool{}, map[string]bool{}
+	for _, c := range cells {
+		if !seenR[c.Region] {
+			seenR[c.Region] = true
+			m.Regions = append(m.Regions, c.Region)
+		}
+		if !seenS[c.Service] {
+			seenS[c.Service] = true
+			m.Services = append(m.Services, c.Service)
+		}
+	}
+	sort.Strings(m.Regions)
+	sort.Strings(m.Services)
+	return m
+}
+
+// linkRegions는 본문에서 추출한 리전으로 whatsnews_regions를 맞추고, 연결이 바뀌었는지 돌려준다.
+func linkRegions(ctx context.Context, tx pgx.Tx, whatsnewsID int, title, content string) (bool, error) {
+	codes := extractRegions(title, content)
+	if codes == nil {
+		codes = []string{}
+	}
+	removed, err := tx.Exec(ctx,
+		`DELETE FROM whatsnews_regions WHERE whatsnew_id = $1 AND NOT (region_code = ANY($2::text[]))`,
+		whatsnewsID, codes)
+	if err != nil {
+		return false, fmt.Errorf("unlink regions: %w", err)
+	}
+	added, err := tx.Exec(ctx,
+		`INSERT INTO whatsnews_regions(whatsnew_id, region_code)
+         SELECT $1, unnest($2::text[])
+         ON CONFLICT DO NOTHING`, whatsnewsID, codes)
+	if err != nil {
+		return false, fmt.Errorf("link regions: %w", err)
+	}
+	return removed.RowsAffected()+added.RowsAffected() > 0, nil
+}
+
+func (s *PgStore) GetRegions(ctx context.Context) ([]Region, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT region_code, COUNT(*) FROM whatsnews_regions GROUP BY region_code`)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	var (
+		code string
+		n    int
+	)
+	if _, err := pgx.ForEachRow(rows, []any{&code, &n}, func() error {
+		counts[code] = n
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return regionsWithCounts(counts), nil
+}
+
+func (s *PgStore) GetRegionMatrix(ctx context.Context, q RegionMatrixQuery) (RegionMatrix, error) {
+	regions := q.Regions
+	if regions == nil {
+		regions = []string{}
+	}
+	rows, err := s.pool.Query(ctx, `
+SELECT wr.region_code, ws.service_code, COUNT(*)::int, MAX(wn.source_created_at)
+FROM   whatsnews_regions wr
+JOIN   whatsnews wn           ON wn.id = wr.whatsnew_id
+JOIN   whatsnews_services ws  ON ws.whatsnew_id = wr.whatsnew_id
+WHERE  cardinality($1::text[]) = 0 OR wr.region_code = ANY($1::text[])
+GROUP  BY wr.region_code, ws.service_code
+ORDER  BY ws.service_code, wr.region_code`, regions)
+	if err != nil {
+		return RegionMatrix{}, err
+	}
+	cells, err := pgx.CollectRows(rows, pgx.RowToStructByPos[RegionMatrixCell])
+	if err != nil {
+		return RegionMatrix{}, err
+	}
+	return newRegionMatrix(cells), nil
+}
@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractRegions(t *testing.T) {
+	cases := []struct {
+		title, content string
+		want           []string
+	}{
+		{"Amazon EC2 I4g instances are now available in AWS Asia Pacific (Seoul) Region", "", []string{"ap-northeast-2"}},
+		{"Amazon EC2 M8g instances now available in additional regions",
+			"<p>available in US East (N. Virginia), Europe (Frankfurt), and Asia Pacific (Tokyo) regions</p>",
+			[]string{"us-east-1", "eu-central-1", "ap-northeast-1"}},
+		{"Amazon S3 adds metrics", "Use ap-northeast-2 or ap-northeast-20", []string{"ap-northeast-2"}},
+		{"Amazon EKS now available in all commercial regions", "", nil},
+		// 별칭은 제목이나 본문의 리전 문구에서만 리전으로 본다
+		{"Amazon Bedrock now available in Tokyo", "", []string{"ap-northeast-1"}},
+		{"Amazon RDS adds instances", "<p>available in the London Region</p>", []string{"eu-west-2"}},
+		{"Amazon RDS adds instances", "<p>now in the Seoul, Tokyo, and Osaka Regions and Mumbai AWS Region</p>",
+			[]string{"ap-northeast-1", "ap-northeast-2", "ap-northeast-3", "ap-south-1"}},
+		{"Amazon RDS adds instances", "<p>in US East (Virginia)</p>", []string{"us-east-1"}},
+		{"AWS Lambda adds a runtime", "<p>Announced at AWS Summit London.</p>", nil},
+		{"Amazon Connect adds languages", "<p>Helps customers in Spain and Ireland, and offices in Virginia.</p>", nil},
+		{"Amazon Q adds features", "<p>Built with partners in Singapore. Available in the Europe (Spain) Region.</p>", []string{"eu-south-2"}},
+	}
+	for _, c := range cases {
+		if got := extractRegions(c.title, c.content); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("extractRegions(%q): got %v, want %v", c.title, got, c.want)
+		}
+	}
+}
+
+func TestResolveRegions(t *testing.T) {
+	got, err := resolveRegions([]string{"Seoul", "ap-northeast-1", "US East (N. Virginia)", "seoul"})
+	want := []string{"ap-northeast-2", "ap-northeast-1", "us-east-1"}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveRegions: got %v, %v, want %v", got, err, want)
+	}
+	if _, err := resolveRegions([]string{"atlantis"}); err == nil {
+		t.Error("resolveRegions(atlantis): expected error")
+	}
+}
@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// relatedTitleWeight는 제목 유사도(0~1)를 공유 태그 몇 개로 칠지
+	relatedTitleWeight = 3.0
+	// relatedMinSimilarity는 공유 태그가 없을 때 관련 발표로 볼 최소 제목 유사도 (pg_trgm 기본 임계값)
+	relatedMinSimilarity = 0.3
+	// relatedCandidates는 공유 태그/제목 각각에서 가져올 후보 수
+	relatedCandidates = 200
+	// relatedIgnoredNamespace의 태그(발표 연도)는 거의 모든 발표가 공유하므로 세지 않는다
+	relatedIgnoredNamespace = "year"
+)
+
+// WhatsNewsDetail은 /api/whatsnews/{id} 응답.
+type WhatsNewsDetail struct {
+	Item      WhatsNews          `json:"item"`
+	Related   []RelatedWhatsNews `json:"related"`
+	Permalink string             `json:"permalink"`
+}
+
+// RelatedWhatsNews는 관련 발표. Score = SharedTags + relatedTitleWeight × TitleSimilarity.
+type RelatedWhatsNews struct {
+	Id              int        `json:"id"`
+	Title           string     `json:"title"`
+	SourceUrl       string     `json:"source_url"`
+	SourceCreatedAt *time.Time `json:"source_created_at"`
+	SharedTags      int        `json:"shared_tags"`
+	TitleSimilarity float64    `json:"title_similarity"`
+	Score           float64    `json:"score"`
+}
+
+// permalinkPath는 발표 하나를 보여주는 HTTP 서버 경로.
+func permalinkPath(id int) string {
+	return "/whatsnews/" + strconv.Itoa(id)
+}
+
+// rankRelated는 후보에 제목 유사도를 더해 점수순으로 limit개를 고른다. 공유 태그도 없고
+// 제목도 충분히 비슷하지 않은 후보는 버린다.
+func rankRelated(title string, cands []RelatedWhatsNews, limit int) []RelatedWhatsNews {
+	out := []RelatedWhatsNews{}
+	for _, c := range cands {
+		c.TitleSimilarity = titleSimilarity(title, c.Title)
+		if c.SharedTags == 0 && c.TitleSimilarity < relatedMinSimilarity {
+			continue
+		}
+		c.Score = float64(c.SharedTags) + relatedTitleWeight*c.TitleSimilarity
+		out = append(out, c)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.SourceCreatedAt != nil && b.SourceCreatedAt != nil && !a.SourceCreatedAt.Equal(*b.SourceCreatedAt) {
+			return a.SourceCreatedAt.After(*b.SourceCreatedAt)
+		}
+		return a.Id > b.Id
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// relatedFTSQuery는 제목 단어 중 하나라도 제목에 있는 발표를 찾는 FTS5 쿼리. 단어가 없으면 빈 문자열.
+func relatedFTSQuery(title string) string {
+	var words []string
+	seen := map[string]bool{}
+	for _, w := range searchWords(title) {
+		if len([]rune(w)) < 3 || seen[w] {
+			continue
+		}
+		seen[w] = true
+		words = append(words, `"`+w+`"`)
+	}
+	if len(words) == 0 {
+		return ""
+	}
+	return "{title} : (" + strings.Join(words, " OR ") + ")"
+}
+
+func (s *PgStore) GetWhatsnewsItem(ctx context.Context, id int) (WhatsNews, error) {
+	res, err := s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 1, IDs: []int{id}})
+	if err != nil {
+		return WhatsNews{}, err
+	}
+	if len(res.Items) == 0 {
+		return WhatsNews{}, ErrNotFound
+	}
+	return res.Items[0], nil
+}
+
+// FindWhatsnewsBySource는 레코드 자체의 source_id, 또는 병합된 출처의 식별자
+// ("mail:<url>" 형태 또는 출처 안의 id)로 whatsnews id를 찾는다.
+func (s *PgStore) FindWhatsnewsBySource(ctx context.Context, sourceID string) (int, error) {
+	var id int
+	err := s.pool.QueryRow(ctx, `
+SELECT id FROM (
+  SELECT id, 0 AS prio FROM whatsnews WHERE source_id = $1
+  UNION ALL
+  SELECT whatsnew_id, 1 FROM whatsnews_merges
+  WHERE  undone_at IS NULL AND (source_id = $1 OR source_type || ':' || source_id = $1)
+) x
+ORDER BY prio, id
+LIMIT 1`, sourceID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return id, err
+}
+
+func (s *PgStore) GetRelatedWhatsnews(ctx context.Context, id, limit int) ([]RelatedWhatsNews, error) {
+	var title string
+	err := s.pool.QueryRow(ctx, `SELECT title FROM whatsnews WHERE id = $1`, id).Scan(&title)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 제목 후보는 pg_trgm % 연산자(idx_whatsnews_title_trgm)로 찾는다
+	rows, err := s.pool.Query(ctx, `
+WITH shared AS (
+  SELECT o.whatsnew_id AS id, COUNT(*)::int AS shared_tags
+  FROM   whatsnews_tags mine
+  JOIN   tags t ON t.id = mine.tag_id
+  JOIN   whatsnews_tags o ON o.tag_id = mine.tag_id AND o.whatsnew_id <> $1
+  WHERE  mine.whatsnew_id = $1 AND t.namespace IS DISTINCT FROM $3
+  GROUP  BY o.whatsnew_id
+  ORDER  BY shared_tags DESC, o.whatsnew_id DESC
+  LIMIT  $4
+), similar AS (
+  SELECT id FROM whatsnews
+  WHERE  title % $2 AND id <> $1
+  ORDER  BY similarity(title, $2) DESC
+  LIMIT  $4
+)
+SELECT wn.id, wn.title, COALESCE(wn.source_url, ''), wn.source_created_at,
+       COALESCE(s.shared_tags, 0), 0::float8, 0::float8
+FROM   (SELECT id FROM shared UNION SELECT id FROM similar) c
+JOIN   whatsnews wn ON wn.id = c.id
+LEFT   JOIN shared s ON s.id = c.id`, id, title, relatedIgnoredNamespace, relatedCandidates)
+	if err != nil {
+		return nil, err
+	}
+	cands, err := pgx.CollectRows(rows, pgx.RowToStructByPos[RelatedWhatsNews])
+	if err != nil {
+		return nil, err
+	}
+	return rankRelated(title, cands, limit), nil
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRankRelated(t *testing.T) {
+	title := "Amazon RDS for PostgreSQL supports minor version 16.3"
+	cands := []RelatedWhatsNews{
+		{Id: 1, Title: "Amazon RDS for PostgreSQL supports minor version 16.4", SharedTags: 2},
+		{Id: 2, Title: "AWS Lambda supports Python 3.13", SharedTags: 1},
+		{Id: 3, Title: "Amazon RDS for PostgreSQL supports minor versions 15.7", SharedTags: 0},
+		{Id: 4, Title: "Amazon QuickSight launches new visuals", SharedTags: 0},
+	}
+	got := rankRelated(title, cands, 10)
+	var ids []int
+	for _, r := range got {
+		ids = append(ids, r.Id)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 3 || ids[2] != 2 {
+		t.Fatalf("rankRelated: got %v, want [1 3 2]", ids)
+	}
+	if got[0].Score <= got[1].Score || got[0].TitleSimilarity < 0.5 {
+		t.Errorf("scores: got %+v", got)
+	}
+	if n := len(rankRelated(title, cands, 1)); n != 1 {
+		t.Errorf("limit: got %d items", n)
+	}
+}
+
+func TestNewPermalinkPage(t *testing.T) {
+	p := newPermalinkPage(WhatsNewsDetail{Item: WhatsNews{
+		Content: "<p>First <b>paragraph</b>.</p>\n<p>Second &amp; last.</p>",
+	}})
+	if len(p.Paragraphs) != 2 || p.Paragraphs[0] != "First paragraph ." || p.Paragraphs[1] != "Second & last." {
+		t.Errorf("paragraphs: got %q", p.Paragraphs)
+	}
+	if p.Summary != "First paragraph . Second & last." {
+		t.Errorf("summary: got %q", p.Summary)
+	}
+
+	published := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	p.Item = WhatsNews{Id: 7, Title: "A <b>title</b>", SourceCreatedAt: &published,
+		Sources: []NewsSource{{Type: SourceAwsApi, Url: "/about-aws/whats-new/x"}}}
+	p.Related = []RelatedWhatsNews{{Id: 8, Title: "Other"}}
+	var b strings.Builder
+	if err := permalinkTemplate.Execute(&b, p); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	for _, want := range []string{"A &lt;b&gt;title&lt;/b&gt;", `href="https://aws.amazon.com/about-aws/whats-new/x"`, `href="/whatsnews/8"`, "2024-06-01"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("page missing %q", want)
+		}
+	}
+}
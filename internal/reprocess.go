@@ -0,0 +1,26 @@
+package internal
+
+// reprocessBatchSize는 원본 payload를 다시 처리할 때 한 번에 읽는 행 수.
+const reprocessBatchSize = 500
+
+// ReprocessResult는 Store.ReprocessAwsItems의 처리 결과.
+type ReprocessResult struct {
+	Updated  int `json:"updated"`  // 다시 계산된 행
+	Failed   int `json:"failed"`   // payload 파싱이나 갱신에 실패한 행 (로그 참고)
+	Skipped  int `json:"skipped"`  // 원본 payload가 없어 컬럼과 태그는 그대로 둔 행
+	Relinked int `json:"relinked"` // 그중 저장된 제목·본문·태그로 리전·서비스 연결이 바뀐 행
+}
+
+type storedPayload struct {
+	Id      int
+	Payload []byte
+}
+
+// storedText는 원본 payload가 없는 행(메일, RSS)에서 리전·서비스를 다시
+// 뽑을 때 쓰는 저장된 값.
+type storedText struct {
+	Id       int
+	Title    string
+	Content  string
+	TagNames []string
+}
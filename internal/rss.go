@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AwsWhatsNewFeedURL은 AWS What's New RSS 피드 주소.
+const AwsWhatsNewFeedURL = "https://aws.amazon.com/about-aws/whats-new/recent/feed/"
+
+type rssFeed struct {
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type rssItem struct {
+	Guid        string `xml:"guid"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+}
+
+// sourceItem은 RSS 항목을 SourceItem으로 만든다. guid가 없으면 정규화한 링크를 식별자로 쓴다.
+func (it rssItem) sourceItem() SourceItem {
+	item := SourceItem{
+		Type:     SourceRSS,
+		SourceId: strings.TrimSpace(it.Guid),
+		Title:    strings.TrimSpace(it.Title),
+		Content:  strings.TrimSpace(it.Description),
+		Url:      strings.TrimSpace(it.Link),
+	}
+	if item.SourceId == "" {
+		item.SourceId = normalizeURL(item.Url)
+	}
+	for _, layout := range []string{time.RFC1123Z, time.RFC1123} {
+		if t, err := time.Parse(layout, strings.TrimSpace(it.PubDate)); err == nil {
+			item.PublishedAt = &t
+			break
+		}
+	}
+	return item
+}
+
+// parseRSS는 RSS 2.0 문서를 SourceItem 목록으로 읽는다.
+func parseRSS(r io.Reader) ([]SourceItem, error) {
+	var feed rssFeed
+	if err := xml.NewDecoder(r).Decode(&feed); err != nil {
+		return nil, err
+	}
+	items := make([]SourceItem, 0, len(feed.Channel.Items))
+	for _, it := range feed.Channel.Items {
+		items = append(items, it.sourceItem())
+	}
+	return items, nil
+}
+
+// IngestRSS는 feedURL의 항목을 store.IngestSourceItem으로 넣는다.
+func IngestRSS(ctx context.Context, store Store, feedURL string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("rss error: %v, %s", resp.Status, string(b))
+	}
+
+	items, err := parseRSS(resp.Body)
+	if err != nil {
+		return fmt.Errorf("parse rss: %w", err)
+	}
+	ingestSourceItems(ctx, store, items)
+	return nil
+}
+
+// ingestSourceItems는 항목별 실패를 로그로 남기고 나머지를 계속 처리한다.
+func ingestSourceItems(ctx context.Context, store Store, items []SourceItem) {
+	for _, item := range items {
+		res, err := store.IngestSourceItem(ctx, item)
+		switch {
+		case err != nil:
+			log.Printf("Failed ingest %s %s: %v", item.Type, item.SourceId, err)
+		case res.Merged:
+			log.Printf("Merged %s %s into whatsnews %d (%s)", item.Type, item.SourceId, res.WhatsnewId, res.Method)
+		case res.Created:
+			log.Printf("Inserted %s %s as whatsnews %d", item.Type, item.SourceId, res.WhatsnewId)
+		}
+	}
+}
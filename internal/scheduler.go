@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// SchedulerInterval은 메일 확인과 AWS API 수집 주기.
+const SchedulerInterval = 3 * time.Hour
+
+// RunScheduler는 주간 메일을 읽어 Slack으로 알리고 AWS What's New를 수집하는 작업을
+// SchedulerInterval마다 반복한다. ctx가 취소되면 반환한다.
+func RunScheduler(ctx context.Context, cfg Config, store Store) {
+	// 카운터를 거치지 않고 적재된 데이터(예: 수동 복구)가 있어도 시작 시점에 정합성을 맞춘다
+	if err := store.RebuildTagStats(ctx); err != nil {
+		log.Printf("RebuildTagStats 에러: %v", err)
+	}
+	// 초기 적재(collect)로 들어온 레코드는 canonical_url이 비어 있다
+	if n, err := store.BackfillCanonicalURLs(ctx); err != nil {
+		log.Printf("BackfillCanonicalURLs 에러: %v", err)
+	} else if n > 0 {
+		log.Printf("canonical_url %d건 채움", n)
+	}
+
+	ticker := time.NewTicker(SchedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		runSchedulerOnce(ctx, cfg, store)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runSchedulerOnce(ctx context.Context, cfg Config, store Store) {
+	// API 항목을 먼저 넣어야 메일/RSS 항목이 새 레코드를 만들지 않고 API 레코드에 병합된다
+	if err := ParseUntilExisting(ctx, store, 100); err != nil {
+		log.Printf("ParseUntilExisting 에러: %v", err)
+	}
+	if err := IngestRSS(ctx, store, AwsWhatsNewFeedURL); err != nil {
+		log.Printf("IngestRSS 에러: %v", err)
+	}
+
+	var readers []io.Reader
+
+	if cfg.Mode == ModeIMAP {
+		c, err := ConnectIMAP(cfg)
+		if err != nil {
+			log.Printf("IMAP connection error: %v", err)
+			return
+		}
+
+		readers, err = FetchMailReadersFromIMAP(c)
+		if err != nil {
+			log.Printf("Failed to fetch mail: %v", err)
+			c.Logout()
+			return
+		}
+		c.Logout()
+
+	} else if cfg.Mode == ModeTestdata {
+		readers, _ = fetchMailReadersFromDir(cfg.TestdataDir)
+	}
+
+	for _, r := range readers {
+		newsItems, updates, subject := ParseMail(r)
+		printMailSummary(subject, newsItems, updates)
+
+		mailItems := make([]SourceItem, 0, len(newsItems))
+		for _, n := range newsItems {
+			mailItems = append(mailItems, mailSourceItem(n))
+		}
+		ingestSourceItems(ctx, store, mailItems)
+
+		var message strings.Builder
+		// message.WriteString(fmt.Sprintf("*%s*\n", subject))
+		// for _, item := range newsItems {
+		// 	message.WriteString(fmt.Sprintf("- %s (%s)\n%s\n", item.Title, item.Date, item.Link))
+		// }
+		if len(updates) > 0 {
+			message.WriteString("\nUpdates:\n" + strings.Join(updates, "\n"))
+		}
+
+		webhookURL := cfg.SlackWebHookUrl
+		if webhookURL != "" {
+			if err := SendToSlack(webhookURL, message.String()); err != nil {
+				log.Printf("Slack notify failed: %v", err)
+			}
+		}
+	}
+}
+
+func fetchMailReadersFromDir(dir string) ([]io.Reader, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		log.Println("Failed to read directory:", err)
+		return nil, err
+	}
+	var readers []io.Reader
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), MIMEFileExtension) {
+			path := dir + "/" + f.Name()
+			src, err := os.Open(path)
+			if err != nil {
+				log.Printf("Failed to open %s: %v", path, err)
+				continue
+			}
+			readers = append(readers, src)
+		}
+	}
+	return readers, nil
+}
+
+func printMailSummary(subject string, newsItems []NewsItem, updates []string) {
+	fmt.Println("Subject:", subject)
+	fmt.Println("--- WhatsNewTable ---")
+	for i, item := range newsItems {
+		fmt.Println("========================================")
+		fmt.Printf("%d.\n", i+1)
+		fmt.Printf("제목: %s\n", strings.TrimSpace(item.Title))
+		fmt.Printf("링크: %s\n", item.Link)
+		fmt.Printf("날짜: %s\n", item.Date)
+	}
+	fmt.Println("--- MainUpdates ---")
+	for _, u := range updates {
+		fmt.Println(u)
+	}
+}
+
+func SendToSlack(webhookURL, message string) error {
+	payload := map[string]string{"text": message}
+	body, _ := json.Marshal(payload)
+	resp, err := http.Post(webhookURL, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("non-200 response from Slack: %d", resp.StatusCode)
+	}
+	return nil
+}
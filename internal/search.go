@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+/*
+검색어 문법
+-----------
+  lambda ec2        두 단어 모두 포함 (AND)
+  "auto scaling"    구문 검색 (단어가 연속으로 등장)
+  lamb*             접두어 검색
+  -preview          제외
+  ec2 OR ecs        둘 중 하나
+
+사용자 입력을 searchTerm 목록으로 파싱한 뒤, PostgreSQL은 to_tsquery 문자열로,
+SQLite는 FTS5 MATCH 식으로 변환한다. 변환 결과에는 영문자/숫자 토큰만 들어가므로
+to_tsquery 문법 오류가 나지 않는다.
+*/
+
+type searchTerm struct {
+	Words  []string // 2개 이상이면 구문
+	Prefix bool     // 마지막 단어를 접두어로 취급
+	Negate bool
+	Or     bool // 앞 항과 OR로 연결
+}
+
+// parseSearch는 검색어를 searchTerm 목록으로 나눈다.
+func parseSearch(search string) []searchTerm {
+	var (
+		terms     []string
+		inQuote   bool
+		cur       strings.Builder
+		pendingOr bool
+		out       []searchTerm
+	)
+	flush := func() {
+		if cur.Len() > 0 {
+			terms = append(terms, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range search {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			cur.WriteRune(r)
+		case unicode.IsSpace(r) && !inQuote:
+			flush()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+
+	for _, raw := range terms {
+		if raw == "OR" || raw == "|" {
+			pendingOr = len(out) > 0
+			continue
+		}
+		var t searchTerm
+		if strings.HasPrefix(raw, "-") && len(raw) > 1 {
+			t.Negate = true
+			raw = raw[1:]
+		}
+		raw = strings.ReplaceAll(raw, `"`, "")
+		if strings.HasSuffix(raw, "*") {
+			t.Prefix = true
+		}
+		t.Words = searchWords(raw)
+		if len(t.Words) == 0 {
+			continue
+		}
+		t.Or = pendingOr && !t.Negate
+		pendingOr = false
+		out = append(out, t)
+	}
+	return out
+}
+
+// searchWords는 문자/숫자 연속 구간만 소문자로 뽑는다.
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// buildTSQuery는 검색어를 to_tsquery('english', ...)에 넘길 문자열로 변환한다.
+// 유효한 단어가 없으면 빈 문자열.
+func buildTSQuery(search string) string {
+	var b strings.Builder
+	for i, t := range parseSearch(search) {
+		if i > 0 {
+			if t.Or {
+				b.WriteString(" | ")
+			} else {
+				b.WriteString(" & ")
+			}
+		}
+		if t.Negate {
+			b.WriteString("!")
+		}
+		expr := strings.Join(t.Words, " <-> ")
+		if t.Prefix {
+			expr += ":*"
+		}
+		if len(t.Words) > 1 {
+			expr = "(" + expr + ")"
+		}
+		b.WriteString(expr)
+	}
+	return b.String()
+}
+
+// buildFTS5Query는 검색어를 SQLite FTS5 MATCH 식으로 변환한다.
+// FTS5의 NOT은 이항 연산자라 제외어만 있는 검색은 표현할 수 없다. 이 경우
+// negated=true와 함께 제외어들을 OR로 묶은 식을 돌려주며, 호출자가 NOT IN으로 처리한다.
+func buildFTS5Query(search string) (match string, negated bool) {
+	var pos, neg []string
+	for _, t := range parseSearch(search) {
+		expr := `"` + strings.Join(t.Words, " ") + `"`
+		if t.Prefix {
+			expr += "*"
+		}
+		if t.Negate {
+			neg = append(neg, expr)
+			continue
+		}
+		if len(pos) > 0 {
+			if t.Or {
+				expr = "OR " + expr
+			} else {
+				expr = "AND " + expr
+			}
+		}
+		pos = append(pos, expr)
+	}
+	if len(pos) == 0 {
+		return strings.Join(neg, " OR "), len(neg) > 0
+	}
+	match = "(" + strings.Join(pos, " ") + ")"
+	for _, n := range neg {
+		match += " NOT " + n
+	}
+	return match, false
+}
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// stripHTML은 본문에서 마크업을 걷어내 검색·스니펫용 평문으로 만든다.
+func stripHTML(s string) string {
+	s = htmlTagPattern.ReplaceAllString(s, " ")
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
@@ -0,0 +1,51 @@
+package internal
+
+import "testing"
+
+func TestBuildTSQuery(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{"lambda", "lambda"},
+		{"EC2 instance", "ec2 & instance"},
+		{`"auto scaling" group`, "(auto <-> scaling) & group"},
+		{"lamb*", "lamb:*"},
+		{"ec2 OR ecs", "ec2 | ecs"},
+		{"compute -preview", "compute & !preview"},
+		{`-"generally available"`, "!(generally <-> available)"},
+		{"s3.amazonaws.com", "(s3 <-> amazonaws <-> com)"},
+		{"'; DROP TABLE --", "drop & table"},
+		{"  ", ""},
+	}
+	for _, c := range cases {
+		if got := buildTSQuery(c.in); got != c.want {
+			t.Errorf("buildTSQuery(%q): got %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestBuildFTS5Query(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    string
+		negated bool
+	}{
+		{"lambda", `("lambda")`, false},
+		{`"auto scaling" lamb*`, `("auto scaling" AND "lamb"*)`, false},
+		{"ec2 OR ecs -preview", `("ec2" OR "ecs") NOT "preview"`, false},
+		{"-preview -beta", `"preview" OR "beta"`, true},
+	}
+	for _, c := range cases {
+		got, negated := buildFTS5Query(c.in)
+		if got != c.want || negated != c.negated {
+			t.Errorf("buildFTS5Query(%q): got %q,%v want %q,%v", c.in, got, negated, c.want, c.negated)
+		}
+	}
+}
+
+func TestStripHTML(t *testing.T) {
+	got := stripHTML(`<p>Amazon <a href="https://aws.amazon.com/ec2">EC2</a> &amp; more</p>`)
+	if want := "Amazon EC2 & more"; got != want {
+		t.Errorf("stripHTML: got %q, want %q", got, want)
+	}
+}
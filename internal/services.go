@@ -0,0 +1,200 @@
+package internal
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Service는 서비스 카탈로그 항목. Aliases는 발표문에서 이 서비스를 가리키는 다른 표기.
+type Service struct {
+	Code      string   `json:"code"`
+	Name      string   `json:"name"`
+	Aliases   []string `json:"aliases"`
+	NewsCount int      `json:"news_count"`
+	// Terraform은 이 서비스에 속하는 Terraform 리소스 타입(접두사). 카탈로그 파일에만 있고
+	// DB에는 저장하지 않는다.
+	Terraform []string `json:"terraform,omitempty" db:"-"`
+}
+
+//go:embed catalog/services.json
+var defaultServiceCatalog []byte
+
+// serviceCatalog는 현재 사용하는 카탈로그와 항목별 매칭 정규식.
+var serviceCatalog = mustParseServiceCatalog(defaultServiceCatalog)
+
+type compiledCatalog struct {
+	services []Service
+	patterns []*regexp.Regexp
+}
+
+func mustParseServiceCatalog(b []byte) compiledCatalog {
+	c, err := parseServiceCatalog(b)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// parseServiceCatalog는 카탈로그 JSON을 읽고 서비스마다 이름·별칭 매칭 정규식을 만든다.
+// 제품명은 대소문자가 일정하므로 대소문자를 구분해 "config" 같은 일반 단어에 걸리지 않게 한다.
+func parseServiceCatalog(b []byte) (compiledCatalog, error) {
+	var services []Service
+	if err := json.Unmarshal(b, &services); err != nil {
+		return compiledCatalog{}, fmt.Errorf("service catalog: %w", err)
+	}
+	c := compiledCatalog{services: services, patterns: make([]*regexp.Regexp, len(services))}
+	seen := map[string]bool{}
+	for i, s := range services {
+		if s.Code == "" || s.Name == "" {
+			return compiledCatalog{}, fmt.Errorf("service catalog: entry %d needs code and name", i)
+		}
+		if seen[s.Code] {
+			return compiledCatalog{}, fmt.Errorf("service catalog: duplicate code %q", s.Code)
+		}
+		seen[s.Code] = true
+
+		alts := []string{regexp.QuoteMeta(s.Name)}
+		for _, a := range s.Aliases {
+			alts = append(alts, regexp.QuoteMeta(a))
+		}
+		c.patterns[i] = regexp.MustCompile(`(?:^|[^\pL\pN-])(?:` + strings.Join(alts, "|") + `)(?:$|[^\pL\pN-])`)
+	}
+	return c, nil
+}
+
+// LoadServiceCatalog는 기본 카탈로그 대신 path의 JSON 파일을 쓴다. Store를 열기 전에 호출한다.
+func LoadServiceCatalog(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	c, err := parseServiceCatalog(b)
+	if err != nil {
+		return err
+	}
+	serviceCatalog = c
+	return nil
+}
+
+// extractServices는 제목과 태그 이름에서 언급된 서비스 코드를 카탈로그 순서로 돌려준다.
+// 본문은 연동 서비스를 나열하는 경우가 많아 보지 않는다.
+func extractServices(title string, tagNames []string) []string {
+	text := title + "\n" + strings.Join(tagNames, "\n")
+	var out []string
+	for i, re := range serviceCatalog.patterns {
+		if re.MatchString(text) {
+			out = append(out, serviceCatalog.services[i].Code)
+		}
+	}
+	return out
+}
+
+func awsTagNames(tags []AwsTag) []string {
+	out := make([]string, len(tags))
+	for i, t := range tags {
+		out[i] = t.Name
+	}
+	return out
+}
+
+// SyncServices는 services 테이블을 카탈로그와 같게 만든다. 카탈로그에서 빠진 서비스는
+// 연결과 함께 지운다.
+func (s *PgStore) SyncServices(ctx context.Context) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	codes := make([]string, 0, len(serviceCatalog.services))
+	for _, svc := range serviceCatalog.services {
+		aliases := svc.Aliases
+		if aliases == nil {
+			aliases = []string{}
+		}
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO services(code, name, aliases) VALUES($1, $2, $3)
+             ON CONFLICT (code) DO UPDATE SET name = EXCLUDED.name, aliases = EXCLUDED.aliases
+             WHERE (services.name, services.aliases) IS DISTINCT FROM (EXCLUDED.name, EXCLUDED.aliases)`,
+			svc.Code, svc.Name, aliases); err != nil {
+			return fmt.Errorf("upsert service %s: %w", svc.Code, err)
+		}
+		codes = append(codes, svc.Code)
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM services WHERE NOT (code = ANY($1::text[]))`, codes); err != nil {
+		return fmt.Errorf("delete services: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// linkServices는 추출한 서비스로 whatsnews_services를 맞추고, 연결이 바뀌었는지 돌려준다.
+// services 테이블에 없는 코드는 무시한다.
+func linkServices(ctx context.Context, tx pgx.Tx, whatsnewsID int, title string, tagNames []string) (bool, error) {
+	codes := extractServices(title, tagNames)
+	if codes == nil {
+		codes = []string{}
+	}
+	removed, err := tx.Exec(ctx,
+		`DELETE FROM whatsnews_services WHERE whatsnew_id = $1 AND NOT (service_code = ANY($2::text[]))`,
+		whatsnewsID, codes)
+	if err != nil {
+		return false, fmt.Errorf("unlink services: %w", err)
+	}
+	added, err := tx.Exec(ctx,
+		`INSERT INTO whatsnews_services(whatsnew_id, service_code)
+         SELECT $1, code FROM services WHERE code = ANY($2::text[])
+         ON CONFLICT DO NOTHING`, whatsnewsID, codes)
+	if err != nil {
+		return false, fmt.Errorf("link services: %w", err)
+	}
+	return removed.RowsAffected()+added.RowsAffected() > 0, nil
+}
+
+// linkDimensions는 제목·본문·태그에서 추출하는 리전과 서비스 연결을 한 번에 갱신하고,
+// 어느 쪽이든 바뀌었는지 돌려준다.
+func linkDimensions(ctx context.Context, tx pgx.Tx, whatsnewsID int, title, content string, tagNames []string) (bool, error) {
+	regions, err := linkRegions(ctx, tx, whatsnewsID, title, content)
+	if err != nil {
+		return false, err
+	}
+	services, err := linkServices(ctx, tx, whatsnewsID, title, tagNames)
+	return regions || services, err
+}
+
+func (s *PgStore) GetServices(ctx context.Context) ([]Service, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT s.code, s.name, s.aliases, COUNT(ws.whatsnew_id)::int AS news_cnt
+FROM   services s
+LEFT   JOIN whatsnews_services ws ON ws.service_code = s.code
+GROUP  BY s.code, s.name, s.aliases
+ORDER  BY news_cnt DESC, s.name`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[Service])
+}
+
+func (s *PgStore) GetService(ctx context.Context, code string) (Service, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT s.code, s.name, s.aliases,
+       (SELECT COUNT(*)::int FROM whatsnews_services ws WHERE ws.service_code = s.code)
+FROM   services s
+WHERE  s.code = $1`, code)
+	if err != nil {
+		return Service{}, err
+	}
+	svc, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByPos[Service])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Service{}, ErrNotFound
+	}
+	return svc, err
+}
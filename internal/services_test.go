@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractServices(t *testing.T) {
+	cases := []struct {
+		title string
+		tags  []string
+		want  []string
+	}{
+		{"Amazon EC2 Auto Scaling adds instance refresh", nil, []string{"ec2", "ec2-autoscaling"}},
+		{"Amazon QuickSight launches new visuals", nil, []string{"quicksight"}},
+		{"Configure retention for logs", []string{"Amazon CloudWatch"}, []string{"cloudwatch"}},
+		{"AWS Lambda supports Python 3.13", nil, []string{"lambda"}},
+	}
+	for _, c := range cases {
+		if got := extractServices(c.title, c.tags); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("extractServices(%q): got %v, want %v", c.title, got, c.want)
+		}
+	}
+}
+
+func TestParseServiceCatalogRejectsDuplicates(t *testing.T) {
+	_, err := parseServiceCatalog([]byte(`[{"code":"s3","name":"Amazon S3"},{"code":"s3","name":"S3"}]`))
+	if err == nil {
+		t.Error("expected duplicate code error")
+	}
+}
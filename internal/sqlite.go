@@ -0,0 +1,1576 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema는 initdb/init.sql과 같은 구조를 SQLite 문법으로 옮긴 것.
+// 파일 DB를 열 때마다 실행되므로 모두 IF NOT EXISTS로 작성한다.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS tags (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  source_id TEXT UNIQUE,
+  namespace TEXT,
+  created_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS whatsnews (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title TEXT NOT NULL,
+  content TEXT,
+  source_id TEXT UNIQUE NOT NULL,
+  source_type TEXT NOT NULL DEFAULT 'aws-api',
+  source_url TEXT,
+  canonical_url TEXT,
+  source_created_at DATETIME,
+  raw_payload TEXT,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS whatsnews_tags (
+  whatsnew_id INTEGER NOT NULL REFERENCES whatsnews(id) ON DELETE CASCADE,
+  tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (whatsnew_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS tag_stats (
+  tag_id INTEGER PRIMARY KEY REFERENCES tags(id) ON DELETE CASCADE,
+  news_cnt INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS whatsnews_regions (
+  whatsnew_id INTEGER NOT NULL REFERENCES whatsnews(id) ON DELETE CASCADE,
+  region_code TEXT NOT NULL,
+  PRIMARY KEY (whatsnew_id, region_code)
+);
+
+CREATE TABLE IF NOT EXISTS services (
+  code TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  aliases TEXT NOT NULL DEFAULT '[]' -- JSON 배열
+);
+
+CREATE TABLE IF NOT EXISTS whatsnews_services (
+  whatsnew_id INTEGER NOT NULL REFERENCES whatsnews(id) ON DELETE CASCADE,
+  service_code TEXT NOT NULL REFERENCES services(code) ON DELETE CASCADE,
+  PRIMARY KEY (whatsnew_id, service_code)
+);
+
+CREATE TABLE IF NOT EXISTS inventory_services (
+  service_code TEXT PRIMARY KEY REFERENCES services(code) ON DELETE CASCADE,
+  source TEXT NOT NULL,
+  resource_types TEXT NOT NULL DEFAULT '[]', -- JSON 배열
+  resource_count INTEGER NOT NULL DEFAULT 0,
+  uploaded_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS whatsnews_merges (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  whatsnew_id INTEGER NOT NULL REFERENCES whatsnews(id) ON DELETE CASCADE,
+  source_type TEXT NOT NULL,
+  source_id TEXT NOT NULL,
+  title TEXT NOT NULL,
+  content TEXT,
+  url TEXT,
+  source_created_at DATETIME,
+  method TEXT NOT NULL,
+  score REAL NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  undone_at DATETIME,
+  split_whatsnew_id INTEGER REFERENCES whatsnews(id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_whatsnews_merges_source ON whatsnews_merges (source_type, source_id) WHERE undone_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_whatsnews_merges_whatsnew_id ON whatsnews_merges (whatsnew_id);
+CREATE INDEX IF NOT EXISTS idx_whatsnews_regions_region ON whatsnews_regions (region_code, whatsnew_id);
+CREATE INDEX IF NOT EXISTS idx_whatsnews_services_service ON whatsnews_services (service_code, whatsnew_id);
+CREATE INDEX IF NOT EXISTS idx_whatsnews_scid_id ON whatsnews (source_created_at DESC, id);
+CREATE INDEX IF NOT EXISTS idx_whatsnews_created_at ON whatsnews (created_at);
+CREATE INDEX IF NOT EXISTS idx_whatsnews_updated_at_id ON whatsnews (updated_at DESC, id);
+CREATE INDEX IF NOT EXISTS idx_whatsnews_tags_tag_whatsnew ON whatsnews_tags (tag_id, whatsnew_id);
+CREATE INDEX IF NOT EXISTS idx_tags_name ON tags (name);
+CREATE INDEX IF NOT EXISTS idx_tags_namespace_name ON tags (namespace, name);
+
+-- 전문 검색 인덱스. rowid = whatsnews.id, body는 HTML 태그를 걷어낸 본문
+CREATE VIRTUAL TABLE IF NOT EXISTS whatsnews_fts USING fts5(title, body, tokenize='porter unicode61');
+
+CREATE TABLE IF NOT EXISTS api_keys (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  prefix TEXT NOT NULL,
+  key_hash TEXT NOT NULL UNIQUE,
+  scopes TEXT NOT NULL, -- JSON 배열
+  created_at DATETIME NOT NULL,
+  last_used_at DATETIME,
+  revoked_at DATETIME
+);
+
+-- 데이터 버전. sqliteDataVersionTriggers가 올린다. modified_at은 unix 밀리초
+CREATE TABLE IF NOT EXISTS data_version (
+  id INTEGER PRIMARY KEY CHECK (id = 1),
+  version INTEGER NOT NULL DEFAULT 0,
+  modified_at INTEGER NOT NULL
+);
+INSERT OR IGNORE INTO data_version(id, modified_at) VALUES (1, CAST(unixepoch('subsec') * 1000 AS INTEGER));
+`
+
+// sqliteDataVersionTriggers는 dataVersionTables의 행이 바뀔 때마다 data_version을 올리는 트리거.
+// SQLite에는 문장 단위 트리거가 없어 행마다 올린다. 쓰기는 트랜잭션 안에서 DB 전체를 잠그므로
+// 한 트랜잭션이 여러 번 올려도 다른 쓰기가 더 기다리지는 않는다.
+func sqliteDataVersionTriggers() string {
+	var b strings.Builder
+	for _, table := range dataVersionTables {
+		for _, op := range []string{"INSERT", "UPDATE", "DELETE"} {
+			fmt.Fprintf(&b, `CREATE TRIGGER IF NOT EXISTS %s_data_version_%s AFTER %s ON %s BEGIN
+  UPDATE data_version SET version = version + 1, modified_at = CAST(unixepoch('subsec') * 1000 AS INTEGER) WHERE id = 1;
+END;
+`, table, strings.ToLower(op), op, table)
+		}
+	}
+	return b.String()
+}
+
+// sqliteAddedColumns는 처음 스키마 이후 추가된 컬럼. CREATE TABLE IF NOT EXISTS로는
+// 기존 DB 파일에 반영되지 않으므로 열 때 없으면 ALTER TABLE로 추가한다.
+var sqliteAddedColumns = []struct{ table, column, ddl string }{
+	{"whatsnews", "raw_payload", "raw_payload TEXT"},
+	{"whatsnews", "source_type", "source_type TEXT NOT NULL DEFAULT 'aws-api'"},
+	{"whatsnews", "canonical_url", "canonical_url TEXT"},
+}
+
+// sqliteAddedIndexes는 sqliteAddedColumns의 컬럼에 거는 인덱스. 컬럼이 생긴 뒤에 실행한다.
+const sqliteAddedIndexes = `
+CREATE INDEX IF NOT EXISTS idx_whatsnews_canonical_url ON whatsnews (canonical_url);
+`
+
+func ensureColumn(db *sql.DB, table, column, ddl string) error {
+	var exists bool
+	err := db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM pragma_table_info(?) WHERE name = ?)", table, column).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+	_, err = db.Exec("ALTER TABLE " + table + " ADD COLUMN " + ddl)
+	return err
+}
+
+// SQLiteStore는 단일 노드 배포와 테스트용 SQLite 기반 Store 구현.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore는 path의 SQLite 파일을 열고 스키마를 준비한다.
+// path가 ":memory:"이면 프로세스 메모리에만 존재하는 DB를 쓴다.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	if path != ":memory:" {
+		dsn += "&_pragma=journal_mode(WAL)"
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if path == ":memory:" {
+		// 커넥션마다 별개의 메모리 DB가 생기므로 하나만 사용
+		db.SetMaxOpenConns(1)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("sqlite schema: %w", err)
+	}
+	for _, c := range sqliteAddedColumns {
+		if err := ensureColumn(db, c.table, c.column, c.ddl); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("sqlite schema: %w", err)
+		}
+	}
+	if _, err := db.Exec(sqliteAddedIndexes); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("sqlite schema: %w", err)
+	}
+	if _, err := db.Exec(sqliteDataVersionTriggers()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("sqlite schema: %w", err)
+	}
+	s := &SQLiteStore{db: db}
+	if err := s.syncSearchIndex(context.Background()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("sqlite search index: %w", err)
+	}
+	return s, nil
+}
+
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func indexSearchText(ctx context.Context, db sqlExecer, id int, title, content string) error {
+	_, err := db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO whatsnews_fts(rowid, title, body) VALUES(?, ?, ?)`,
+		id, title, stripHTML(content))
+	return err
+}
+
+// syncSearchIndex는 검색 인덱스에 빠진 뉴스를 채운다.
+// 검색 인덱스가 생기기 전에 만들어진 DB 파일을 처음 열 때 필요하다.
+func (s *SQLiteStore) syncSearchIndex(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, title, COALESCE(content, '')
+FROM   whatsnews
+WHERE  id NOT IN (SELECT rowid FROM whatsnews_fts)`)
+	if err != nil {
+		return err
+	}
+	type pending struct {
+		id             int
+		title, content string
+	}
+	var todo []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.title, &p.content); err != nil {
+			rows.Close()
+			return err
+		}
+		todo = append(todo, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(todo) == 0 {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, p := range todo {
+		if err := indexSearchText(ctx, tx, p.id, p.title, p.content); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) DataVersion(ctx context.Context) (DataVersion, error) {
+	var v DataVersion
+	var ms int64
+	err := s.db.QueryRowContext(ctx, `SELECT version, modified_at FROM data_version WHERE id = 1`).Scan(&v.Version, &ms)
+	v.ModifiedAt = time.UnixMilli(ms).UTC()
+	return v, err
+}
+
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *SQLiteStore) Close() {
+	s.db.Close()
+}
+
+func (s *SQLiteStore) SourceIdExists(ctx context.Context, sourceId string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM whatsnews WHERE source_id = ?)", sourceId).Scan(&exists)
+	return exists, err
+}
+
+func (s *SQLiteStore) InsertAwsItem(ctx context.Context, el AwsApiItem) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	title, body, url, sourceTime := awsItemFields(el)
+	sourceTime = utcTime(sourceTime)
+	payload, err := el.payload()
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+	now := time.Now().UTC()
+
+	canonicalURL := normalizeURL(url)
+
+	var whatsnewsID int
+	err = tx.QueryRowContext(ctx, "SELECT id FROM whatsnews WHERE source_id = ?", el.Item.Id).Scan(&whatsnewsID)
+	switch {
+	case err == nil:
+		// 원본 payload가 없던 기존 행은 이번에 받은 payload로 채운다
+		_, err = tx.ExecContext(ctx,
+			"UPDATE whatsnews SET raw_payload = ? WHERE id = ? AND raw_payload IS NULL", string(payload), whatsnewsID)
+	case errors.Is(err, sql.ErrNoRows):
+		item := SourceItem{Type: SourceAwsApi, Title: title, Url: url, PublishedAt: sourceTime}
+		var (
+			method string
+			score  float64
+		)
+		whatsnewsID, method, score, err = sqliteFindCanonical(ctx, tx, item, canonicalURL)
+		if err != nil {
+			return fmt.Errorf("find canonical: %w", err)
+		}
+		if whatsnewsID > 0 {
+			// 메일/RSS로 먼저 만들어진 레코드를 API 항목으로 대체한다
+			if err := sqliteDemotePrimary(ctx, tx, whatsnewsID, method, score, now); err != nil {
+				return fmt.Errorf("insert whatsnews_merges: %w", err)
+			}
+			_, err = tx.ExecContext(ctx,
+				`UPDATE whatsnews
+                 SET    title = ?, content = ?, source_id = ?, source_type = ?, source_url = ?,
+                        canonical_url = ?, source_created_at = ?, raw_payload = ?, updated_at = ?
+                 WHERE  id = ?`,
+				title, body, el.Item.Id, SourceAwsApi, url, canonicalURL, sourceTime, string(payload), now, whatsnewsID)
+		} else {
+			err = tx.QueryRowContext(ctx,
+				`INSERT INTO whatsnews(title, content, source_id, source_type, source_url, canonical_url, source_created_at, raw_payload, created_at, updated_at)
+                 VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+                 RETURNING id`,
+				title, body, el.Item.Id, SourceAwsApi, url, canonicalURL, sourceTime, string(payload), now, now,
+			).Scan(&whatsnewsID)
+		}
+		if err == nil {
+			err = indexSearchText(ctx, tx, whatsnewsID, title, body)
+		}
+		if err == nil {
+			_, err = sqliteLinkDimensions(ctx, tx, whatsnewsID, title, body, awsTagNames(el.Tags))
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("insert/select whatsnews: %w", err)
+	}
+
+	for _, tag := range el.Tags {
+		tagID, err := sqliteUpsertTag(ctx, tx, tag, now)
+		if err != nil {
+			return fmt.Errorf("insert/select tag: %w (name=%s)", err, tag.Name)
+		}
+		if _, err := sqliteLinkTag(ctx, tx, whatsnewsID, tagID, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+// sqliteLinkTag는 뉴스와 태그를 연결하고, 새로 연결된 경우에만 태그 카운터를 올리고 true를 돌려준다.
+func sqliteLinkTag(ctx context.Context, tx *sql.Tx, whatsnewsID, tagID int, now time.Time) (bool, error) {
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO whatsnews_tags(whatsnew_id, tag_id, created_at)
+         VALUES(?, ?, ?) ON CONFLICT DO NOTHING`, whatsnewsID, tagID, now)
+	if err != nil {
+		return false, fmt.Errorf("insert whatsnews_tags: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		return false, nil
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO tag_stats(tag_id, news_cnt) VALUES(?, 1)
+         ON CONFLICT (tag_id) DO UPDATE SET news_cnt = news_cnt + 1`, tagID)
+	if err != nil {
+		return false, fmt.Errorf("update tag_stats: %w (tag_id=%d)", err, tagID)
+	}
+	return true, nil
+}
+
+func (s *SQLiteStore) ReprocessAwsItems(ctx context.Context) (ReprocessResult, error) {
+	var res ReprocessResult
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM whatsnews WHERE raw_payload IS NULL").Scan(&res.Skipped); err != nil {
+		return res, err
+	}
+	relinked, err := s.relinkStoredText(ctx)
+	if err != nil {
+		return res, err
+	}
+	res.Relinked = relinked
+
+	lastID := 0
+	for {
+		rows, err := s.db.QueryContext(ctx,
+			`SELECT id, raw_payload FROM whatsnews
+             WHERE  id > ? AND raw_payload IS NOT NULL
+             ORDER  BY id LIMIT ?`, lastID, reprocessBatchSize)
+		if err != nil {
+			return res, err
+		}
+		var batch []storedPayload
+		for rows.Next() {
+			var (
+				p       storedPayload
+				payload string
+			)
+			if err := rows.Scan(&p.Id, &payload); err != nil {
+				rows.Close()
+				return res, err
+			}
+			p.Payload = []byte(payload)
+			batch = append(batch, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return res, err
+		}
+		if len(batch) == 0 {
+			return res, nil
+		}
+		for _, p := range batch {
+			if err := s.reprocessOne(ctx, p); err != nil {
+				log.Printf("reprocess whatsnews %d: %v", p.Id, err)
+				res.Failed++
+			} else {
+				res.Updated++
+			}
+			lastID = p.Id
+		}
+	}
+}
+
+// relinkStoredText는 PgStore.relinkStoredText와 같다.
+func (s *SQLiteStore) relinkStoredText(ctx context.Context) (int, error) {
+	relinked, lastID := 0, 0
+	for {
+		rows, err := s.db.QueryContext(ctx, `
+SELECT wn.id, wn.title, COALESCE(wn.content, ''),
+       COALESCE((SELECT group_concat(name, char(10)) FROM (
+                   SELECT t.name FROM whatsnews_tags wt JOIN tags t ON t.id = wt.tag_id
+                   WHERE  wt.whatsnew_id = wn.id ORDER BY t.name)), '')
+FROM   whatsnews wn
+WHERE  wn.id > ? AND wn.raw_payload IS NULL
+ORDER  BY wn.id LIMIT ?`, lastID, reprocessBatchSize)
+		if err != nil {
+			return relinked, err
+		}
+		var batch []storedText
+		for rows.Next() {
+			var (
+				r    storedText
+				tags string
+			)
+			if err := rows.Scan(&r.Id, &r.Title, &r.Content, &tags); err != nil {
+				rows.Close()
+				return relinked, err
+			}
+			if tags != "" {
+				r.TagNames = strings.Split(tags, "\n")
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return relinked, err
+		}
+		if len(batch) == 0 {
+			return relinked, nil
+		}
+
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return relinked, err
+		}
+		now, n := time.Now().UTC(), 0
+		for _, r := range batch {
+			changed, err := sqliteLinkDimensions(ctx, tx, r.Id, r.Title, r.Content, r.TagNames)
+			if err != nil {
+				tx.Rollback()
+				return relinked, fmt.Errorf("relink whatsnews %d: %w", r.Id, err)
+			}
+			if changed {
+				if _, err := tx.ExecContext(ctx, "UPDATE whatsnews SET updated_at = ? WHERE id = ?", now, r.Id); err != nil {
+					tx.Rollback()
+					return relinked, fmt.Errorf("update whatsnews: %w", err)
+				}
+				n++
+			}
+			lastID = r.Id
+		}
+		if err := tx.Commit(); err != nil {
+			return relinked, err
+		}
+		relinked += n
+	}
+}
+
+// reprocessOne은 PgStore.reprocessOne과 같다. 바뀐 것이 있을 때만 updated_at을 올린다.
+func (s *SQLiteStore) reprocessOne(ctx context.Context, p storedPayload) error {
+	el, err := parseAwsPayload(p.Payload)
+	if err != nil {
+		return fmt.Errorf("parse payload: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	title, body, url, sourceTime := awsItemFields(el)
+	now := time.Now().UTC()
+	updated, err := tx.ExecContext(ctx,
+		`UPDATE whatsnews
+         SET    title = ?1, content = ?2, source_url = ?3, canonical_url = ?4, source_created_at = ?5
+         WHERE  id = ?6
+           AND  (title, content, source_url, canonical_url, source_created_at) IS NOT (?1, ?2, ?3, ?4, ?5)`,
+		title, body, url, normalizeURL(url), utcTime(sourceTime), p.Id)
+	if err != nil {
+		return fmt.Errorf("update whatsnews: %w", err)
+	}
+	n, _ := updated.RowsAffected()
+	changed := n > 0
+	if err := indexSearchText(ctx, tx, p.Id, title, body); err != nil {
+		return fmt.Errorf("search index: %w", err)
+	}
+	dims, err := sqliteLinkDimensions(ctx, tx, p.Id, title, body, awsTagNames(el.Tags))
+	if err != nil {
+		return err
+	}
+	changed = changed || dims
+
+	tagIDs := make([]any, 0, len(el.Tags))
+	for _, tag := range el.Tags {
+		tagID, err := sqliteUpsertTag(ctx, tx, tag, now)
+		if err != nil {
+			return fmt.Errorf("insert/select tag: %w (name=%s)", err, tag.Name)
+		}
+		tagIDs = append(tagIDs, tagID)
+	}
+
+	// payload에 없는 태그 연결은 끊고 카운터도 되돌린다
+	notIn := ""
+	if len(tagIDs) > 0 {
+		notIn = " AND tag_id NOT IN (" + placeholders(len(tagIDs)) + ")"
+	}
+	if _, err := tx.ExecContext(ctx, `
+UPDATE tag_stats SET news_cnt = news_cnt - 1
+WHERE  tag_id IN (SELECT tag_id FROM whatsnews_tags WHERE whatsnew_id = ?`+notIn+`)`,
+		append([]any{p.Id}, tagIDs...)...); err != nil {
+		return fmt.Errorf("unlink tags: %w", err)
+	}
+	unlinked, err := tx.ExecContext(ctx,
+		"DELETE FROM whatsnews_tags WHERE whatsnew_id = ?"+notIn,
+		append([]any{p.Id}, tagIDs...)...)
+	if err != nil {
+		return fmt.Errorf("unlink tags: %w", err)
+	}
+	if n, _ := unlinked.RowsAffected(); n > 0 {
+		changed = true
+	}
+	for _, tagID := range tagIDs {
+		linked, err := sqliteLinkTag(ctx, tx, p.Id, tagID.(int), now)
+		if err != nil {
+			return err
+		}
+		changed = changed || linked
+	}
+	if changed {
+		if _, err := tx.ExecContext(ctx, "UPDATE whatsnews SET updated_at = ? WHERE id = ?", now, p.Id); err != nil {
+			return fmt.Errorf("update whatsnews: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// sqliteUpsertTag는 PgStore의 upsertTag와 같은 규칙으로 태그를 넣고 id를 돌려준다.
+func sqliteUpsertTag(ctx context.Context, tx *sql.Tx, tag AwsTag, now time.Time) (int, error) {
+	var tagID int
+	if tag.Id == "" {
+		err := tx.QueryRowContext(ctx, "SELECT id FROM tags WHERE name = ? ORDER BY id LIMIT 1", tag.Name).Scan(&tagID)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = tx.QueryRowContext(ctx,
+				"INSERT INTO tags(name, created_at) VALUES(?, ?) RETURNING id", tag.Name, now).Scan(&tagID)
+		}
+		return tagID, err
+	}
+
+	namespace := awsTagNamespace(tag)
+	err := tx.QueryRowContext(ctx,
+		`UPDATE tags SET source_id = ?, namespace = ?
+         WHERE  id = (SELECT id FROM tags WHERE name = ? AND source_id IS NULL ORDER BY id LIMIT 1)
+         RETURNING id`, tag.Id, namespace, tag.Name).Scan(&tagID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = tx.QueryRowContext(ctx,
+			`INSERT INTO tags(name, source_id, namespace, created_at) VALUES(?, ?, ?, ?)
+             ON CONFLICT (source_id) DO UPDATE SET name = excluded.name, namespace = excluded.namespace
+             RETURNING id`, tag.Name, tag.Id, namespace, now).Scan(&tagID)
+	}
+	return tagID, err
+}
+
+func (s *SQLiteStore) RebuildTagStats(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 값이 바뀐 행만 쓴다 (PgStore.RebuildTagStats 참고)
+	if _, err := tx.ExecContext(ctx, `
+DELETE FROM tag_stats
+WHERE  NOT EXISTS (SELECT 1 FROM whatsnews_tags wnt WHERE wnt.tag_id = tag_stats.tag_id)`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO tag_stats(tag_id, news_cnt)
+SELECT tag_id, COUNT(*) FROM whatsnews_tags WHERE true GROUP BY tag_id
+ON CONFLICT (tag_id) DO UPDATE SET news_cnt = excluded.news_cnt
+WHERE  news_cnt <> excluded.news_cnt`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) GetTags(ctx context.Context, tq TagsQuery) (TagsResult, error) {
+	const q = `
+SELECT t.id,
+       t.name,
+       COALESCE(t.namespace, '')      AS namespace,
+       COALESCE(t.source_id, '')      AS source_id,
+       COALESCE(s.news_cnt, 0)        AS news_cnt,
+       COUNT(*) OVER()                AS total_rows
+FROM   tags        AS t
+LEFT   JOIN tag_stats AS s ON s.tag_id = t.id
+WHERE  t.name LIKE ?
+AND    (? = '' OR t.namespace = ?)
+ORDER  BY news_cnt DESC, t.name
+LIMIT  ? OFFSET ?`
+
+	like := "%"
+	if tq.Name != "" {
+		like = "%" + tq.Name + "%"
+	}
+	limit, offset := tq.Limit, tq.Offset
+
+	rows, err := s.db.QueryContext(ctx, q, like, tq.Namespace, tq.Namespace, limit, offset)
+	if err != nil {
+		return TagsResult{}, err
+	}
+	defer rows.Close()
+
+	res := TagsResult{
+		Limit:  limit,
+		Offset: offset,
+		Page:   1,
+	}
+	for rows.Next() {
+		var (
+			t         Tag
+			totalRows int
+		)
+		if err := rows.Scan(&t.Id, &t.Name, &t.Namespace, &t.SourceId, &t.NewsCount, &totalRows); err != nil {
+			return TagsResult{}, err
+		}
+		if res.Total == 0 {
+			res.Total = totalRows
+		}
+		res.Items = append(res.Items, t)
+	}
+
+	if res.Total > 0 && res.Limit > 0 {
+		res.Page = res.Offset/res.Limit + 1
+		res.TotalPage = (res.Total + res.Limit - 1) / res.Limit
+	} else {
+		res.TotalPage = 1
+	}
+	return res, rows.Err()
+}
+
+func (s *SQLiteStore) GetTagNamespaces(ctx context.Context) ([]TagNamespace, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT COALESCE(t.namespace, '')       AS namespace,
+       COUNT(*)                        AS tag_cnt,
+       COALESCE(SUM(s.news_cnt), 0)    AS news_cnt
+FROM   tags        AS t
+LEFT   JOIN tag_stats AS s ON s.tag_id = t.id
+GROUP  BY 1
+ORDER  BY 1`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TagNamespace
+	for rows.Next() {
+		var ns TagNamespace
+		if err := rows.Scan(&ns.Name, &ns.TagCount, &ns.NewsCount); err != nil {
+			return nil, err
+		}
+		out = append(out, ns)
+	}
+	return out, rows.Err()
+}
+
+// sqliteFilter는 WhatsNewsQuery의 필터 조건을 SQL로 옮긴 것. 목록(GetWhatsnews)과
+// 통계(stats.go)가 같은 조건을 쓴다. 조건은 whatsnews를 wn으로 참조한다.
+type sqliteFilter struct {
+	from     string // 검색어가 있으면 FTS 결과(s)와의 JOIN이 붙는다
+	rankCols string // 목록의 rank, headline, snippet 열
+	conds    []string
+	args     []any
+}
+
+// where는 WHERE 절. 조건이 없으면 빈 문자열.
+func (f *sqliteFilter) where() string {
+	if len(f.conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(f.conds, " AND ")
+}
+
+// newSQLiteFilter는 normalize된 q로 조건을 만든다. 커서(After)와 정렬·페이지는 다루지 않는다.
+func newSQLiteFilter(q WhatsNewsQuery) *sqliteFilter {
+	f := &sqliteFilter{from: "whatsnews wn", rankCols: "0.0 AS rank, '' AS headline, '' AS snippet"}
+
+	// 검색어: FTS5 (제목 가중치 10, 본문 1)
+	if match, negated := buildFTS5Query(q.Search); match != "" {
+		if negated {
+			f.conds = append(f.conds, "wn.id NOT IN (SELECT rowid FROM whatsnews_fts WHERE whatsnews_fts MATCH ?)")
+		} else {
+			f.from += `
+JOIN  (SELECT rowid AS id,
+              -bm25(whatsnews_fts, 10.0, 1.0) AS rank,
+              highlight(whatsnews_fts, 0, '<mark>', '</mark>') AS headline,
+              snippet(whatsnews_fts, 1, '<mark>', '</mark>', ' … ', 24) AS snippet
+       FROM   whatsnews_fts
+       WHERE  whatsnews_fts MATCH ?) s ON s.id = wn.id`
+			f.rankCols = "s.rank, s.headline, s.snippet"
+		}
+		f.args = append(f.args, match)
+	}
+
+	// 태그 배열: all이면 모든 태그를, any면 하나라도 가진 뉴스만
+	switch {
+	case len(q.TagIDs) > 0 && q.TagMode == TagModeAny:
+		f.conds = append(f.conds, `wn.id IN (
+  SELECT whatsnew_id FROM whatsnews_tags
+  WHERE  tag_id IN (`+placeholders(len(q.TagIDs))+`))`)
+		for _, id := range q.TagIDs {
+			f.args = append(f.args, id)
+		}
+	case len(q.TagIDs) > 0:
+		wanted := make(map[int]struct{}, len(q.TagIDs))
+		for _, id := range q.TagIDs {
+			wanted[id] = struct{}{}
+		}
+		f.conds = append(f.conds, `wn.id IN (
+  SELECT whatsnew_id FROM whatsnews_tags
+  WHERE  tag_id IN (`+placeholders(len(q.TagIDs))+`)
+  GROUP  BY whatsnew_id
+  HAVING COUNT(DISTINCT tag_id) = ?)`)
+		for _, id := range q.TagIDs {
+			f.args = append(f.args, id)
+		}
+		f.args = append(f.args, len(wanted))
+	}
+	if len(q.ExcludeTagIDs) > 0 {
+		f.conds = append(f.conds, `NOT EXISTS (
+  SELECT 1 FROM whatsnews_tags xt
+  WHERE  xt.whatsnew_id = wn.id AND xt.tag_id IN (`+placeholders(len(q.ExcludeTagIDs))+`))`)
+		for _, id := range q.ExcludeTagIDs {
+			f.args = append(f.args, id)
+		}
+	}
+
+	if len(q.IDs) > 0 {
+		f.conds = append(f.conds, "wn.id IN ("+placeholders(len(q.IDs))+")")
+		for _, id := range q.IDs {
+			f.args = append(f.args, id)
+		}
+	}
+
+	// 네임스페이스별 패싯
+	for _, ns := range q.facetNamespaces() {
+		names := lowerAll(q.Facets[ns])
+		f.conds = append(f.conds, `EXISTS (
+  SELECT 1
+  FROM   whatsnews_tags ft
+  JOIN   tags t ON t.id = ft.tag_id
+  WHERE  ft.whatsnew_id = wn.id
+  AND    t.namespace = ?
+  AND    lower(t.name) IN (`+placeholders(len(names))+`))`)
+		f.args = append(f.args, ns)
+		for _, n := range names {
+			f.args = append(f.args, n)
+		}
+	}
+
+	if len(q.Regions) > 0 {
+		f.conds = append(f.conds, `EXISTS (
+  SELECT 1 FROM whatsnews_regions wr
+  WHERE  wr.whatsnew_id = wn.id AND wr.region_code IN (`+placeholders(len(q.Regions))+`))`)
+		for _, code := range q.Regions {
+			f.args = append(f.args, code)
+		}
+	}
+
+	if len(q.Services) > 0 {
+		f.conds = append(f.conds, `EXISTS (
+  SELECT 1 FROM whatsnews_services ws
+  WHERE  ws.whatsnew_id = wn.id AND ws.service_code IN (`+placeholders(len(q.Services))+`))`)
+		for _, code := range q.Services {
+			f.args = append(f.args, code)
+		}
+	}
+
+	if len(q.SourceTypes) > 0 {
+		f.conds = append(f.conds, `(wn.source_type IN (`+placeholders(len(q.SourceTypes))+`) OR EXISTS (
+  SELECT 1 FROM whatsnews_merges sm
+  WHERE  sm.whatsnew_id = wn.id AND sm.undone_at IS NULL AND sm.source_type IN (`+placeholders(len(q.SourceTypes))+`)))`)
+		for range 2 {
+			for _, t := range q.SourceTypes {
+				f.args = append(f.args, t)
+			}
+		}
+	}
+
+	if q.RelevantOnly {
+		f.conds = append(f.conds, relevantExpr)
+	}
+
+	if q.From != nil {
+		f.conds = append(f.conds, "wn.source_created_at >= ?")
+		f.args = append(f.args, *q.From)
+	}
+	if q.To != nil {
+		f.conds = append(f.conds, "wn.source_created_at < ?")
+		f.args = append(f.args, *q.To)
+	}
+	if q.Since != nil {
+		f.conds = append(f.conds, "wn.created_at >= ?")
+		f.args = append(f.args, *q.Since)
+	}
+	if q.SinceID > 0 {
+		f.conds = append(f.conds, "wn.id > ?")
+		f.args = append(f.args, q.SinceID)
+	}
+	return f
+}
+
+func (s *SQLiteStore) GetWhatsnews(ctx context.Context, q WhatsNewsQuery) (WhatsNewsResult, error) {
+	q.normalize()
+	if err := q.validate(); err != nil {
+		return WhatsNewsResult{}, err
+	}
+
+	f := newSQLiteFilter(q)
+	from, rankCols, where, args := f.from, f.rankCols, f.where(), f.args
+
+	var total *int
+	if !q.SkipTotal {
+		total = new(int)
+		if err := s.db.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM "+from+" "+where, args...).Scan(total); err != nil {
+			return WhatsNewsResult{}, err
+		}
+	}
+
+	// 커서 조건은 목록 쿼리에만 붙인다. SQLite의 DESC 정렬은 NULL이 마지막에 온다
+	if c := q.After; c != nil {
+		cond := "(wn.source_created_at IS NULL AND wn.id > ?)"
+		if c.SourceCreatedAt != nil {
+			cond = "(wn.source_created_at < ? OR (wn.source_created_at = ? AND wn.id > ?) OR wn.source_created_at IS NULL)"
+			args = append(args, *c.SourceCreatedAt, *c.SourceCreatedAt)
+		}
+		args = append(args, c.Id)
+		if where == "" {
+			where = "WHERE " + cond
+		} else {
+			where += " AND " + cond
+		}
+	}
+
+	order := prefixColumns("wn.", q.orderBy())
+	if q.Sort == SortRelevance {
+		order = "rank DESC, " + order
+	}
+
+	dataSQL := `
+SELECT wn.id, wn.title, wn.content, wn.source_url, wn.source_created_at,
+       wn.created_at, wn.updated_at, wn.source_type, wn.source_id, ` + relevantExpr + ` AS relevant,
+       ` + rankCols + `,
+       (SELECT json_group_array(json_object('id', x.id, 'name', x.name, 'namespace', x.namespace))
+        FROM  (SELECT t.id, t.name, t.namespace
+               FROM   whatsnews_tags wnt
+               JOIN   tags t ON t.id = wnt.tag_id
+               WHERE  wnt.whatsnew_id = wn.id
+               ORDER  BY t.name) x) AS tags,
+       (SELECT json_group_array(json_object('type', m.source_type, 'source_id', m.source_id, 'url', m.url))
+        FROM  (SELECT source_type, source_id, url
+               FROM   whatsnews_merges
+               WHERE  whatsnew_id = wn.id AND undone_at IS NULL
+               ORDER  BY id) m) AS sources,
+       (SELECT json_group_array(region_code)
+        FROM  (SELECT region_code FROM whatsnews_regions
+               WHERE  whatsnew_id = wn.id ORDER BY region_code)) AS regions,
+       (SELECT json_group_array(service_code)
+        FROM  (SELECT service_code FROM whatsnews_services
+               WHERE  whatsnew_id = wn.id ORDER BY service_code)) AS services
+FROM   ` + from + `
+` + where + `
+ORDER  BY ` + order + `
+LIMIT  ? OFFSET ?`
+
+	rows, err := s.db.QueryContext(ctx, dataSQL, append(args, q.Limit+1, q.Offset)...)
+	if err != nil {
+		return WhatsNewsResult{}, err
+	}
+	defer rows.Close()
+
+	var items []WhatsNews
+	for rows.Next() {
+		var (
+			it                   WhatsNews
+			content              sql.NullString
+			url                  sql.NullString
+			sourceType, sourceID string
+			tagsJSON, sourceJSON string
+			regionsJSON          string
+			servicesJSON         string
+		)
+		if err := rows.Scan(&it.Id, &it.Title, &content, &url, &it.SourceCreatedAt, &it.IngestedAt, &it.UpdatedAt, &sourceType, &sourceID, &it.Relevant,
+			&it.Rank, &it.Headline, &it.Snippet, &tagsJSON, &sourceJSON, &regionsJSON, &servicesJSON); err != nil {
+			return WhatsNewsResult{}, err
+		}
+		it.Content = content.String
+		it.SourceUrl = url.String
+		if err := json.Unmarshal([]byte(tagsJSON), &it.Tags); err != nil {
+			return WhatsNewsResult{}, err
+		}
+		if err := it.setSources(sourceType, sourceID, []byte(sourceJSON)); err != nil {
+			return WhatsNewsResult{}, err
+		}
+		if err := json.Unmarshal([]byte(regionsJSON), &it.Regions); err != nil {
+			return WhatsNewsResult{}, err
+		}
+		if err := json.Unmarshal([]byte(servicesJSON), &it.Services); err != nil {
+			return WhatsNewsResult{}, err
+		}
+		items = append(items, it)
+	}
+	if err := rows.Err(); err != nil {
+		return WhatsNewsResult{}, err
+	}
+
+	return newWhatsNewsResult(items, total, q), nil
+}
+
+func (s *SQLiteStore) GetWhatsnewsItem(ctx context.Context, id int) (WhatsNews, error) {
+	res, err := s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 1, IDs: []int{id}})
+	if err != nil {
+		return WhatsNews{}, err
+	}
+	if len(res.Items) == 0 {
+		return WhatsNews{}, ErrNotFound
+	}
+	return res.Items[0], nil
+}
+
+func (s *SQLiteStore) FindWhatsnewsBySource(ctx context.Context, sourceID string) (int, error) {
+	var id int
+	err := s.db.QueryRowContext(ctx, `
+SELECT id FROM (
+  SELECT id, 0 AS prio FROM whatsnews WHERE source_id = ?1
+  UNION ALL
+  SELECT whatsnew_id, 1 FROM whatsnews_merges
+  WHERE  undone_at IS NULL AND (source_id = ?1 OR source_type || ':' || source_id = ?1)
+)
+ORDER BY prio, id
+LIMIT 1`, sourceID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return id, err
+}
+
+// GetRelatedWhatsnews는 PgStore와 같은 점수를 쓰고, 제목 후보만 pg_trgm 대신 FTS5로 찾는다.
+func (s *SQLiteStore) GetRelatedWhatsnews(ctx context.Context, id, limit int) ([]RelatedWhatsNews, error) {
+	var title string
+	err := s.db.QueryRowContext(ctx, `SELECT title FROM whatsnews WHERE id = ?`, id).Scan(&title)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	args := []any{id, relatedIgnoredNamespace, relatedCandidates}
+	similar := ""
+	if match := relatedFTSQuery(title); match != "" {
+		similar = `
+  UNION
+  SELECT id FROM (SELECT rowid AS id FROM whatsnews_fts
+                  WHERE  whatsnews_fts MATCH ? AND rowid <> ?
+                  ORDER  BY rank LIMIT ?)`
+		args = append(args, match, id, relatedCandidates)
+	}
+	rows, err := s.db.QueryContext(ctx, `
+WITH shared AS (
+  SELECT o.whatsnew_id AS id, COUNT(*) AS shared_tags
+  FROM   whatsnews_tags mine
+  JOIN   tags t ON t.id = mine.tag_id
+  JOIN   whatsnews_tags o ON o.tag_id = mine.tag_id AND o.whatsnew_id <> mine.whatsnew_id
+  WHERE  mine.whatsnew_id = ? AND t.namespace IS NOT ?
+  GROUP  BY o.whatsnew_id
+  ORDER  BY shared_tags DESC, o.whatsnew_id DESC
+  LIMIT  ?
+)
+SELECT wn.id, wn.title, COALESCE(wn.source_url, ''), wn.source_created_at, COALESCE(s.shared_tags, 0)
+FROM   (SELECT id FROM shared`+similar+`) c
+JOIN   whatsnews wn ON wn.id = c.id
+LEFT   JOIN shared s ON s.id = c.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cands []RelatedWhatsNews
+	for rows.Next() {
+		var c RelatedWhatsNews
+		if err := rows.Scan(&c.Id, &c.Title, &c.SourceUrl, &c.SourceCreatedAt, &c.SharedTags); err != nil {
+			return nil, err
+		}
+		cands = append(cands, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return rankRelated(title, cands, limit), nil
+}
+
+func sqliteSourceExists(ctx context.Context, tx *sql.Tx, sourceType, sourceId string) (bool, error) {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `
+SELECT EXISTS (SELECT 1 FROM whatsnews WHERE source_id = ?)
+    OR EXISTS (SELECT 1 FROM whatsnews_merges
+               WHERE source_type = ? AND source_id = ? AND undone_at IS NULL)`,
+		recordSourceId(sourceType, sourceId), sourceType, sourceId).Scan(&exists)
+	return exists, err
+}
+
+// sqliteFindCanonical은 pgFindCanonical과 같은 규칙으로 같은 발표의 레코드를 찾는다.
+func sqliteFindCanonical(ctx context.Context, tx *sql.Tx, item SourceItem, canonicalURL string) (id int, method string, score float64, err error) {
+	if canonicalURL != "" {
+		err = tx.QueryRowContext(ctx,
+			`SELECT id FROM whatsnews
+             WHERE  canonical_url = ? AND source_type <> ?
+             ORDER  BY id LIMIT 1`, canonicalURL, item.Type).Scan(&id)
+		if err == nil {
+			return id, MergeByURL, 1, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return 0, "", 0, err
+		}
+	}
+	if item.PublishedAt == nil {
+		return 0, "", 0, nil
+	}
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, title, source_type FROM whatsnews
+         WHERE  source_created_at BETWEEN ? AND ?`,
+		item.PublishedAt.Add(-canonicalTitleWindow).UTC(), item.PublishedAt.Add(canonicalTitleWindow).UTC())
+	if err != nil {
+		return 0, "", 0, err
+	}
+	defer rows.Close()
+	var cands []canonCandidate
+	for rows.Next() {
+		var c canonCandidate
+		if err := rows.Scan(&c.Id, &c.Title, &c.SourceType); err != nil {
+			return 0, "", 0, err
+		}
+		cands = append(cands, c)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, "", 0, err
+	}
+	if id, score = pickByTitle(item.Title, item.Type, cands); id > 0 {
+		method = MergeByTitle
+	}
+	return id, method, score, nil
+}
+
+func sqliteDemotePrimary(ctx context.Context, tx *sql.Tx, whatsnewID int, method string, score float64, now time.Time) error {
+	_, err := tx.ExecContext(ctx, `
+INSERT INTO whatsnews_merges(whatsnew_id, source_type, source_id, title, content, url, source_created_at, method, score, created_at)
+SELECT id, source_type, substr(source_id, length(source_type) + 2), title, content, source_url, source_created_at, ?, ?, ?
+FROM   whatsnews
+WHERE  id = ?`, method, score, now, whatsnewID)
+	return err
+}
+
+func (s *SQLiteStore) IngestSourceItem(ctx context.Context, item SourceItem) (IngestResult, error) {
+	if err := item.validate(); err != nil {
+		return IngestResult{}, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return IngestResult{}, err
+	}
+	defer tx.Rollback()
+
+	exists, err := sqliteSourceExists(ctx, tx, item.Type, item.SourceId)
+	if err != nil || exists {
+		return IngestResult{Skipped: exists}, err
+	}
+
+	canonicalURL := normalizeURL(item.Url)
+	id, method, score, err := sqliteFindCanonical(ctx, tx, item, canonicalURL)
+	if err != nil {
+		return IngestResult{}, fmt.Errorf("find canonical: %w", err)
+	}
+
+	now := time.Now().UTC()
+	published := utcTime(item.PublishedAt)
+	res := IngestResult{WhatsnewId: id, Method: method}
+	if id > 0 {
+		res.Merged = true
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO whatsnews_merges(whatsnew_id, source_type, source_id, title, content, url, source_created_at, method, score, created_at)
+             VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, item.Type, item.SourceId, item.Title, item.Content, item.Url, published, method, score, now)
+		if err != nil {
+			return IngestResult{}, fmt.Errorf("insert whatsnews_merges: %w", err)
+		}
+	} else {
+		res.Created = true
+		res.WhatsnewId, err = sqliteInsertRecord(ctx, tx, item.Type, item.SourceId, item.Title, item.Content, item.Url, published, now)
+		if err != nil {
+			return IngestResult{}, err
+		}
+	}
+	return res, tx.Commit()
+}
+
+// sqliteInsertRecord는 API 외 출처의 항목을 독립 레코드로 저장하고 검색 인덱스에 넣는다.
+func sqliteInsertRecord(ctx context.Context, tx *sql.Tx, sourceType, sourceId, title, content, url string, published *time.Time, now time.Time) (int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO whatsnews(title, content, source_id, source_type, source_url, canonical_url, source_created_at, created_at, updated_at)
+         VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)
+         RETURNING id`,
+		title, content, recordSourceId(sourceType, sourceId), sourceType, url, normalizeURL(url), published, now, now).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert whatsnews: %w", err)
+	}
+	if err := indexSearchText(ctx, tx, id, title, content); err != nil {
+		return 0, fmt.Errorf("search index: %w", err)
+	}
+	if _, err := sqliteLinkDimensions(ctx, tx, id, title, content, nil); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+const sqliteMergeColumns = `id, whatsnew_id, source_type, source_id, title, COALESCE(url, ''), source_created_at,
+       method, score, created_at, undone_at, split_whatsnew_id, COALESCE(content, '')`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSQLiteMerge(r rowScanner) (mergeWithContent, error) {
+	var m mergeWithContent
+	err := r.Scan(&m.Id, &m.WhatsnewId, &m.SourceType, &m.SourceId, &m.Title, &m.Url, &m.SourceCreatedAt,
+		&m.Method, &m.Score, &m.CreatedAt, &m.UndoneAt, &m.SplitWhatsnewId, &m.Content)
+	return m, err
+}
+
+func (s *SQLiteStore) ListMerges(ctx context.Context, q MergesQuery) ([]MergeDecision, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+sqliteMergeColumns+`
+FROM   whatsnews_merges
+WHERE  (? = 0 OR whatsnew_id = ?)
+AND    (? OR undone_at IS NULL)
+ORDER  BY id DESC
+LIMIT  ? OFFSET ?`, q.WhatsnewId, q.WhatsnewId, q.IncludeUndone, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []MergeDecision
+	for rows.Next() {
+		m, err := scanSQLiteMerge(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m.MergeDecision)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLiteStore) UndoMerge(ctx context.Context, id int) (MergeDecision, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return MergeDecision{}, err
+	}
+	defer tx.Rollback()
+
+	m, err := scanSQLiteMerge(tx.QueryRowContext(ctx,
+		"SELECT "+sqliteMergeColumns+" FROM whatsnews_merges WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return MergeDecision{}, ErrNotFound
+	}
+	if err != nil {
+		return MergeDecision{}, err
+	}
+	if m.UndoneAt != nil {
+		return m.MergeDecision, ErrMergeUndone
+	}
+
+	// 병합된 출처를 원래 값으로 별도 레코드로 분리한다
+	now := time.Now().UTC()
+	splitID, err := sqliteInsertRecord(ctx, tx, m.SourceType, m.SourceId, m.Title, m.Content, m.Url,
+		utcTime(m.SourceCreatedAt), now)
+	if err != nil {
+		return MergeDecision{}, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE whatsnews_merges SET undone_at = ?, split_whatsnew_id = ? WHERE id = ?", now, splitID, id); err != nil {
+		return MergeDecision{}, fmt.Errorf("update whatsnews_merges: %w", err)
+	}
+	m.UndoneAt = &now
+	m.SplitWhatsnewId = &splitID
+	return m.MergeDecision, tx.Commit()
+}
+
+func (s *SQLiteStore) BackfillCanonicalURLs(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, COALESCE(source_url, '') FROM whatsnews WHERE canonical_url IS NULL")
+	if err != nil {
+		return 0, err
+	}
+	type pending struct {
+		id  int
+		url string
+	}
+	var todo []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.url); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		todo = append(todo, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(todo) == 0 {
+		return 0, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	for _, p := range todo {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE whatsnews SET canonical_url = ? WHERE id = ?", normalizeURL(p.url), p.id); err != nil {
+			return 0, err
+		}
+	}
+	return len(todo), tx.Commit()
+}
+
+// sqliteLinkRegions는 linkRegions의 SQLite 버전.
+func sqliteLinkRegions(ctx context.Context, tx *sql.Tx, whatsnewsID int, title, content string) (bool, error) {
+	codes := extractRegions(title, content)
+	args := []any{whatsnewsID}
+	notIn := ""
+	if len(codes) > 0 {
+		notIn = " AND region_code NOT IN (" + placeholders(len(codes)) + ")"
+		for _, c := range codes {
+			args = append(args, c)
+		}
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM whatsnews_regions WHERE whatsnew_id = ?"+notIn, args...)
+	if err != nil {
+		return false, fmt.Errorf("unlink regions: %w", err)
+	}
+	changed, _ := res.RowsAffected()
+	for _, c := range codes {
+		res, err := tx.ExecContext(ctx,
+			"INSERT INTO whatsnews_regions(whatsnew_id, region_code) VALUES(?, ?) ON CONFLICT DO NOTHING",
+			whatsnewsID, c)
+		if err != nil {
+			return false, fmt.Errorf("link regions: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		changed += n
+	}
+	return changed > 0, nil
+}
+
+func (s *SQLiteStore) GetRegions(ctx context.Context) ([]Region, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT region_code, COUNT(*) FROM whatsnews_regions GROUP BY region_code")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := map[string]int{}
+	for rows.Next() {
+		var (
+			code string
+			n    int
+		)
+		if err := rows.Scan(&code, &n); err != nil {
+			return nil, err
+		}
+		counts[code] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return regionsWithCounts(counts), nil
+}
+
+func (s *SQLiteStore) GetRegionMatrix(ctx context.Context, q RegionMatrixQuery) (RegionMatrix, error) {
+	var args []any
+	regionCond := ""
+	if len(q.Regions) > 0 {
+		regionCond = "WHERE wr.region_code IN (" + placeholders(len(q.Regions)) + ")"
+		for _, c := range q.Regions {
+			args = append(args, c)
+		}
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT wr.region_code, ws.service_code, COUNT(*), MAX(wn.source_created_at)
+FROM   whatsnews_regions wr
+JOIN   whatsnews wn           ON wn.id = wr.whatsnew_id
+JOIN   whatsnews_services ws  ON ws.whatsnew_id = wr.whatsnew_id
+`+regionCond+`
+GROUP  BY wr.region_code, ws.service_code
+ORDER  BY ws.service_code, wr.region_code`, args...)
+	if err != nil {
+		return RegionMatrix{}, err
+	}
+	defer rows.Close()
+
+	var cells []RegionMatrixCell
+	for rows.Next() {
+		var (
+			c      RegionMatrixCell
+			latest sql.NullString
+		)
+		if err := rows.Scan(&c.Region, &c.Service, &c.Count, &latest); err != nil {
+			return RegionMatrix{}, err
+		}
+		if latest.Valid {
+			if t, err := parseSQLiteTime(latest.String); err == nil {
+				c.LatestAt = &t
+			}
+		}
+		cells = append(cells, c)
+	}
+	if err := rows.Err(); err != nil {
+		return RegionMatrix{}, err
+	}
+	return newRegionMatrix(cells), nil
+}
+
+func sqliteLinkServices(ctx context.Context, tx *sql.Tx, whatsnewsID int, title string, tagNames []string) (bool, error) {
+	codes := extractServices(title, tagNames)
+	args := []any{whatsnewsID}
+	notIn := ""
+	if len(codes) > 0 {
+		notIn = " AND service_code NOT IN (" + placeholders(len(codes)) + ")"
+		for _, c := range codes {
+			args = append(args, c)
+		}
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM whatsnews_services WHERE whatsnew_id = ?"+notIn, args...)
+	if err != nil {
+		return false, fmt.Errorf("unlink services: %w", err)
+	}
+	changed, _ := res.RowsAffected()
+	for _, c := range codes {
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO whatsnews_services(whatsnew_id, service_code)
+             SELECT ?, code FROM services WHERE code = ?
+             ON CONFLICT DO NOTHING`, whatsnewsID, c)
+		if err != nil {
+			return false, fmt.Errorf("link services: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		changed += n
+	}
+	return changed > 0, nil
+}
+
+func sqliteLinkDimensions(ctx context.Context, tx *sql.Tx, whatsnewsID int, title, content string, tagNames []string) (bool, error) {
+	regions, err := sqliteLinkRegions(ctx, tx, whatsnewsID, title, content)
+	if err != nil {
+		return false, err
+	}
+	services, err := sqliteLinkServices(ctx, tx, whatsnewsID, title, tagNames)
+	return regions || services, err
+}
+
+func (s *SQLiteStore) SyncServices(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	codes := make([]any, 0, len(serviceCatalog.services))
+	for _, svc := range serviceCatalog.services {
+		aliases := svc.Aliases
+		if aliases == nil {
+			aliases = []string{}
+		}
+		aliasJSON, _ := json.Marshal(aliases)
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO services(code, name, aliases) VALUES(?, ?, ?)
+             ON CONFLICT (code) DO UPDATE SET name = excluded.name, aliases = excluded.aliases
+             WHERE name <> excluded.name OR aliases <> excluded.aliases`,
+			svc.Code, svc.Name, string(aliasJSON)); err != nil {
+			return fmt.Errorf("upsert service %s: %w", svc.Code, err)
+		}
+		codes = append(codes, svc.Code)
+	}
+	if len(codes) > 0 {
+		if _, err := tx.ExecContext(ctx,
+			"DELETE FROM services WHERE code NOT IN ("+placeholders(len(codes))+")", codes...); err != nil {
+			return fmt.Errorf("delete services: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+const sqliteServiceColumns = `s.code, s.name, s.aliases,
+       (SELECT COUNT(*) FROM whatsnews_services ws WHERE ws.service_code = s.code) AS news_cnt`
+
+func scanSQLiteService(r rowScanner) (Service, error) {
+	var (
+		svc     Service
+		aliases string
+	)
+	if err := r.Scan(&svc.Code, &svc.Name, &aliases, &svc.NewsCount); err != nil {
+		return Service{}, err
+	}
+	err := json.Unmarshal([]byte(aliases), &svc.Aliases)
+	return svc, err
+}
+
+func (s *SQLiteStore) GetServices(ctx context.Context) ([]Service, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+sqliteServiceColumns+" FROM services s ORDER BY news_cnt DESC, s.name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Service
+	for rows.Next() {
+		svc, err := scanSQLiteService(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, svc)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLiteStore) GetService(ctx context.Context, code string) (Service, error) {
+	svc, err := scanSQLiteService(s.db.QueryRowContext(ctx,
+		"SELECT "+sqliteServiceColumns+" FROM services s WHERE s.code = ?", code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Service{}, ErrNotFound
+	}
+	return svc, err
+}
+
+func (s *SQLiteStore) ReplaceInventory(ctx context.Context, inv Inventory) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM inventory_services`); err != nil {
+		return fmt.Errorf("clear inventory: %w", err)
+	}
+	now := time.Now().UTC()
+	for _, is := range inv.Services {
+		types := is.ResourceTypes
+		if types == nil {
+			types = []string{}
+		}
+		typesJSON, _ := json.Marshal(types)
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO inventory_services(service_code, source, resource_types, resource_count, uploaded_at)
+             VALUES(?, ?, ?, ?, ?)`,
+			is.Code, inv.Source, string(typesJSON), is.ResourceCount, now); err != nil {
+			return fmt.Errorf("insert inventory %s: %w", is.Code, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) GetInventory(ctx context.Context) (Inventory, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT i.service_code, s.name, i.resource_types, i.resource_count, i.source, i.uploaded_at
+FROM   inventory_services i
+JOIN   services s ON s.code = i.service_code
+ORDER  BY s.name`)
+	if err != nil {
+		return Inventory{}, err
+	}
+	defer rows.Close()
+
+	inv := Inventory{Services: []InventoryService{}}
+	for rows.Next() {
+		var (
+			is        InventoryService
+			typesJSON string
+			uploaded  time.Time
+		)
+		if err := rows.Scan(&is.Code, &is.Name, &typesJSON, &is.ResourceCount, &inv.Source, &uploaded); err != nil {
+			return Inventory{}, err
+		}
+		if err := json.Unmarshal([]byte(typesJSON), &is.ResourceTypes); err != nil {
+			return Inventory{}, err
+		}
+		inv.Services = append(inv.Services, is)
+		inv.UploadedAt = &uploaded
+	}
+	return inv, rows.Err()
+}
+
+// parseSQLiteTime은 집계 함수(MAX 등)가 돌려준 시각 문자열을 읽는다. 이 경우 드라이버가
+// 컬럼 타입을 모르므로 time.Time으로 바꿔 주지 않는다.
+func parseSQLiteTime(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999 -0700 MST", time.RFC3339Nano, "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", s)
+}
+
+// placeholders는 "?, ?, ?" 형태의 바인드 목록을 만든다.
+func placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
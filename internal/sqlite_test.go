@@ -0,0 +1,876 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	s, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(s.Close)
+	if err := s.SyncServices(context.Background()); err != nil {
+		t.Fatalf("SyncServices: %v", err)
+	}
+	return s
+}
+
+func awsTestItem(id, headline, postDateTime string, tags ...string) AwsApiItem {
+	el := AwsApiItem{
+		Item: AwsItem{
+			Id: id,
+			AdditionalFields: map[string]any{
+				"headline":     headline,
+				"postBody":     "<p>" + headline + "</p>",
+				"headlineUrl":  "https://aws.amazon.com/about-aws/whats-new/" + id,
+				"postDateTime": postDateTime,
+			},
+		},
+	}
+	for _, name := range tags {
+		el.Tags = append(el.Tags, AwsTag{Name: name})
+	}
+	return el
+}
+
+func TestSQLiteStoreInsertAndQuery(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	items := []AwsApiItem{
+		awsTestItem("a", "Amazon EC2 adds instance type", "2024-06-01T10:00:00Z", "EC2", "Compute"),
+		awsTestItem("b", "Amazon RDS supports new engine", "2024-06-02T10:00:00Z", "RDS"),
+		awsTestItem("c", "Amazon EC2 Auto Scaling update", "2024-06-03T10:00:00Z", "EC2"),
+	}
+	for _, el := range items {
+		if err := s.InsertAwsItem(ctx, el); err != nil {
+			t.Fatalf("InsertAwsItem(%s): %v", el.Item.Id, err)
+		}
+	}
+	// 같은 source_id 재삽입은 카운터를 늘리지 않아야 한다
+	if err := s.InsertAwsItem(ctx, items[0]); err != nil {
+		t.Fatalf("InsertAwsItem duplicate: %v", err)
+	}
+
+	exists, err := s.SourceIdExists(ctx, "b")
+	if err != nil || !exists {
+		t.Fatalf("SourceIdExists(b): got %v, %v", exists, err)
+	}
+
+	tags, err := s.GetTags(ctx, TagsQuery{Limit: 10})
+	if err != nil {
+		t.Fatalf("GetTags: %v", err)
+	}
+	if tags.Total != 3 {
+		t.Fatalf("tags.Total: got %d, want 3", tags.Total)
+	}
+	if tags.Items[0].Name != "EC2" || tags.Items[0].NewsCount != 2 {
+		t.Errorf("tags.Items[0]: got %+v, want EC2 with 2 news", tags.Items[0])
+	}
+
+	res, err := s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10})
+	if err != nil {
+		t.Fatalf("GetWhatsnews: %v", err)
+	}
+	if *res.Total != 3 || len(res.Items) != 3 {
+		t.Fatalf("GetWhatsnews: got total=%d len=%d, want 3", *res.Total, len(res.Items))
+	}
+	if res.Items[0].Title != "Amazon EC2 Auto Scaling update" {
+		t.Errorf("newest first: got %q", res.Items[0].Title)
+	}
+
+	ec2 := tags.Items[0].Id
+	res, err = s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10, TagIDs: []int{ec2}, Search: "auto scaling"})
+	if err != nil {
+		t.Fatalf("GetWhatsnews filtered: %v", err)
+	}
+	if *res.Total != 1 || res.Items[0].Title != "Amazon EC2 Auto Scaling update" {
+		t.Errorf("filtered: got %+v", res.Items)
+	}
+	if len(res.Items[0].Tags) != 1 || res.Items[0].Tags[0].Name != "EC2" {
+		t.Errorf("filtered tags: got %+v", res.Items[0].Tags)
+	}
+
+	if err := s.RebuildTagStats(ctx); err != nil {
+		t.Fatalf("RebuildTagStats: %v", err)
+	}
+	after, err := s.GetTags(ctx, TagsQuery{Limit: 10, Name: "ec"})
+	if err != nil {
+		t.Fatalf("GetTags after rebuild: %v", err)
+	}
+	if after.Total != 1 || after.Items[0].NewsCount != 2 {
+		t.Errorf("after rebuild: got %+v", after.Items)
+	}
+}
+
+func TestSQLiteStoreSearch(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	for _, el := range []AwsApiItem{
+		awsTestItem("a", "Amazon EC2 adds instance type", "2024-06-01T10:00:00Z"),
+		awsTestItem("b", "AWS Lambda supports new runtime for EC2 users", "2024-06-03T10:00:00Z"),
+		awsTestItem("c", "Amazon RDS preview of Aurora feature", "2024-06-02T10:00:00Z"),
+	} {
+		if err := s.InsertAwsItem(ctx, el); err != nil {
+			t.Fatalf("InsertAwsItem(%s): %v", el.Item.Id, err)
+		}
+	}
+
+	res, err := s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10, Search: "ec2", Sort: SortRelevance})
+	if err != nil {
+		t.Fatalf("GetWhatsnews: %v", err)
+	}
+	if *res.Total != 2 {
+		t.Fatalf("ec2: got total %d, want 2", *res.Total)
+	}
+	if res.Items[0].Title != "Amazon EC2 adds instance type" {
+		t.Errorf("relevance order: got %q first", res.Items[0].Title)
+	}
+	if res.Items[0].Headline != "Amazon <mark>EC2</mark> adds instance type" {
+		t.Errorf("headline: got %q", res.Items[0].Headline)
+	}
+
+	// 마크업 안의 단어("p" 태그)는 매칭되지 않아야 한다
+	res, err = s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10, Search: "p"})
+	if err != nil || *res.Total != 0 {
+		t.Errorf("markup match: got total %d, err %v", *res.Total, err)
+	}
+
+	res, err = s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10, Search: "lamb* OR aurora"})
+	if err != nil || *res.Total != 2 {
+		t.Errorf("prefix/or: got total %d, err %v", *res.Total, err)
+	}
+
+	res, err = s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10, Search: "-preview"})
+	if err != nil || *res.Total != 2 {
+		t.Errorf("exclude only: got total %d, err %v", *res.Total, err)
+	}
+
+	res, err = s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10, Search: `"instance type"`})
+	if err != nil || *res.Total != 1 {
+		t.Errorf("phrase: got total %d, err %v", *res.Total, err)
+	}
+}
+
+func awsNamespacedTag(namespace, slug, name string) AwsTag {
+	return AwsTag{
+		Id:             "whats-new-v2#" + namespace + "#" + slug,
+		Name:           name,
+		TagNamespaceId: "whats-new-v2#" + namespace,
+	}
+}
+
+func TestSQLiteStoreFacets(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	// 예전 방식(이름만)으로 들어온 태그는 id가 있는 태그가 오면 같은 행으로 합쳐진다
+	if err := s.InsertAwsItem(ctx, awsTestItem("old", "Old EC2 news", "2023-01-01T00:00:00Z", "Amazon EC2")); err != nil {
+		t.Fatalf("InsertAwsItem(old): %v", err)
+	}
+
+	ec2 := awsNamespacedTag("general-products", "amazon-ec2", "Amazon EC2")
+	rds := awsNamespacedTag("general-products", "amazon-rds", "Amazon RDS")
+	compute := awsNamespacedTag("marketing-marchitecture", "compute", "compute")
+	databases := awsNamespacedTag("marketing-marchitecture", "databases", "databases")
+
+	a := awsTestItem("a", "EC2 compute news", "2024-06-01T00:00:00Z")
+	a.Tags = []AwsTag{ec2, compute}
+	b := awsTestItem("b", "RDS database news", "2024-06-02T00:00:00Z")
+	b.Tags = []AwsTag{rds, databases}
+	c := awsTestItem("c", "EC2 database news", "2024-06-03T00:00:00Z")
+	c.Tags = []AwsTag{ec2, databases}
+	for _, el := range []AwsApiItem{a, b, c} {
+		if err := s.InsertAwsItem(ctx, el); err != nil {
+			t.Fatalf("InsertAwsItem(%s): %v", el.Item.Id, err)
+		}
+	}
+
+	tags, err := s.GetTags(ctx, TagsQuery{Limit: 10, Namespace: "general-products"})
+	if err != nil {
+		t.Fatalf("GetTags: %v", err)
+	}
+	if tags.Total != 2 {
+		t.Fatalf("general-products tags: got %d, want 2", tags.Total)
+	}
+	if tags.Items[0].Name != "Amazon EC2" || tags.Items[0].NewsCount != 3 || tags.Items[0].SourceId != ec2.Id {
+		t.Errorf("legacy tag not merged: got %+v", tags.Items[0])
+	}
+
+	namespaces, err := s.GetTagNamespaces(ctx)
+	if err != nil {
+		t.Fatalf("GetTagNamespaces: %v", err)
+	}
+	if len(namespaces) != 2 || namespaces[0].Name != "general-products" || namespaces[0].TagCount != 2 {
+		t.Errorf("namespaces: got %+v", namespaces)
+	}
+
+	res, err := s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10, Facets: map[string][]string{
+		"general-products":        {"amazon ec2"},
+		"marketing-marchitecture": {"compute"},
+	}})
+	if err != nil {
+		t.Fatalf("GetWhatsnews: %v", err)
+	}
+	if *res.Total != 1 || res.Items[0].Title != "EC2 compute news" {
+		t.Errorf("product AND category: got %+v", res.Items)
+	}
+
+	res, err = s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10, Facets: map[string][]string{
+		"general-products": {"Amazon EC2", "Amazon RDS"},
+	}})
+	if err != nil || *res.Total != 4 {
+		t.Errorf("OR within namespace: got total %d, err %v", *res.Total, err)
+	}
+	if res.Items[0].Tags[0].Namespace != "general-products" {
+		t.Errorf("item tag namespace: got %+v", res.Items[0].Tags)
+	}
+}
+
+func TestSQLiteStoreReprocess(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	var el AwsApiItem
+	raw := `{"item":{"id":"a","name":"extra-field-kept","additionalFields":{"headline":"Old title","postDateTime":"2024-06-01T00:00:00Z"}},"tags":[{"name":"A"},{"name":"B"}]}`
+	if err := json.Unmarshal([]byte(raw), &el); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if err := s.InsertAwsItem(ctx, el); err != nil {
+		t.Fatalf("InsertAwsItem: %v", err)
+	}
+
+	var stored string
+	if err := s.db.QueryRow("SELECT raw_payload FROM whatsnews WHERE source_id = 'a'").Scan(&stored); err != nil {
+		t.Fatalf("select raw_payload: %v", err)
+	}
+	if stored != raw {
+		t.Errorf("raw_payload: got %s", stored)
+	}
+
+	// 추출 규칙이 바뀐 상황을 흉내내기 위해 payload를 바꿔 둔다
+	changed := `{"item":{"id":"a","additionalFields":{"headline":"New title","postDateTime":"2024-06-01T00:00:00Z"}},"tags":[{"name":"B"},{"name":"C"}]}`
+	if _, err := s.db.Exec("UPDATE whatsnews SET raw_payload = ? WHERE source_id = 'a'", changed); err != nil {
+		t.Fatalf("update raw_payload: %v", err)
+	}
+
+	res, err := s.ReprocessAwsItems(ctx)
+	if err != nil {
+		t.Fatalf("ReprocessAwsItems: %v", err)
+	}
+	if res.Updated != 1 || res.Failed != 0 {
+		t.Errorf("result: got %+v", res)
+	}
+
+	news, err := s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10, Search: "new"})
+	if err != nil || *news.Total != 1 {
+		t.Fatalf("search reprocessed title: got %d, err %v", *news.Total, err)
+	}
+	var names []string
+	for _, tg := range news.Items[0].Tags {
+		names = append(names, tg.Name)
+	}
+	if strings.Join(names, ",") != "B,C" {
+		t.Errorf("tags: got %v, want B,C", names)
+	}
+
+	tags, err := s.GetTags(ctx, TagsQuery{Limit: 10})
+	if err != nil {
+		t.Fatalf("GetTags: %v", err)
+	}
+	counts := map[string]int{}
+	for _, tg := range tags.Items {
+		counts[tg.Name] = tg.NewsCount
+	}
+	if counts["A"] != 0 || counts["B"] != 1 || counts["C"] != 1 {
+		t.Errorf("tag counts: got %v", counts)
+	}
+
+	// 바뀐 것이 없으면 updated_at(피드의 갱신 시각)을 건드리지 않는다
+	updatedAt := func() time.Time {
+		t.Helper()
+		it, err := s.GetWhatsnewsItem(ctx, news.Items[0].Id)
+		if err != nil || it.UpdatedAt == nil {
+			t.Fatalf("GetWhatsnewsItem: %+v, %v", it, err)
+		}
+		return *it.UpdatedAt
+	}
+	before := updatedAt()
+	time.Sleep(10 * time.Millisecond)
+	if res, err := s.ReprocessAwsItems(ctx); err != nil || res.Updated != 1 {
+		t.Fatalf("ReprocessAwsItems again: %+v, %v", res, err)
+	}
+	if after := updatedAt(); !after.Equal(before) {
+		t.Errorf("updated_at changed without changes: %v -> %v", before, after)
+	}
+}
+
+func TestSQLiteStoreReprocessRelinks(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	// payload 없이 들어온 메일 레코드. collect처럼 리전 연결 없이 적재된 상황을 흉내낸다
+	res, err := s.IngestSourceItem(ctx, mailSourceItem(NewsItem{
+		Title: "Amazon RDS is now available in the Asia Pacific (Seoul) Region",
+		Link:  "https://aws.amazon.com/about-aws/whats-new/rds-seoul/",
+		Date:  "2024년 06월 02일",
+	}))
+	if err != nil || !res.Created {
+		t.Fatalf("IngestSourceItem: %+v, %v", res, err)
+	}
+	if _, err := s.db.Exec("DELETE FROM whatsnews_regions; DELETE FROM whatsnews_services"); err != nil {
+		t.Fatal(err)
+	}
+
+	rp, err := s.ReprocessAwsItems(ctx)
+	if err != nil {
+		t.Fatalf("ReprocessAwsItems: %v", err)
+	}
+	if rp.Skipped != 1 || rp.Relinked != 1 {
+		t.Errorf("result: got %+v", rp)
+	}
+	it, err := s.GetWhatsnewsItem(ctx, res.WhatsnewId)
+	if err != nil {
+		t.Fatalf("GetWhatsnewsItem: %v", err)
+	}
+	if strings.Join(it.Regions, ",") != "ap-northeast-2" || strings.Join(it.Services, ",") != "rds" {
+		t.Errorf("dimensions: regions=%v services=%v", it.Regions, it.Services)
+	}
+	// 다시 돌리면 바뀐 연결이 없다
+	if rp, err := s.ReprocessAwsItems(ctx); err != nil || rp.Relinked != 0 {
+		t.Errorf("second run: %+v, %v", rp, err)
+	}
+}
+
+func TestSQLiteStoreMailDate(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	// 메일 날짜는 KST 자정이다. 저장한 값도, 제목 창의 경계도 UTC로 비교해야 한다
+	mail := mailSourceItem(NewsItem{
+		Title: "Amazon RDS supports new engine",
+		Link:  "https://aws.amazon.com/about-aws/whats-new/rds-engine/",
+		Date:  "2024년 06월 02일",
+	})
+	if want := mustTime(t, "2024-06-01T15:00:00Z"); mail.PublishedAt == nil || !mail.PublishedAt.Equal(want) {
+		t.Fatalf("mailSourceItem: got %v, want %v", mail.PublishedAt, want)
+	}
+	res, err := s.IngestSourceItem(ctx, mail)
+	if err != nil || !res.Created {
+		t.Fatalf("IngestSourceItem(mail): got %+v, %v", res, err)
+	}
+	it, err := s.GetWhatsnewsItem(ctx, res.WhatsnewId)
+	if err != nil {
+		t.Fatalf("GetWhatsnewsItem: %v", err)
+	}
+	if got := it.SourceCreatedAt; got == nil || !got.Equal(*mail.PublishedAt) {
+		t.Errorf("source_created_at: got %v, want %v", got, mail.PublishedAt)
+	}
+
+	// 같은 시각을 UTC로 가진 RSS 항목은 제목으로 병합된다
+	published := mustTime(t, "2024-06-01T15:00:00Z")
+	res, err = s.IngestSourceItem(ctx, SourceItem{
+		Type: SourceRSS, SourceId: "guid-1", Title: "Amazon RDS now supports new engine",
+		Url: "https://aws.amazon.com/blogs/rds-engine", PublishedAt: &published,
+	})
+	if err != nil || !res.Merged || res.Method != MergeByTitle {
+		t.Errorf("IngestSourceItem(rss): got %+v, %v", res, err)
+	}
+}
+
+func TestSQLiteStoreMerge(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	// 메일이 API보다 먼저 들어온 발표
+	mail := mailSourceItem(NewsItem{
+		Title: "Amazon RDS supports new engine",
+		Link:  "https://aws.amazon.com/ko/about-aws/whats-new/rds/?sc_channel=em",
+		Date:  "2024년 06월 02일",
+	})
+	res, err := s.IngestSourceItem(ctx, mail)
+	if err != nil || !res.Created {
+		t.Fatalf("IngestSourceItem(mail): got %+v, %v", res, err)
+	}
+	if res, err := s.IngestSourceItem(ctx, mail); err != nil || !res.Skipped {
+		t.Fatalf("IngestSourceItem(mail) again: got %+v, %v", res, err)
+	}
+
+	// API 항목이 URL로 메일 레코드를 차지하고, 메일은 병합된 출처가 된다
+	if err := s.InsertAwsItem(ctx, awsTestItem("rds", "Amazon RDS supports new engine", "2024-06-01T20:00:00Z")); err != nil {
+		t.Fatalf("InsertAwsItem: %v", err)
+	}
+	// RSS는 제목으로 같은 레코드에 병합된다
+	published := mustTime(t, "2024-06-01T21:00:00Z")
+	res, err = s.IngestSourceItem(ctx, SourceItem{
+		Type: SourceRSS, SourceId: "guid-1", Title: "Amazon RDS now supports new engine",
+		Url: "https://aws.amazon.com/blogs/rds-engine", PublishedAt: &published,
+	})
+	if err != nil || !res.Merged || res.Method != MergeByTitle || res.WhatsnewId != 1 {
+		t.Fatalf("IngestSourceItem(rss): got %+v, %v", res, err)
+	}
+
+	list, err := s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10})
+	if err != nil {
+		t.Fatalf("GetWhatsnews: %v", err)
+	}
+	if *list.Total != 1 {
+		t.Fatalf("GetWhatsnews: got total=%d, want 1", *list.Total)
+	}
+	var types []string
+	for _, src := range list.Items[0].Sources {
+		types = append(types, src.Type+":"+src.SourceId)
+	}
+	want := "aws-api:rds,mail:aws.amazon.com/about-aws/whats-new/rds,rss:guid-1"
+	if strings.Join(types, ",") != want {
+		t.Errorf("sources: got %v, want %s", types, want)
+	}
+
+	merges, err := s.ListMerges(ctx, MergesQuery{Limit: 10})
+	if err != nil || len(merges) != 2 {
+		t.Fatalf("ListMerges: got %d, %v", len(merges), err)
+	}
+	mailMerge := merges[1]
+	if mailMerge.SourceType != SourceMail || mailMerge.Method != MergeByURL {
+		t.Fatalf("mail merge: got %+v", mailMerge)
+	}
+
+	// 되돌리면 메일 항목이 별도 레코드가 되고, 다시 들어와도 병합되지 않는다
+	undone, err := s.UndoMerge(ctx, mailMerge.Id)
+	if err != nil || undone.SplitWhatsnewId == nil {
+		t.Fatalf("UndoMerge: got %+v, %v", undone, err)
+	}
+	if _, err := s.UndoMerge(ctx, mailMerge.Id); !errors.Is(err, ErrMergeUndone) {
+		t.Errorf("UndoMerge again: got %v, want ErrMergeUndone", err)
+	}
+	if res, err := s.IngestSourceItem(ctx, mail); err != nil || !res.Skipped {
+		t.Fatalf("IngestSourceItem(mail) after undo: got %+v, %v", res, err)
+	}
+	list, err = s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10})
+	if err != nil || *list.Total != 2 {
+		t.Fatalf("GetWhatsnews after undo: got total=%d, %v", *list.Total, err)
+	}
+}
+
+func mustTime(t *testing.T, s string) time.Time {
+	t.Helper()
+	v, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestSQLiteStoreRegions(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	ec2 := awsNamespacedTag("general-products", "amazon-ec2", "Amazon EC2")
+	rds := awsNamespacedTag("general-products", "amazon-rds", "Amazon RDS")
+	items := []AwsApiItem{
+		awsTestItem("a", "Amazon EC2 C7g now available in Asia Pacific (Seoul)", "2024-06-01T10:00:00Z"),
+		awsTestItem("b", "Amazon RDS now available in Asia Pacific (Tokyo) and Seoul", "2024-06-02T10:00:00Z"),
+		awsTestItem("c", "Amazon EC2 C7g now available in Europe (Paris)", "2024-06-03T10:00:00Z"),
+	}
+	items[0].Tags = []AwsTag{ec2}
+	items[1].Tags = []AwsTag{rds}
+	items[2].Tags = []AwsTag{ec2}
+	for _, el := range items {
+		if err := s.InsertAwsItem(ctx, el); err != nil {
+			t.Fatalf("InsertAwsItem(%s): %v", el.Item.Id, err)
+		}
+	}
+
+	res, err := s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10, Regions: []string{"ap-northeast-2"}})
+	if err != nil {
+		t.Fatalf("GetWhatsnews: %v", err)
+	}
+	if *res.Total != 2 {
+		t.Fatalf("regions filter: got total=%d, want 2", *res.Total)
+	}
+	if got := strings.Join(res.Items[0].Regions, ","); got != "ap-northeast-1,ap-northeast-2" {
+		t.Errorf("item regions: got %s", got)
+	}
+
+	regions, err := s.GetRegions(ctx)
+	if err != nil {
+		t.Fatalf("GetRegions: %v", err)
+	}
+	if regions[0].Code != "ap-northeast-2" || regions[0].NewsCount != 2 {
+		t.Errorf("GetRegions[0]: got %+v", regions[0])
+	}
+
+	matrix, err := s.GetRegionMatrix(ctx, RegionMatrixQuery{Regions: []string{"ap-northeast-2", "eu-west-3"}})
+	if err != nil {
+		t.Fatalf("GetRegionMatrix: %v", err)
+	}
+	if len(matrix.Cells) != 3 || strings.Join(matrix.Services, ",") != "ec2,rds" {
+		t.Fatalf("GetRegionMatrix: got %+v", matrix)
+	}
+	if matrix.Cells[0].LatestAt == nil {
+		t.Errorf("GetRegionMatrix: latest_at not set")
+	}
+}
+
+func TestSQLiteStoreServices(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	items := []AwsApiItem{
+		awsTestItem("a", "Amazon RDS for PostgreSQL supports minor version 16.3", "2024-06-01T10:00:00Z"),
+		awsTestItem("b", "Announcing new instance sizes", "2024-06-02T10:00:00Z"),
+		awsTestItem("c", "AWS Config adds new resource types", "2024-06-03T10:00:00Z"),
+	}
+	// 제목에 없어도 제품 태그로 연결된다
+	items[1].Tags = []AwsTag{awsNamespacedTag("general-products", "amazon-rds", "Amazon RDS")}
+	for _, el := range items {
+		if err := s.InsertAwsItem(ctx, el); err != nil {
+			t.Fatalf("InsertAwsItem(%s): %v", el.Item.Id, err)
+		}
+	}
+
+	svc, err := s.GetService(ctx, "rds")
+	if err != nil || svc.Name != "Amazon RDS" || svc.NewsCount != 2 {
+		t.Fatalf("GetService(rds): got %+v, %v", svc, err)
+	}
+	if _, err := s.GetService(ctx, "nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetService(nope): got %v, want ErrNotFound", err)
+	}
+
+	res, err := s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10, Services: []string{"config"}})
+	if err != nil {
+		t.Fatalf("GetWhatsnews: %v", err)
+	}
+	if *res.Total != 1 || strings.Join(res.Items[0].Services, ",") != "config" {
+		t.Fatalf("services filter: got %+v", res)
+	}
+}
+
+func TestSQLiteStoreInventory(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	for _, el := range []AwsApiItem{
+		awsTestItem("a", "Amazon RDS for PostgreSQL supports minor version 16.3", "2024-06-01T10:00:00Z"),
+		awsTestItem("b", "AWS Lambda supports Python 3.13", "2024-06-02T10:00:00Z"),
+	} {
+		if err := s.InsertAwsItem(ctx, el); err != nil {
+			t.Fatalf("InsertAwsItem(%s): %v", el.Item.Id, err)
+		}
+	}
+
+	inv, err := ParseInventory([]byte(`{"version": 4, "resources": [
+  {"mode": "managed", "type": "aws_db_instance", "instances": [{}]}]}`))
+	if err != nil {
+		t.Fatalf("ParseInventory: %v", err)
+	}
+	if err := s.ReplaceInventory(ctx, inv); err != nil {
+		t.Fatalf("ReplaceInventory: %v", err)
+	}
+	stored, err := s.GetInventory(ctx)
+	if err != nil {
+		t.Fatalf("GetInventory: %v", err)
+	}
+	if len(stored.Services) != 1 || stored.Services[0].Code != "rds" || stored.UploadedAt == nil ||
+		strings.Join(stored.Services[0].ResourceTypes, ",") != "aws_db_instance" {
+		t.Fatalf("GetInventory: got %+v", stored)
+	}
+
+	res, err := s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10})
+	if err != nil {
+		t.Fatalf("GetWhatsnews: %v", err)
+	}
+	for _, it := range res.Items {
+		if want := strings.HasPrefix(it.Title, "Amazon RDS"); it.Relevant != want {
+			t.Errorf("%q relevant: got %v, want %v", it.Title, it.Relevant, want)
+		}
+	}
+	res, err = s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10, RelevantOnly: true})
+	if err != nil {
+		t.Fatalf("GetWhatsnews relevant_only: %v", err)
+	}
+	if *res.Total != 1 || !res.Items[0].Relevant {
+		t.Fatalf("relevant_only: got %+v", res)
+	}
+
+	if err := s.ReplaceInventory(ctx, Inventory{}); err != nil {
+		t.Fatalf("ReplaceInventory(empty): %v", err)
+	}
+	res, _ = s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10, RelevantOnly: true})
+	if *res.Total != 0 {
+		t.Errorf("after clear: got %d relevant items", *res.Total)
+	}
+}
+
+func TestSQLiteStoreItemAndRelated(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	rds := awsNamespacedTag("general-products", "amazon-rds", "Amazon RDS")
+	year := awsNamespacedTag("year", "2024", "2024")
+	items := []AwsApiItem{
+		awsTestItem("a", "Amazon RDS for PostgreSQL supports minor version 16.3", "2024-06-01T10:00:00Z"),
+		awsTestItem("b", "Amazon RDS for PostgreSQL supports minor version 16.4", "2024-08-01T10:00:00Z"),
+		awsTestItem("c", "Amazon RDS Proxy adds IAM authentication", "2024-07-01T10:00:00Z"),
+		awsTestItem("d", "AWS Lambda supports Python 3.13", "2024-06-02T10:00:00Z"),
+	}
+	items[0].Tags = []AwsTag{rds, year}
+	items[1].Tags = []AwsTag{rds, year}
+	items[2].Tags = []AwsTag{rds, year}
+	items[3].Tags = []AwsTag{year}
+	for _, el := range items {
+		if err := s.InsertAwsItem(ctx, el); err != nil {
+			t.Fatalf("InsertAwsItem(%s): %v", el.Item.Id, err)
+		}
+	}
+	published := mustTime(t, "2024-06-01T12:00:00Z")
+	if res, err := s.IngestSourceItem(ctx, SourceItem{
+		Type: SourceRSS, SourceId: "rss-a", Title: "Amazon RDS for PostgreSQL supports minor version 16.3",
+		Url: "https://aws.amazon.com/about-aws/whats-new/a", PublishedAt: &published,
+	}); err != nil || !res.Merged {
+		t.Fatalf("IngestSourceItem: got %+v, %v", res, err)
+	}
+
+	id, err := s.FindWhatsnewsBySource(ctx, "a")
+	if err != nil {
+		t.Fatalf("FindWhatsnewsBySource(a): %v", err)
+	}
+	for _, sid := range []string{"rss-a", "rss:rss-a"} {
+		if got, err := s.FindWhatsnewsBySource(ctx, sid); err != nil || got != id {
+			t.Errorf("FindWhatsnewsBySource(%s): got %d, %v; want %d", sid, got, err, id)
+		}
+	}
+	if _, err := s.FindWhatsnewsBySource(ctx, "nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindWhatsnewsBySource(nope): got %v, want ErrNotFound", err)
+	}
+
+	item, err := s.GetWhatsnewsItem(ctx, id)
+	if err != nil {
+		t.Fatalf("GetWhatsnewsItem: %v", err)
+	}
+	if item.Id != id || len(item.Tags) != 2 || len(item.Sources) != 2 {
+		t.Errorf("GetWhatsnewsItem: got %+v", item)
+	}
+	if _, err := s.GetWhatsnewsItem(ctx, 9999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetWhatsnewsItem(9999): got %v, want ErrNotFound", err)
+	}
+
+	related, err := s.GetRelatedWhatsnews(ctx, id, 5)
+	if err != nil {
+		t.Fatalf("GetRelatedWhatsnews: %v", err)
+	}
+	// d는 연도 태그만 공유하고 제목도 달라 빠진다
+	var titles []string
+	for _, r := range related {
+		titles = append(titles, r.Title)
+	}
+	if len(related) != 2 || related[0].Title != "Amazon RDS for PostgreSQL supports minor version 16.4" || related[0].SharedTags != 1 {
+		t.Fatalf("GetRelatedWhatsnews: got %q", titles)
+	}
+}
+
+func TestSQLiteStoreCursorPagination(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	for _, el := range []AwsApiItem{
+		awsTestItem("a", "News A", "2024-06-01T10:00:00Z"),
+		awsTestItem("b", "News B", "2024-06-03T10:00:00Z"),
+		awsTestItem("c", "News C", "2024-06-03T10:00:00Z"), // b와 같은 시각: id로 구분
+		awsTestItem("d", "News D", "2024-06-02T10:00:00Z"),
+		awsTestItem("e", "News E", ""), // 날짜 없음: 마지막
+	} {
+		if err := s.InsertAwsItem(ctx, el); err != nil {
+			t.Fatalf("InsertAwsItem(%s): %v", el.Item.Id, err)
+		}
+	}
+
+	var titles []string
+	q := WhatsNewsQuery{Limit: 2, SkipTotal: true}
+	for page := 0; ; page++ {
+		res, err := s.GetWhatsnews(ctx, q)
+		if err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+		if res.Total != nil {
+			t.Errorf("page %d: total counted with SkipTotal", page)
+		}
+		for _, it := range res.Items {
+			titles = append(titles, it.Title)
+		}
+		if page == 0 {
+			// 스크롤 중에 들어온 새 발표는 다음 페이지에 끼어들지 않는다
+			if err := s.InsertAwsItem(ctx, awsTestItem("f", "News F", "2024-06-04T10:00:00Z")); err != nil {
+				t.Fatalf("InsertAwsItem(f): %v", err)
+			}
+		}
+		if !res.HasMore {
+			if res.NextCursor != "" {
+				t.Errorf("last page has next_cursor")
+			}
+			break
+		}
+		c, err := DecodeWhatsNewsCursor(res.NextCursor)
+		if err != nil {
+			t.Fatalf("DecodeWhatsNewsCursor: %v", err)
+		}
+		q.After = &c
+	}
+	if got := strings.Join(titles, ","); got != "News B,News C,News D,News A,News E" {
+		t.Errorf("cursor pages: got %s", got)
+	}
+
+	res, err := s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 2})
+	if err != nil || *res.Total != 6 || *res.TotalPage != 3 || !res.HasMore {
+		t.Errorf("with total: got %+v, %v", res, err)
+	}
+	_, err = s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 2, Search: "news", Sort: SortRelevance, After: q.After})
+	if !errors.Is(err, ErrCursorSort) {
+		t.Errorf("relevance with cursor: got %v, want ErrCursorSort", err)
+	}
+}
+
+func TestSQLiteStoreDateRangeAndSort(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	for _, el := range []AwsApiItem{
+		awsTestItem("a", "March news", "2024-03-15T10:00:00Z"),
+		awsTestItem("b", "June news", "2024-06-30T23:00:00Z"),
+		awsTestItem("c", "July news", "2024-07-01T01:00:00Z"),
+	} {
+		if err := s.InsertAwsItem(ctx, el); err != nil {
+			t.Fatalf("InsertAwsItem(%s): %v", el.Item.Id, err)
+		}
+	}
+	titles := func(q WhatsNewsQuery) string {
+		t.Helper()
+		q.Limit = 10
+		res, err := s.GetWhatsnews(ctx, q)
+		if err != nil {
+			t.Fatalf("GetWhatsnews: %v", err)
+		}
+		var out []string
+		for _, it := range res.Items {
+			out = append(out, it.Title)
+		}
+		return strings.Join(out, ",")
+	}
+
+	from, to := mustTime(t, "2024-03-01T00:00:00Z"), mustTime(t, "2024-07-01T00:00:00Z")
+	if got := titles(WhatsNewsQuery{From: &from, To: &to}); got != "June news,March news" {
+		t.Errorf("from/to: got %s", got)
+	}
+	if got := titles(WhatsNewsQuery{Sort: SortOldest}); got != "March news,June news,July news" {
+		t.Errorf("oldest: got %s", got)
+	}
+
+	// 저장 시각 기준: 이후에 들어온 발표만
+	time.Sleep(10 * time.Millisecond)
+	since := time.Now()
+	if err := s.InsertAwsItem(ctx, awsTestItem("d", "Late crawl of old news", "2024-01-01T00:00:00Z")); err != nil {
+		t.Fatalf("InsertAwsItem(d): %v", err)
+	}
+	if got := titles(WhatsNewsQuery{Since: &since}); got != "Late crawl of old news" {
+		t.Errorf("since: got %s", got)
+	}
+	if got := titles(WhatsNewsQuery{Sort: SortUpdated}); !strings.HasPrefix(got, "Late crawl of old news,") {
+		t.Errorf("updated: got %s", got)
+	}
+}
+
+func TestSQLiteStoreTagModes(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	for _, el := range []AwsApiItem{
+		awsTestItem("a", "EC2 news", "2024-06-01T10:00:00Z", "EC2", "Compute"),
+		awsTestItem("b", "ECS news", "2024-06-02T10:00:00Z", "ECS", "Compute"),
+		awsTestItem("c", "ECS preview", "2024-06-03T10:00:00Z", "ECS", "Compute", "Preview"),
+		awsTestItem("d", "RDS news", "2024-06-04T10:00:00Z", "RDS"),
+	} {
+		if err := s.InsertAwsItem(ctx, el); err != nil {
+			t.Fatalf("InsertAwsItem(%s): %v", el.Item.Id, err)
+		}
+	}
+	tags, err := s.GetTags(ctx, TagsQuery{Limit: 10})
+	if err != nil {
+		t.Fatalf("GetTags: %v", err)
+	}
+	id := map[string]int{}
+	for _, tg := range tags.Items {
+		id[tg.Name] = tg.Id
+	}
+	titles := func(q WhatsNewsQuery) string {
+		t.Helper()
+		q.Limit = 10
+		res, err := s.GetWhatsnews(ctx, q)
+		if err != nil {
+			t.Fatalf("GetWhatsnews: %v", err)
+		}
+		var out []string
+		for _, it := range res.Items {
+			out = append(out, it.Title)
+		}
+		return strings.Join(out, ",")
+	}
+
+	if got := titles(WhatsNewsQuery{TagIDs: []int{id["EC2"], id["ECS"]}}); got != "" {
+		t.Errorf("all: got %s", got)
+	}
+	if got := titles(WhatsNewsQuery{TagIDs: []int{id["EC2"], id["ECS"]}, TagMode: TagModeAny}); got != "ECS preview,ECS news,EC2 news" {
+		t.Errorf("any: got %s", got)
+	}
+	if got := titles(WhatsNewsQuery{TagIDs: []int{id["Compute"]}, ExcludeTagIDs: []int{id["Preview"]}}); got != "ECS news,EC2 news" {
+		t.Errorf("compute but not preview: got %s", got)
+	}
+	if got := titles(WhatsNewsQuery{ExcludeTagIDs: []int{id["Compute"]}}); got != "RDS news" {
+		t.Errorf("exclude only: got %s", got)
+	}
+}
+
+func TestSQLiteStoreSourceTypes(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	if err := s.InsertAwsItem(ctx, awsTestItem("rds", "Amazon RDS supports new engine", "2024-06-01T20:00:00Z")); err != nil {
+		t.Fatalf("InsertAwsItem: %v", err)
+	}
+	published := mustTime(t, "2024-06-02T21:00:00Z")
+	for _, it := range []SourceItem{
+		// 제목으로 rds 레코드에 병합된다
+		{Type: SourceRSS, SourceId: "guid-1", Title: "Amazon RDS now supports new engine", Url: "https://aws.amazon.com/blogs/rds-engine", PublishedAt: &published},
+		{Type: SourceRSS, SourceId: "guid-2", Title: "AWS Lambda adds Python 3.13 runtime", Url: "https://aws.amazon.com/blogs/lambda-python", PublishedAt: &published},
+	} {
+		if _, err := s.IngestSourceItem(ctx, it); err != nil {
+			t.Fatalf("IngestSourceItem(%s): %v", it.SourceId, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		types []string
+		want  int
+	}{
+		{nil, 2},
+		{[]string{SourceRSS}, 2}, // 병합된 출처도 센다
+		{[]string{SourceAwsApi}, 1},
+		{[]string{SourceMail}, 0},
+		{[]string{SourceMail, SourceAwsApi}, 1},
+	} {
+		res, err := s.GetWhatsnews(ctx, WhatsNewsQuery{Limit: 10, SourceTypes: tc.types})
+		if err != nil {
+			t.Fatalf("GetWhatsnews(%v): %v", tc.types, err)
+		}
+		if *res.Total != tc.want || len(res.Items) != tc.want {
+			t.Errorf("GetWhatsnews(%v): got total=%d items=%d, want %d", tc.types, *res.Total, len(res.Items), tc.want)
+		}
+	}
+}
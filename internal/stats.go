@@ -0,0 +1,467 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// 통계 버킷 단위
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week" // 월요일 시작 (date_trunc와 같다)
+	BucketMonth = "month"
+)
+
+// 통계를 묶는 기준
+const (
+	GroupNone    = "none"
+	GroupTag     = "tag"
+	GroupService = "service"
+	GroupSource  = "source" // 레코드 자체와 병합된 출처를 모두 센다
+)
+
+// maxStatsBuckets는 시계열 하나의 최대 버킷 수.
+const maxStatsBuckets = 1000
+
+var ErrStatsRange = errors.New("too many buckets; narrow from/to or use a larger bucket")
+
+// StatsQuery는 Store.GetStats의 집계 조건. 필터에 그룹 기준과 같은 종류의 값이 있으면
+// (group_by=tag&tags=1,2) 그 값들이 각각의 그룹이 된다. 두 태그를 모두 가진 뉴스가 아니라
+// 태그 1과 태그 2 각각의 건수다.
+type StatsQuery struct {
+	// Filter는 /api/whatsnews와 같은 필터. Limit/Offset/After/Sort는 쓰지 않는다
+	Filter WhatsNewsQuery
+	// Bucket이 비어 있으면 기간을 나누지 않고 센다
+	Bucket string
+	// Location은 버킷 경계의 시간대. nil이면 UTC
+	Location *time.Location
+	GroupBy  string
+	// Namespace가 있으면 GroupTag에서 이 네임스페이스의 태그만 묶는다
+	Namespace string
+	// Limit은 건수가 많은 순으로 남길 그룹 수. 0이면 전부
+	Limit int
+}
+
+func (q *StatsQuery) normalize() {
+	q.Filter.normalize()
+	if q.Location == nil {
+		q.Location = time.UTC
+	}
+	if q.GroupBy == "" {
+		q.GroupBy = GroupNone
+	}
+}
+
+// StatsCount는 GetStats가 돌려주는 (그룹, 시각)별 건수. Bucket은 버킷 시작 시각이거나
+// (SQLite) 발표 시각 그대로이며, 호출하는 쪽이 truncateBucket으로 맞춘다.
+type StatsCount struct {
+	Key    string
+	Name   string
+	Bucket *time.Time
+	Count  int
+}
+
+// StatsSeries는 그룹 하나의 시계열. Counts는 StatsTimeseries.Buckets와 같은 순서다.
+type StatsSeries struct {
+	Key    string `json:"key"`
+	Name   string `json:"name"`
+	Total  int    `json:"total"`
+	Counts []int  `json:"counts"`
+}
+
+// StatsTimeseries는 /api/stats/timeseries 응답.
+type StatsTimeseries struct {
+	Bucket  string        `json:"bucket"`
+	GroupBy string        `json:"group_by"`
+	TZ      string        `json:"tz"`
+	Buckets []time.Time   `json:"buckets"`
+	Series  []StatsSeries `json:"series"`
+}
+
+// StatsTopItem은 기간 안의 건수와 바로 앞 같은 길이 기간의 건수.
+type StatsTopItem struct {
+	Key           string `json:"key"`
+	Name          string `json:"name"`
+	Count         int    `json:"count"`
+	PreviousCount int    `json:"previous_count"`
+	Change        int    `json:"change"`
+}
+
+// StatsTop은 /api/stats/top 응답.
+type StatsTop struct {
+	GroupBy string         `json:"group_by"`
+	Period  string         `json:"period"`
+	From    time.Time      `json:"from"`
+	To      time.Time      `json:"to"`
+	Items   []StatsTopItem `json:"items"`
+}
+
+// truncateBucket은 t가 loc 기준으로 속한 버킷의 시작 시각 (UTC).
+func truncateBucket(t time.Time, bucket string, loc *time.Location) time.Time {
+	t = t.In(loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	switch bucket {
+	case BucketWeek:
+		day = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case BucketMonth:
+		day = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	}
+	return day.UTC()
+}
+
+// nextBucket은 버킷 시작 시각 t 다음 버킷의 시작 시각 (UTC).
+func nextBucket(t time.Time, bucket string, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch bucket {
+	case BucketWeek:
+		return t.AddDate(0, 0, 7).UTC()
+	case BucketMonth:
+		return t.AddDate(0, 1, 0).UTC()
+	}
+	return t.AddDate(0, 0, 1).UTC()
+}
+
+// GetStatsTimeseries는 q.Bucket 단위 시계열을 만든다. 범위는 from/to가 있으면 그 기간,
+// 없으면 첫 발표와 마지막 발표가 속한 버킷까지이며 빈 버킷은 0으로 채운다.
+// 발표 시각이 없는 뉴스는 세지 않는다.
+func GetStatsTimeseries(ctx context.Context, store Store, q StatsQuery) (StatsTimeseries, error) {
+	q.normalize()
+	if q.Bucket == "" {
+		q.Bucket = BucketWeek
+	}
+	counts, err := store.GetStats(ctx, q)
+	if err != nil {
+		return StatsTimeseries{}, err
+	}
+
+	var first, last time.Time
+	for i, c := range counts {
+		b := truncateBucket(*c.Bucket, q.Bucket, q.Location)
+		counts[i].Bucket = &b
+		if first.IsZero() || b.Before(first) {
+			first = b
+		}
+		if last.IsZero() || b.After(last) {
+			last = b
+		}
+	}
+	if q.Filter.From != nil {
+		first = truncateBucket(*q.Filter.From, q.Bucket, q.Location)
+	}
+	if q.Filter.To != nil {
+		last = truncateBucket(q.Filter.To.Add(-time.Nanosecond), q.Bucket, q.Location)
+	}
+
+	res := StatsTimeseries{
+		Bucket:  q.Bucket,
+		GroupBy: q.GroupBy,
+		TZ:      q.Location.String(),
+		Buckets: []time.Time{},
+		Series:  []StatsSeries{},
+	}
+	index := map[time.Time]int{}
+	if !first.IsZero() {
+		for b := first; !b.After(last); b = nextBucket(b, q.Bucket, q.Location) {
+			if len(res.Buckets) == maxStatsBuckets {
+				return StatsTimeseries{}, ErrStatsRange
+			}
+			index[b] = len(res.Buckets)
+			res.Buckets = append(res.Buckets, b)
+		}
+	}
+
+	series := map[string]*StatsSeries{}
+	for _, c := range counts {
+		s := series[c.Key]
+		if s == nil {
+			s = &StatsSeries{Key: c.Key, Name: c.Name, Counts: make([]int, len(res.Buckets))}
+			series[c.Key] = s
+		}
+		if i, ok := index[*c.Bucket]; ok {
+			s.Counts[i] += c.Count
+			s.Total += c.Count
+		}
+	}
+	for _, s := range series {
+		res.Series = append(res.Series, *s)
+	}
+	sort.Slice(res.Series, func(i, j int) bool {
+		a, b := res.Series[i], res.Series[j]
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.Key < b.Key
+	})
+	return res, nil
+}
+
+var statsPeriodPattern = regexp.MustCompile(`^([1-9][0-9]{0,3})([dwmy])$`)
+
+// periodStart는 end에서 period(30d, 12w, 6m, 1y)만큼 거슬러 올라간 시각.
+func periodStart(end time.Time, period string) (time.Time, error) {
+	m := statsPeriodPattern.FindStringSubmatch(period)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("invalid period %q (use e.g. 30d, 12w, 6m, 1y)", period)
+	}
+	n, _ := strconv.Atoi(m[1])
+	switch m[2] {
+	case "w":
+		return end.AddDate(0, 0, -7*n), nil
+	case "m":
+		return end.AddDate(0, -n, 0), nil
+	case "y":
+		return end.AddDate(-n, 0, 0), nil
+	}
+	return end.AddDate(0, 0, -n), nil
+}
+
+// GetStatsTop은 [end-period, end) 동안 건수가 많은 그룹과 바로 앞 같은 길이 기간의 건수를
+// 돌려준다. end는 q.Filter.To, 없으면 now. q.Filter.From은 period로 대신한다.
+func GetStatsTop(ctx context.Context, store Store, q StatsQuery, period string, now time.Time) (StatsTop, error) {
+	q.normalize()
+	q.Bucket = ""
+	end := now.UTC()
+	if q.Filter.To != nil {
+		end = *q.Filter.To
+	}
+	start, err := periodStart(end, period)
+	if err != nil {
+		return StatsTop{}, err
+	}
+	prevStart, _ := periodStart(start, period)
+
+	cur := q
+	cur.Filter.From, cur.Filter.To = &start, &end
+	counts, err := store.GetStats(ctx, cur)
+	if err != nil {
+		return StatsTop{}, err
+	}
+	prev := q
+	prev.Filter.From, prev.Filter.To, prev.Limit = &prevStart, &start, 0
+	prevCounts, err := store.GetStats(ctx, prev)
+	if err != nil {
+		return StatsTop{}, err
+	}
+	previous := map[string]int{}
+	for _, c := range prevCounts {
+		previous[c.Key] += c.Count
+	}
+
+	res := StatsTop{GroupBy: q.GroupBy, Period: period, From: start, To: end, Items: []StatsTopItem{}}
+	for _, c := range counts {
+		p := previous[c.Key]
+		res.Items = append(res.Items, StatsTopItem{Key: c.Key, Name: c.Name, Count: c.Count, PreviousCount: p, Change: c.Count - p})
+	}
+	sort.Slice(res.Items, func(i, j int) bool {
+		a, b := res.Items[i], res.Items[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Key < b.Key
+	})
+	return res, nil
+}
+
+// statsGroup은 그룹 기준별로 필터된 뉴스(m)에 붙이는 JOIN과 키·이름 식.
+type statsGroup struct {
+	join      string
+	key, name string
+}
+
+func (s *PgStore) GetStats(ctx context.Context, q StatsQuery) ([]StatsCount, error) {
+	q.normalize()
+	fq := q.Filter
+	// group_by=tag에서는 tags를 뉴스 필터가 아니라 그룹 제한으로 쓴다
+	groupTags := q.GroupBy == GroupTag && len(fq.TagIDs) > 0
+	if groupTags {
+		fq.TagIDs = nil
+	}
+	f := newPgFilter(fq)
+	if q.Bucket != "" {
+		f.conds = append(f.conds, "wn.source_created_at IS NOT NULL")
+	}
+
+	var g statsGroup
+	switch q.GroupBy {
+	case GroupTag:
+		g = statsGroup{join: `JOIN whatsnews_tags g ON g.whatsnew_id = m.id
+JOIN tags t ON t.id = g.tag_id`, key: "t.id::text", name: "t.name"}
+		if groupTags {
+			g.join += " AND t.id = ANY(" + f.arg(q.Filter.TagIDs) + "::int[])"
+		}
+		if q.Namespace != "" {
+			g.join += " AND t.namespace = " + f.arg(q.Namespace)
+		}
+	case GroupService:
+		g = statsGroup{join: `JOIN whatsnews_services g ON g.whatsnew_id = m.id
+JOIN services sv ON sv.code = g.service_code`, key: "sv.code", name: "sv.name"}
+		if len(fq.Services) > 0 {
+			g.join += " AND sv.code = ANY(" + f.arg(fq.Services) + "::text[])"
+		}
+	case GroupSource:
+		g = statsGroup{join: `JOIN (
+  SELECT id AS whatsnew_id, source_type FROM whatsnews
+  UNION
+  SELECT whatsnew_id, source_type FROM whatsnews_merges WHERE undone_at IS NULL
+) g ON g.whatsnew_id = m.id`, key: "g.source_type", name: "g.source_type"}
+		if len(fq.SourceTypes) > 0 {
+			g.join += " AND g.source_type = ANY(" + f.arg(fq.SourceTypes) + "::text[])"
+		}
+	default:
+		g = statsGroup{key: "'all'", name: "'All'"}
+	}
+
+	bucket := "NULL::timestamptz"
+	if q.Bucket != "" {
+		// source_created_at은 UTC로 저장한 TIMESTAMP라, 세션 TimeZone으로 해석되지 않게 먼저 timestamptz로 바꾼다
+		bucket = "date_trunc(" + f.arg(q.Bucket) + ", m.source_created_at AT TIME ZONE 'UTC', " + f.arg(q.Location.String()) + ")"
+	}
+	limit := "ALL"
+	if q.Limit > 0 {
+		limit = f.arg(q.Limit)
+	}
+
+	with := f.with()
+	if with == "" {
+		with = "WITH "
+	} else {
+		with += ", "
+	}
+	rows, err := s.pool.Query(ctx, with+`matched AS (
+  SELECT wn.id, wn.source_created_at
+  FROM   `+f.from()+`
+  `+f.where()+`
+), counts AS (
+  SELECT `+g.key+` AS key, `+g.name+` AS name, `+bucket+` AS bucket, COUNT(*)::int AS n
+  FROM   matched m
+  `+g.join+`
+  GROUP  BY 1, 2, 3
+), top AS (
+  SELECT key FROM counts GROUP BY key ORDER BY SUM(n) DESC, key LIMIT `+limit+`
+)
+SELECT c.key, c.name, c.bucket, c.n
+FROM   counts c
+JOIN   top USING (key)
+ORDER  BY c.key, c.bucket;
+`, f.args...)
+	if err != nil {
+		return nil, fmt.Errorf("stats: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[StatsCount])
+}
+
+// GetStats는 SQLite에 date_trunc와 시간대 변환이 없어 버킷을 나누지 않고 발표 시각별로 센다.
+// GetStatsTimeseries가 시각을 버킷 시작으로 맞춰 합친다.
+func (s *SQLiteStore) GetStats(ctx context.Context, q StatsQuery) ([]StatsCount, error) {
+	q.normalize()
+	fq := q.Filter
+	groupTags := q.GroupBy == GroupTag && len(fq.TagIDs) > 0
+	if groupTags {
+		fq.TagIDs = nil
+	}
+	f := newSQLiteFilter(fq)
+	if q.Bucket != "" {
+		f.conds = append(f.conds, "wn.source_created_at IS NOT NULL")
+	}
+	args := f.args
+
+	var g statsGroup
+	switch q.GroupBy {
+	case GroupTag:
+		g = statsGroup{join: `JOIN whatsnews_tags g ON g.whatsnew_id = m.id
+JOIN tags t ON t.id = g.tag_id`, key: "CAST(t.id AS TEXT)", name: "t.name"}
+		if groupTags {
+			g.join += " AND t.id IN (" + placeholders(len(q.Filter.TagIDs)) + ")"
+			for _, id := range q.Filter.TagIDs {
+				args = append(args, id)
+			}
+		}
+		if q.Namespace != "" {
+			g.join += " AND t.namespace = ?"
+			args = append(args, q.Namespace)
+		}
+	case GroupService:
+		g = statsGroup{join: `JOIN whatsnews_services g ON g.whatsnew_id = m.id
+JOIN services sv ON sv.code = g.service_code`, key: "sv.code", name: "sv.name"}
+		if len(fq.Services) > 0 {
+			g.join += " AND sv.code IN (" + placeholders(len(fq.Services)) + ")"
+			for _, c := range fq.Services {
+				args = append(args, c)
+			}
+		}
+	case GroupSource:
+		g = statsGroup{join: `JOIN (
+  SELECT id AS whatsnew_id, source_type FROM whatsnews
+  UNION
+  SELECT whatsnew_id, source_type FROM whatsnews_merges WHERE undone_at IS NULL
+) g ON g.whatsnew_id = m.id`, key: "g.source_type", name: "g.source_type"}
+		if len(fq.SourceTypes) > 0 {
+			g.join += " AND g.source_type IN (" + placeholders(len(fq.SourceTypes)) + ")"
+			for _, t := range fq.SourceTypes {
+				args = append(args, t)
+			}
+		}
+	default:
+		g = statsGroup{key: "'all'", name: "'All'"}
+	}
+
+	bucket := "NULL"
+	if q.Bucket != "" {
+		bucket = "m.source_created_at"
+	}
+	limit := -1
+	if q.Limit > 0 {
+		limit = q.Limit
+	}
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, `
+WITH matched AS (
+  SELECT wn.id, wn.source_created_at
+  FROM   `+f.from+`
+  `+f.where()+`
+), counts AS (
+  SELECT `+g.key+` AS key, `+g.name+` AS name, `+bucket+` AS bucket, COUNT(*) AS n
+  FROM   matched m
+  `+g.join+`
+  GROUP  BY 1, 2, 3
+), top AS (
+  SELECT key FROM counts GROUP BY key ORDER BY SUM(n) DESC, key LIMIT ?
+)
+SELECT c.key, c.name, c.bucket, c.n
+FROM   counts c
+JOIN   top ON top.key = c.key
+ORDER  BY c.key, c.bucket`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("stats: %w", err)
+	}
+	defer rows.Close()
+
+	var out []StatsCount
+	for rows.Next() {
+		var (
+			c  StatsCount
+			at sql.NullString
+		)
+		if err := rows.Scan(&c.Key, &c.Name, &at, &c.Count); err != nil {
+			return nil, err
+		}
+		if at.Valid {
+			t, err := parseSQLiteTime(at.String)
+			if err != nil {
+				return nil, fmt.Errorf("stats: %w", err)
+			}
+			c.Bucket = &t
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
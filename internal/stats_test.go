@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTruncateBucket(t *testing.T) {
+	seoul, err := time.LoadLocation("Asia/Seoul")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	cases := []struct {
+		at     string
+		bucket string
+		loc    *time.Location
+		want   string
+	}{
+		{"2024-06-05T13:00:00Z", BucketDay, time.UTC, "2024-06-05T00:00:00Z"},
+		{"2024-06-05T13:00:00Z", BucketWeek, time.UTC, "2024-06-03T00:00:00Z"},
+		{"2024-06-09T23:59:00Z", BucketWeek, time.UTC, "2024-06-03T00:00:00Z"}, // 일요일은 그 주의 마지막 날
+		{"2024-06-05T13:00:00Z", BucketMonth, time.UTC, "2024-06-01T00:00:00Z"},
+		// 서울 기준 6월 10일(월) 05시
+		{"2024-06-09T20:00:00Z", BucketWeek, seoul, "2024-06-09T15:00:00Z"},
+		{"2024-05-31T16:00:00Z", BucketMonth, seoul, "2024-05-31T15:00:00Z"},
+	}
+	for _, c := range cases {
+		got := truncateBucket(mustTime(t, c.at), c.bucket, c.loc)
+		if !got.Equal(mustTime(t, c.want)) {
+			t.Errorf("truncateBucket(%s, %s, %s) = %s, want %s", c.at, c.bucket, c.loc, got.Format(time.RFC3339), c.want)
+		}
+	}
+
+	if got := nextBucket(mustTime(t, "2024-01-31T15:00:00Z"), BucketMonth, seoul); !got.Equal(mustTime(t, "2024-02-29T15:00:00Z")) {
+		t.Errorf("nextBucket(month, Seoul) = %s", got.Format(time.RFC3339))
+	}
+}
+
+func TestPeriodStart(t *testing.T) {
+	end := mustTime(t, "2024-06-30T00:00:00Z")
+	for period, want := range map[string]string{
+		"30d": "2024-05-31T00:00:00Z",
+		"2w":  "2024-06-16T00:00:00Z",
+		"6m":  "2023-12-30T00:00:00Z",
+		"1y":  "2023-06-30T00:00:00Z",
+	} {
+		got, err := periodStart(end, period)
+		if err != nil || !got.Equal(mustTime(t, want)) {
+			t.Errorf("periodStart(%s) = %s, %v; want %s", period, got.Format(time.RFC3339), err, want)
+		}
+	}
+	for _, period := range []string{"", "0d", "30", "1h", "d"} {
+		if _, err := periodStart(end, period); err == nil {
+			t.Errorf("periodStart(%q): want error", period)
+		}
+	}
+}
+
+func newStatsTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	ctx := context.Background()
+	s := newTestStore(t)
+	for _, it := range []AwsApiItem{
+		awsTestItem("a", "Amazon EC2 adds instances", "2024-06-03T00:00:00Z", "Amazon EC2"),
+		awsTestItem("b", "Amazon RDS supports new engine", "2024-06-05T00:00:00Z", "Amazon RDS"),
+		awsTestItem("c", "Amazon EC2 expands to Seoul", "2024-06-12T00:00:00Z", "Amazon EC2"),
+		awsTestItem("d", "Amazon EC2 adds capacity blocks", "2024-06-26T00:00:00Z", "Amazon EC2"),
+	} {
+		if err := s.InsertAwsItem(ctx, it); err != nil {
+			t.Fatalf("InsertAwsItem: %v", err)
+		}
+	}
+	published := mustTime(t, "2024-06-05T02:00:00Z")
+	res, err := s.IngestSourceItem(ctx, SourceItem{
+		Type: SourceRSS, SourceId: "guid-b", Title: "Amazon RDS now supports new engine",
+		Url: "https://aws.amazon.com/blogs/rds-engine", PublishedAt: &published,
+	})
+	if err != nil || !res.Merged {
+		t.Fatalf("IngestSourceItem: %+v, %v", res, err)
+	}
+	return s
+}
+
+func TestStatsTimeseries(t *testing.T) {
+	ctx := context.Background()
+	s := newStatsTestStore(t)
+
+	res, err := GetStatsTimeseries(ctx, s, StatsQuery{Bucket: BucketWeek})
+	if err != nil {
+		t.Fatalf("GetStatsTimeseries: %v", err)
+	}
+	wantBuckets := []time.Time{
+		mustTime(t, "2024-06-03T00:00:00Z"), mustTime(t, "2024-06-10T00:00:00Z"),
+		mustTime(t, "2024-06-17T00:00:00Z"), mustTime(t, "2024-06-24T00:00:00Z"),
+	}
+	if !reflect.DeepEqual(res.Buckets, wantBuckets) {
+		t.Fatalf("buckets: got %v", res.Buckets)
+	}
+	if len(res.Series) != 1 || res.Series[0].Key != "all" || !reflect.DeepEqual(res.Series[0].Counts, []int{2, 1, 0, 1}) {
+		t.Errorf("series: %+v", res.Series)
+	}
+
+	// group_by=tag&tags=...: 태그마다 시계열 하나 (모두 가진 뉴스가 아니라)
+	tags, err := s.GetTags(ctx, TagsQuery{Limit: 10})
+	if err != nil {
+		t.Fatalf("GetTags: %v", err)
+	}
+	var tagIDs []int
+	for _, tag := range tags.Items {
+		tagIDs = append(tagIDs, tag.Id)
+	}
+	res, err = GetStatsTimeseries(ctx, s, StatsQuery{
+		Bucket: BucketWeek, GroupBy: GroupTag,
+		Filter: WhatsNewsQuery{TagIDs: tagIDs, From: ptrTime(mustTime(t, "2024-06-01T00:00:00Z")), To: ptrTime(mustTime(t, "2024-06-15T00:00:00Z"))},
+	})
+	if err != nil {
+		t.Fatalf("GetStatsTimeseries(tag): %v", err)
+	}
+	if len(res.Buckets) != 3 || !res.Buckets[0].Equal(mustTime(t, "2024-05-27T00:00:00Z")) {
+		t.Errorf("buckets with from/to: %v", res.Buckets)
+	}
+	got := map[string][]int{}
+	for _, se := range res.Series {
+		got[se.Name] = se.Counts
+	}
+	if !reflect.DeepEqual(got, map[string][]int{"Amazon EC2": {0, 1, 1}, "Amazon RDS": {0, 1, 0}}) {
+		t.Errorf("tag series: %v", got)
+	}
+
+	// 병합된 출처도 센다
+	res, err = GetStatsTimeseries(ctx, s, StatsQuery{Bucket: BucketMonth, GroupBy: GroupSource})
+	if err != nil {
+		t.Fatalf("GetStatsTimeseries(source): %v", err)
+	}
+	got = map[string][]int{}
+	for _, se := range res.Series {
+		got[se.Key] = se.Counts
+	}
+	if !reflect.DeepEqual(got, map[string][]int{SourceAwsApi: {4}, SourceRSS: {1}}) {
+		t.Errorf("source series: %v", got)
+	}
+
+	// 필터는 /api/whatsnews와 같다
+	res, err = GetStatsTimeseries(ctx, s, StatsQuery{Bucket: BucketMonth, Filter: WhatsNewsQuery{Search: "capacity"}})
+	if err != nil {
+		t.Fatalf("GetStatsTimeseries(search): %v", err)
+	}
+	if len(res.Series) != 1 || res.Series[0].Total != 1 {
+		t.Errorf("search series: %+v", res.Series)
+	}
+
+	if _, err := GetStatsTimeseries(ctx, s, StatsQuery{
+		Bucket: BucketDay,
+		Filter: WhatsNewsQuery{From: ptrTime(mustTime(t, "2000-01-01T00:00:00Z"))},
+	}); err != ErrStatsRange {
+		t.Errorf("day buckets since 2000: got %v, want ErrStatsRange", err)
+	}
+}
+
+func TestStatsTop(t *testing.T) {
+	ctx := context.Background()
+	s := newStatsTestStore(t)
+
+	res, err := GetStatsTop(ctx, s, StatsQuery{GroupBy: GroupTag, Limit: 5}, "14d", mustTime(t, "2024-06-27T00:00:00Z"))
+	if err != nil {
+		t.Fatalf("GetStatsTop: %v", err)
+	}
+	if !res.From.Equal(mustTime(t, "2024-06-13T00:00:00Z")) {
+		t.Errorf("from: %v", res.From)
+	}
+	want := []StatsTopItem{{Name: "Amazon EC2", Count: 1, PreviousCount: 2, Change: -1}}
+	for i := range res.Items {
+		res.Items[i].Key = ""
+	}
+	if !reflect.DeepEqual(res.Items, want) {
+		t.Errorf("items: %+v", res.Items)
+	}
+
+	// to가 있으면 그 시각이 기간의 끝
+	res, err = GetStatsTop(ctx, s, StatsQuery{GroupBy: GroupTag, Filter: WhatsNewsQuery{To: ptrTime(mustTime(t, "2024-06-13T00:00:00Z"))}}, "14d", time.Now())
+	if err != nil {
+		t.Fatalf("GetStatsTop(to): %v", err)
+	}
+	if len(res.Items) != 2 || res.Items[0].Name != "Amazon EC2" || res.Items[0].Count != 2 || res.Items[1].Count != 1 {
+		t.Errorf("items with to: %+v", res.Items)
+	}
+}
+
+func ptrTime(t time.Time) *time.Time { return &t }
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrMergeUndone = errors.New("merge already undone")
+)
+
+// Store는 whatsnews/tags 저장소의 읽기·쓰기·태그 통계 연산을 묶은 인터페이스.
+// PostgreSQL(PgStore)과 단일 노드용 SQLite(SQLiteStore) 구현이 있다.
+type Store interface {
+	GetWhatsnews(ctx context.Context, q WhatsNewsQuery) (WhatsNewsResult, error)
+	// GetWhatsnewsItem은 id의 뉴스 하나를 목록과 같은 모양으로 돌려준다. 없으면 ErrNotFound.
+	GetWhatsnewsItem(ctx context.Context, id int) (WhatsNews, error)
+	// FindWhatsnewsBySource는 출처 식별자로 whatsnews id를 찾는다. 없으면 ErrNotFound.
+	FindWhatsnewsBySource(ctx context.Context, sourceID string) (int, error)
+	// GetRelatedWhatsnews는 공유 태그와 제목 유사도로 고른 관련 발표를 돌려준다. related.go 참고.
+	GetRelatedWhatsnews(ctx context.Context, id, limit int) ([]RelatedWhatsNews, error)
+	// Suggest는 검색어와 비슷한 태그·서비스·제목을 종류별로 limit개씩 찾는다. suggest.go 참고.
+	Suggest(ctx context.Context, q string, limit int) (Suggestions, error)
+	// SimilarWords는 "did you mean" 교정에 쓸, word와 비슷한 단어를 모은다.
+	SimilarWords(ctx context.Context, word string) ([]string, error)
+	GetTags(ctx context.Context, q TagsQuery) (TagsResult, error)
+	GetTagNamespaces(ctx context.Context) ([]TagNamespace, error)
+	// GetRegions는 리전 카탈로그 전체와 리전별 뉴스 건수를 돌려준다.
+	GetRegions(ctx context.Context) ([]Region, error)
+	GetRegionMatrix(ctx context.Context, q RegionMatrixQuery) (RegionMatrix, error)
+	// GetServices는 서비스 카탈로그와 서비스별 뉴스 건수를 돌려준다.
+	GetServices(ctx context.Context) ([]Service, error)
+	// GetService는 code의 서비스를 돌려준다. 없으면 ErrNotFound.
+	GetService(ctx context.Context, code string) (Service, error)
+	// SyncServices는 services 테이블을 현재 서비스 카탈로그와 맞춘다. NewStore가 호출한다.
+	SyncServices(ctx context.Context) error
+
+	// ReplaceInventory는 인벤토리(팀이 쓰는 서비스 목록)를 통째로 바꾼다.
+	ReplaceInventory(ctx context.Context, inv Inventory) error
+	GetInventory(ctx context.Context) (Inventory, error)
+
+	SourceIdExists(ctx context.Context, sourceId string) (bool, error)
+	InsertAwsItem(ctx context.Context, el AwsApiItem) error
+
+	// IngestSourceItem은 API 외 출처(메일, RSS)의 항목을 같은 발표의 기존 레코드에 병합하거나
+	// 새 레코드로 저장한다. 판단 규칙은 canonical.go 참고.
+	IngestSourceItem(ctx context.Context, item SourceItem) (IngestResult, error)
+	// ListMerges는 병합 결정을 최신순으로 돌려준다.
+	ListMerges(ctx context.Context, q MergesQuery) ([]MergeDecision, error)
+	// UndoMerge는 병합된 출처를 별도 레코드로 분리하고 결정을 되돌림으로 표시한다.
+	UndoMerge(ctx context.Context, id int) (MergeDecision, error)
+	// BackfillCanonicalURLs는 canonical_url이 비어 있는 레코드(초기 적재 등)를 채운다.
+	BackfillCanonicalURLs(ctx context.Context) (int, error)
+
+	// ReprocessAwsItems는 저장된 원본 payload로 whatsnews 컬럼과 태그 연결을 다시 만든다.
+	// 새로 추출할 필드가 생겼을 때 AWS를 다시 수집하지 않고 반영하기 위한 것.
+	ReprocessAwsItems(ctx context.Context) (ReprocessResult, error)
+
+	// RebuildTagStats는 whatsnews_tags 기준으로 태그별 뉴스 건수를 다시 계산한다.
+	RebuildTagStats(ctx context.Context) error
+
+	// GetStats는 필터에 맞는 뉴스를 그룹·버킷별로 센다. stats.go 참고.
+	GetStats(ctx context.Context, q StatsQuery) ([]StatsCount, error)
+
+	// ListenNewWhatsnews는 새 뉴스 레코드가 저장될 때마다 fn(id)를 부른다. ctx가 끝나거나
+	// 연결이 끊길 때까지 돌아오지 않는다. stream.go 참고.
+	ListenNewWhatsnews(ctx context.Context, fn func(id int)) error
+
+	// CreateAPIKey는 키 해시와 함께 k를 저장한다. 키 발급은 IssueAPIKey로 한다.
+	CreateAPIKey(ctx context.Context, k APIKey, keyHash string) (APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+	// RevokeAPIKey는 id의 키를 폐기한다. 이미 폐기한 키는 그대로 돌려주고, 없으면 ErrNotFound.
+	RevokeAPIKey(ctx context.Context, id int) (APIKey, error)
+	// UseAPIKey는 해시가 keyHash인 폐기되지 않은 키를 찾아 last_used_at을 갱신한다. 없으면 ErrNotFound.
+	UseAPIKey(ctx context.Context, keyHash string) (APIKey, error)
+
+	// DataVersion은 데이터가 바뀔 때마다 DB 트리거가 올리는 버전. 응답 캐시 무효화에 쓴다.
+	DataVersion(ctx context.Context) (DataVersion, error)
+
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// NewStore는 cfg.DBDriver에 맞는 Store를 열고 services 테이블을 서비스 카탈로그와 맞춘다.
+func NewStore(cfg Config) (Store, error) {
+	if cfg.ServicesCatalog != "" {
+		if err := LoadServiceCatalog(cfg.ServicesCatalog); err != nil {
+			return nil, fmt.Errorf("load service catalog: %w", err)
+		}
+	}
+
+	var store Store
+	switch cfg.DBDriver {
+	case DriverPostgres, "":
+		pool, err := NewDBPool(cfg)
+		if err != nil {
+			return nil, err
+		}
+		store = NewPgStore(pool)
+	case DriverSQLite:
+		s, err := NewSQLiteStore(cfg.DBPath)
+		if err != nil {
+			return nil, err
+		}
+		store = s
+	default:
+		return nil, fmt.Errorf("unknown DATABASE_DRIVER %q", cfg.DBDriver)
+	}
+
+	if err := store.SyncServices(context.Background()); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("sync services: %w", err)
+	}
+	return store, nil
+}
@@ -0,0 +1,336 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// whatsnewsNotifyChannel은 새 whatsnews 레코드를 알리는 NOTIFY 채널. payload는 id.
+const whatsnewsNotifyChannel = "whatsnews_new"
+
+const (
+	// streamReplayLimit은 Last-Event-ID로 다시 연결했을 때 보내는 최대 건수. 더 밀렸으면
+	// 오래된 것부터 이만큼 보내고 reset 이벤트로 목록을 다시 읽으라고 알린다
+	streamReplayLimit = 100
+	// streamBufferSize만큼 밀린 구독자는 끊는다. 다시 연결하면 Last-Event-ID로 이어 받는다
+	streamBufferSize = 64
+	// streamRetry는 클라이언트(EventSource)가 끊긴 뒤 다시 연결하기까지 기다리는 시간
+	streamRetry = 5 * time.Second
+)
+
+// streamHeartbeatInterval마다 주석 한 줄을 보내 프록시가 유휴 연결을 끊지 않게 한다.
+var streamHeartbeatInterval = 15 * time.Second
+
+// sqlitePollInterval은 SQLite 저장소가 새 뉴스를 확인하는 주기 (SQLite에는 LISTEN/NOTIFY가 없다).
+var sqlitePollInterval = 2 * time.Second
+
+// pgNotifyNew는 새 whatsnews 레코드를 알린다. NOTIFY는 트랜잭션이 커밋될 때 전달된다.
+func pgNotifyNew(ctx context.Context, tx pgx.Tx, id int) error {
+	_, err := tx.Exec(ctx, "SELECT pg_notify($1, $2)", whatsnewsNotifyChannel, strconv.Itoa(id))
+	return err
+}
+
+// ListenNewWhatsnews는 전용 연결에서 LISTEN하고 알림마다 fn을 부른다.
+func (s *PgStore) ListenNewWhatsnews(ctx context.Context, fn func(id int)) error {
+	pc, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// LISTEN 상태의 연결을 풀에 돌려주지 않는다
+	conn := pc.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+whatsnewsNotifyChannel); err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		id, err := strconv.Atoi(n.Payload)
+		if err != nil {
+			log.Printf("stream: bad notification payload %q", n.Payload)
+			continue
+		}
+		fn(id)
+	}
+}
+
+// ListenNewWhatsnews는 sqlitePollInterval마다 마지막으로 본 id보다 큰 뉴스를 찾는다.
+// 다른 프로세스가 같은 파일에 넣은 뉴스도 보인다.
+func (s *SQLiteStore) ListenNewWhatsnews(ctx context.Context, fn func(id int)) error {
+	var last int
+	if err := s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM whatsnews`).Scan(&last); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(sqlitePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		rows, err := s.db.QueryContext(ctx, `SELECT id FROM whatsnews WHERE id > ? ORDER BY id`, last)
+		if err != nil {
+			return err
+		}
+		var ids []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		// :memory: DB는 연결이 하나뿐이라 fn의 조회는 rows를 닫은 뒤에 한다
+		for _, id := range ids {
+			fn(id)
+			last = id
+		}
+	}
+}
+
+// StreamBroker는 새 뉴스를 /api/stream 구독자에게 나눠 준다. 구독자가 있는 동안만
+// 저장소의 ListenNewWhatsnews를 돌리고, 같은 필터의 구독자는 한 번만 조회한다.
+type StreamBroker struct {
+	store Store
+
+	mu     sync.Mutex
+	subs   map[*streamSubscriber]struct{}
+	cancel context.CancelFunc // 돌고 있는 listen을 멈춘다. 구독자가 없으면 nil
+	lastID int                // listen이 마지막으로 알린 id. 다시 연결할 때 놓친 뉴스를 찾는다
+}
+
+type streamSubscriber struct {
+	q   WhatsNewsQuery
+	key string // 필터를 구별하는 키 (정렬된 쿼리 문자열)
+	ch  chan WhatsNews
+}
+
+func NewStreamBroker(store Store) *StreamBroker {
+	return &StreamBroker{store: store, subs: map[*streamSubscriber]struct{}{}}
+}
+
+// subscribe는 q에 맞는 새 뉴스를 받을 구독자를 등록한다. 첫 구독자면 listen을 시작한다.
+func (b *StreamBroker) subscribe(q WhatsNewsQuery, key string) *streamSubscriber {
+	sub := &streamSubscriber{q: q, key: key, ch: make(chan WhatsNews, streamBufferSize)}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub] = struct{}{}
+	if b.cancel == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		b.cancel = cancel
+		go b.run(ctx)
+	}
+	return sub
+}
+
+func (b *StreamBroker) unsubscribe(sub *streamSubscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removeLocked(sub)
+}
+
+func (b *StreamBroker) removeLocked(sub *streamSubscriber) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	if len(b.subs) == 0 && b.cancel != nil {
+		b.cancel()
+		b.cancel, b.lastID = nil, 0
+	}
+}
+
+// run은 ctx가 끝날 때까지 listen하고, 연결이 끊기면 기다렸다가 다시 연결해 그 사이의
+// 뉴스부터 알린다.
+func (b *StreamBroker) run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		err := b.store.ListenNewWhatsnews(ctx, func(id int) { b.publish(ctx, id) })
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("stream: listen: %v (retry in %s)", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, time.Minute)
+
+		b.mu.Lock()
+		last := b.lastID
+		b.mu.Unlock()
+		if last > 0 {
+			b.catchUp(ctx, last)
+		}
+	}
+}
+
+// catchUp은 listen이 끊긴 사이에 들어온 last 다음 뉴스를 id 순서로 모두 알린다.
+func (b *StreamBroker) catchUp(ctx context.Context, last int) {
+	for {
+		missed, err := b.store.GetWhatsnews(ctx, WhatsNewsQuery{SinceID: last, Sort: sortID, Limit: streamReplayLimit, SkipTotal: true})
+		if err != nil {
+			log.Printf("stream: catch up after %d: %v", last, err)
+			return
+		}
+		for _, it := range missed.Items {
+			b.publish(ctx, it.Id)
+			last = it.Id
+		}
+		if !missed.HasMore {
+			return
+		}
+	}
+}
+
+// publish는 id의 뉴스를 필터별로 한 번씩 조회해 맞는 구독자에게 보낸다.
+func (b *StreamBroker) publish(ctx context.Context, id int) {
+	b.mu.Lock()
+	b.lastID = max(b.lastID, id)
+	groups := map[string][]*streamSubscriber{}
+	for sub := range b.subs {
+		groups[sub.key] = append(groups[sub.key], sub)
+	}
+	b.mu.Unlock()
+
+	for _, subs := range groups {
+		q := subs[0].q
+		q.IDs, q.Limit, q.Offset, q.After, q.SkipTotal = []int{id}, 1, 0, nil, true
+		res, err := b.store.GetWhatsnews(ctx, q)
+		if err != nil {
+			log.Printf("stream: load whatsnews %d: %v", id, err)
+			continue
+		}
+		if len(res.Items) == 0 {
+			continue
+		}
+		b.mu.Lock()
+		for _, sub := range subs {
+			if _, ok := b.subs[sub]; !ok {
+				continue
+			}
+			select {
+			case sub.ch <- res.Items[0]:
+			default:
+				// 못 따라오는 구독자. 채널을 닫아 연결을 끝낸다
+				b.removeLocked(sub)
+				close(sub.ch)
+			}
+		}
+		b.mu.Unlock()
+	}
+}
+
+// ServeStream은 q에 맞는 새 뉴스를 Server-Sent Events로 보낸다. 이벤트 id는 whatsnews id이고,
+// lastEventID가 있으면 그보다 큰 뉴스를 id 순서로 streamReplayLimit건까지 먼저 보낸다. 그보다
+// 많이 밀렸으면 reset 이벤트를 보내고 연결을 끊는다. 새 뉴스를 이어 보내면 Last-Event-ID가 보내지
+// 않은 뉴스를 건너뛰기 때문이다. 클라이언트는 목록을 다시 읽거나, 다시 연결해 마지막으로 받은 id
+// 다음부터 이어 받는다.
+func (b *StreamBroker) ServeStream(w http.ResponseWriter, r *http.Request, q WhatsNewsQuery, lastEventID int) {
+	ctx := r.Context()
+	sub := b.subscribe(q, r.URL.Query().Encode())
+	defer b.unsubscribe(sub)
+
+	// 구독한 뒤에 읽어야 그 사이에 들어온 뉴스를 놓치지 않는다
+	var (
+		replay      []WhatsNews
+		replayReset bool
+	)
+	if lastEventID > 0 {
+		rq := q
+		rq.SinceID, rq.Limit, rq.Offset, rq.After, rq.Sort, rq.SkipTotal = lastEventID, streamReplayLimit, 0, nil, sortID, true
+		res, err := b.store.GetWhatsnews(ctx, rq)
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		replay, replayReset = res.Items, res.HasMore
+	}
+
+	rc := http.NewResponseController(w)
+	extendWriteDeadline(r, rc)
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no") // nginx가 버퍼링하지 않게
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+
+	replayed := lastEventID
+	for _, it := range replay {
+		if err := writeStreamEvent(w, it); err != nil {
+			return
+		}
+		replayed = it.Id
+	}
+	if replayReset {
+		// id를 붙이지 않아 Last-Event-ID는 마지막으로 보낸 뉴스에 머문다
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+		rc.Flush()
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		logRequestError(r, fmt.Errorf("stream: %w", err))
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+	shuttingDown := serverShuttingDown(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-shuttingDown:
+			// 클라이언트는 retry 후 다른 인스턴스(또는 재시작한 서버)에 Last-Event-ID로 다시 붙는다
+			return
+		case it, ok := <-sub.ch:
+			if !ok {
+				return
+			}
+			// 재전송한 것과 겹치는 알림
+			if it.Id <= replayed {
+				continue
+			}
+			if err := writeStreamEvent(w, it); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+		extendWriteDeadline(r, rc)
+	}
+}
+
+// writeStreamEvent는 뉴스 하나를 whatsnews 이벤트로 쓴다. data는 목록 항목과 같은 JSON이다.
+func writeStreamEvent(w http.ResponseWriter, it WhatsNews) error {
+	data, err := json.Marshal(it)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: whatsnews\ndata: %s\n\n", it.Id, data)
+	return err
+}
@@ -0,0 +1,262 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sseEvent struct {
+	id, event, data string
+	comment         bool
+}
+
+// readSSE는 본문에서 이벤트를 읽어 채널로 보낸다. 주석 줄은 comment 이벤트 하나로 보낸다.
+func readSSE(body *bufio.Reader) <-chan sseEvent {
+	out := make(chan sseEvent, 16)
+	go func() {
+		defer close(out)
+		var ev sseEvent
+		for {
+			line, err := body.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "":
+				if ev != (sseEvent{}) {
+					out <- ev
+				}
+				ev = sseEvent{}
+			case strings.HasPrefix(line, ":"):
+				ev.comment = true
+			case strings.HasPrefix(line, "id: "):
+				ev.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				ev.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				ev.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return out
+}
+
+// nextItem은 다음 whatsnews 이벤트의 뉴스를 돌려준다. 하트비트는 건너뛰고 seenPing에 표시한다.
+func nextItem(t *testing.T, events <-chan sseEvent, seenPing *bool) WhatsNews {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatal("stream closed")
+			}
+			if ev.comment {
+				*seenPing = true
+				continue
+			}
+			if ev.event != "whatsnews" {
+				continue
+			}
+			var it WhatsNews
+			if err := json.Unmarshal([]byte(ev.data), &it); err != nil {
+				t.Fatalf("event data: %v", err)
+			}
+			if ev.id != strconv.Itoa(it.Id) {
+				t.Errorf("event id %q for whatsnews %d", ev.id, it.Id)
+			}
+			return it
+		case <-timeout:
+			t.Fatal("no event")
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	defer func(poll, hb time.Duration) {
+		sqlitePollInterval, streamHeartbeatInterval = poll, hb
+	}(sqlitePollInterval, streamHeartbeatInterval)
+	sqlitePollInterval, streamHeartbeatInterval = 10*time.Millisecond, 50*time.Millisecond
+
+	ctx := context.Background()
+	s := newTestStore(t)
+	for _, it := range []AwsApiItem{
+		awsTestItem("a", "Amazon EC2 adds instances", "2024-06-01T00:00:00Z", "Amazon EC2"),
+		awsTestItem("b", "Amazon RDS supports engine", "2024-06-02T00:00:00Z", "Amazon RDS"),
+	} {
+		if err := s.InsertAwsItem(ctx, it); err != nil {
+			t.Fatalf("InsertAwsItem: %v", err)
+		}
+	}
+	tags, err := s.GetTags(ctx, TagsQuery{Limit: 10, Name: "Amazon EC2"})
+	if err != nil || len(tags.Items) != 1 {
+		t.Fatalf("GetTags: %+v, %v", tags, err)
+	}
+	ec2 := tags.Items[0].Id
+
+	broker := NewStreamBroker(s)
+	srv := httptest.NewServer(LoggingMiddleware(newMux(s, nil, broker, NewAssets(""))))
+	defer srv.Close()
+
+	// Last-Event-ID가 없으면 연결한 뒤에 들어온 뉴스만 받는다
+	reqCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(reqCtx, http.MethodGet, srv.URL+"/api/stream?tags="+strconv.Itoa(ec2), nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type: %q", ct)
+	}
+	events := readSSE(bufio.NewReader(resp.Body))
+
+	var seenPing bool
+	for _, it := range []AwsApiItem{
+		awsTestItem("c", "Amazon RDS adds storage", "2024-06-03T00:00:00Z", "Amazon RDS"),
+		awsTestItem("d", "Amazon EC2 in Seoul", "2024-06-04T00:00:00Z", "Amazon EC2"),
+	} {
+		if err := s.InsertAwsItem(ctx, it); err != nil {
+			t.Fatalf("InsertAwsItem: %v", err)
+		}
+	}
+	live := nextItem(t, events, &seenPing)
+	if live.Title != "Amazon EC2 in Seoul" {
+		t.Errorf("live event: got %q", live.Title)
+	}
+	time.Sleep(2 * streamHeartbeatInterval)
+	cancel()
+	for ev := range events {
+		seenPing = seenPing || ev.comment
+	}
+	if !seenPing {
+		t.Error("no heartbeat")
+	}
+
+	// 다시 연결하면 Last-Event-ID 다음의 맞는 뉴스부터 받는다 (b, c는 태그가 달라 빠진다)
+	reqCtx, cancel = context.WithCancel(ctx)
+	defer cancel()
+	req, _ = http.NewRequestWithContext(reqCtx, http.MethodGet, srv.URL+"/api/stream?tags="+strconv.Itoa(ec2), nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/stream: %v", err)
+	}
+	defer resp.Body.Close()
+	events = readSSE(bufio.NewReader(resp.Body))
+	if it := nextItem(t, events, &seenPing); it.Id != live.Id {
+		t.Errorf("replay: got %d %q, want %d", it.Id, it.Title, live.Id)
+	}
+	cancel()
+	for range events {
+	}
+
+	// 구독자가 없으면 listen을 멈춘다
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		broker.mu.Lock()
+		idle := len(broker.subs) == 0 && broker.cancel == nil
+		broker.mu.Unlock()
+		if idle {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("broker still listening after clients left")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStreamReplayGap(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	n := streamReplayLimit + 5
+	for i := 0; i <= n; i++ {
+		id := strconv.Itoa(i)
+		if err := s.InsertAwsItem(ctx, awsTestItem("gap-"+id, "Amazon EC2 update "+id, "2024-06-01T00:00:00Z")); err != nil {
+			t.Fatalf("InsertAwsItem: %v", err)
+		}
+	}
+
+	broker := NewStreamBroker(s)
+	srv := httptest.NewServer(newMux(s, nil, broker, NewAssets("")))
+	defer srv.Close()
+
+	// 한도보다 많이 밀렸으면 오래된 것부터 한도만큼 보내고 reset을 보낸 뒤 연결을 끊는다.
+	// 다시 연결하면 그다음부터 이어 받는다
+	replay := func(lastEventID int) (ids []int, reset bool) {
+		t.Helper()
+		reqCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(reqCtx, http.MethodGet, srv.URL+"/api/stream", nil)
+		req.Header.Set("Last-Event-ID", strconv.Itoa(lastEventID))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /api/stream: %v", err)
+		}
+		defer resp.Body.Close()
+		events := readSSE(bufio.NewReader(resp.Body))
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					if !reset {
+						t.Fatalf("stream closed without reset after %d ids", len(ids))
+					}
+					return ids, reset
+				}
+				switch ev.event {
+				case "whatsnews":
+					if reset {
+						t.Fatalf("event after reset: %+v", ev)
+					}
+					id, _ := strconv.Atoi(ev.id)
+					ids = append(ids, id)
+				case "reset":
+					reset = true
+				}
+			case <-timeout:
+				if reset {
+					t.Fatalf("stream still open after reset")
+				}
+				return ids, false
+			}
+			if len(ids) == n-streamReplayLimit && lastEventID > 1 {
+				return ids, false
+			}
+		}
+	}
+	ids, reset := replay(1)
+	if len(ids) != streamReplayLimit || ids[0] != 2 || ids[len(ids)-1] != streamReplayLimit+1 || !reset {
+		t.Fatalf("first replay: %d ids (%v…), reset=%v", len(ids), ids[:min(len(ids), 3)], reset)
+	}
+	ids, reset = replay(ids[len(ids)-1])
+	if len(ids) != n-streamReplayLimit || ids[0] != streamReplayLimit+2 || reset {
+		t.Fatalf("second replay: %v, reset=%v", ids, reset)
+	}
+
+	// listen이 끊긴 사이의 뉴스는 한도와 관계없이 id 순서로 모두 알린다
+	sub := broker.subscribe(WhatsNewsQuery{Limit: 1}, "")
+	defer broker.unsubscribe(sub)
+	go broker.catchUp(ctx, 1)
+	for want := 2; want <= n+1; want++ {
+		select {
+		case it, ok := <-sub.ch:
+			if !ok || it.Id != want {
+				t.Fatalf("catch up: got %d (open=%v), want %d", it.Id, ok, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("catch up stopped before %d", want)
+		}
+	}
+}
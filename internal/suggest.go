@@ -0,0 +1,430 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultSuggestLimit = 5
+	maxSuggestLimit     = 20
+	// maxSuggestQueryLen은 q의 최대 길이 (문자 수)
+	maxSuggestQueryLen = 100
+	// suggestMinWordSimilarity는 태그·서비스·제목 후보로 볼 최소 word_similarity
+	// (pg_trgm 기본값 0.6은 한 글자 오타도 자주 놓친다)
+	suggestMinWordSimilarity = 0.4
+	// suggestMinSimilarity는 "did you mean" 교정 후보로 볼 최소 단어 유사도 (pg_trgm 기본 임계값)
+	suggestMinSimilarity = 0.3
+	// suggestTitleCandidates는 교정 후보 단어를 뽑을 제목 수
+	suggestTitleCandidates = 50
+)
+
+// Suggestions는 /api/suggest 응답. DidYouMean은 q로 찾은 뉴스가 없을 때만, 고친 검색어로는
+// 뉴스가 있을 때만 채운다.
+type Suggestions struct {
+	Query      string            `json:"query"`
+	Tags       []Tag             `json:"tags"`
+	Services   []Service         `json:"services"`
+	Titles     []TitleSuggestion `json:"titles"`
+	DidYouMean string            `json:"did_you_mean,omitempty"`
+}
+
+// TitleSuggestion은 검색어와 비슷한 제목의 발표.
+type TitleSuggestion struct {
+	Id    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+// GetSuggestions는 q와 비슷한 태그·서비스·제목을 종류별로 limit개씩 찾고, q로 찾은 뉴스가
+// 없으면 고친 검색어를 제안한다.
+func GetSuggestions(ctx context.Context, store Store, q string, limit int) (Suggestions, error) {
+	res, err := store.Suggest(ctx, q, limit)
+	if err != nil {
+		return Suggestions{}, err
+	}
+	res.Query = q
+
+	found, err := hasSearchResults(ctx, store, q)
+	if err != nil || found {
+		return res, err
+	}
+	res.DidYouMean, err = didYouMean(ctx, store, q)
+	return res, err
+}
+
+func hasSearchResults(ctx context.Context, store Store, search string) (bool, error) {
+	res, err := store.GetWhatsnews(ctx, WhatsNewsQuery{Search: search, Limit: 1, SkipTotal: true})
+	return len(res.Items) > 0, err
+}
+
+// didYouMean은 검색어의 단어 중 그 자체로는 찾은 뉴스가 없는 단어를 태그·서비스·제목에 있는
+// 가장 비슷한 단어로 바꾼다. 제외어와 접두어는 고치지 않는다. 바꾼 검색어로도 뉴스가 없으면
+// 빈 문자열.
+func didYouMean(ctx context.Context, store Store, search string) (string, error) {
+	terms := parseSearch(search)
+	changed := false
+	for i, t := range terms {
+		if t.Negate {
+			continue
+		}
+		for j, w := range t.Words {
+			if len([]rune(w)) < 3 || (t.Prefix && j == len(t.Words)-1) {
+				continue
+			}
+			if _, err := strconv.Atoi(w); err == nil {
+				continue
+			}
+			found, err := hasSearchResults(ctx, store, w)
+			if err != nil {
+				return "", err
+			}
+			if found {
+				continue
+			}
+			cands, err := store.SimilarWords(ctx, w)
+			if err != nil {
+				return "", err
+			}
+			if best := closestWord(w, cands); best != "" {
+				terms[i].Words[j] = best
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return "", nil
+	}
+	corrected := formatSearch(terms)
+	found, err := hasSearchResults(ctx, store, corrected)
+	if err != nil || !found {
+		return "", err
+	}
+	return corrected, nil
+}
+
+// closestWord는 cands 중 word와 가장 비슷한 단어. 유사도가 같으면 길이 차가 작은 것,
+// 그다음 사전순. suggestMinSimilarity 미만뿐이면 빈 문자열.
+func closestWord(word string, cands []string) string {
+	type scored struct {
+		w    string
+		sim  float64
+		diff int
+	}
+	var best *scored
+	n := len([]rune(word))
+	for _, c := range cands {
+		if c == word {
+			continue
+		}
+		sc := scored{c, titleSimilarity(word, c), abs(len([]rune(c)) - n)}
+		if sc.sim < suggestMinSimilarity {
+			continue
+		}
+		if best == nil || sc.sim > best.sim ||
+			(sc.sim == best.sim && (sc.diff < best.diff || (sc.diff == best.diff && sc.w < best.w))) {
+			best = &sc
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return best.w
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// formatSearch는 parseSearch 결과를 다시 검색어 문자열로 만든다.
+func formatSearch(terms []searchTerm) string {
+	var parts []string
+	for i, t := range terms {
+		if t.Or && i > 0 {
+			parts = append(parts, "OR")
+		}
+		s := strings.Join(t.Words, " ")
+		if len(t.Words) > 1 {
+			s = `"` + s + `"`
+		}
+		if t.Prefix {
+			s += "*"
+		}
+		if t.Negate {
+			s = "-" + s
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
+}
+
+// wordSimilarity는 pg_trgm의 word_similarity()처럼 s의 3-gram 중 text의 연속된 단어 구간과
+// 겹치는 비율의 최댓값을 0~1로 계산한다. 구간은 단어 단위로 자른다.
+func wordSimilarity(s, text string) float64 {
+	ts := trigrams(s)
+	if len(ts) == 0 {
+		return 0
+	}
+	words := searchWords(text)
+	n := len(searchWords(s))
+	best := 0
+	for i := range words {
+		for j := i + 1; j <= len(words) && j-i <= n; j++ {
+			inter := 0
+			for g := range trigrams(strings.Join(words[i:j], " ")) {
+				if ts[g] {
+					inter++
+				}
+			}
+			best = max(best, inter)
+		}
+	}
+	return float64(best) / float64(len(ts))
+}
+
+// suggestScore는 q가 후보 이름들 중 하나에 부분 문자열로 들어 있는지와 가장 높은 word_similarity.
+func suggestScore(q string, names ...string) (matched bool, score float64) {
+	lq := strings.ToLower(q)
+	for _, n := range names {
+		if strings.Contains(strings.ToLower(n), lq) {
+			matched = true
+		}
+		score = max(score, wordSimilarity(q, n))
+	}
+	return matched, score
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// likeContains는 s를 그대로 포함하는 값을 찾는 LIKE 패턴. s의 %, _, \는 와일드카드가
+// 아니도록 이스케이프하므로 쿼리에 ESCAPE '\'를 붙여야 한다.
+func likeContains(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// pgTrgmTx는 pg_trgm 임계값(% 와 <% 연산자)을 이 트랜잭션에서만 suggest용으로 바꾼 읽기 전용
+// 트랜잭션을 연다.
+func (s *PgStore) pgTrgmTx(ctx context.Context) (pgx.Tx, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `
+SELECT set_config('pg_trgm.word_similarity_threshold', $1, true),
+       set_config('pg_trgm.similarity_threshold', $2, true)`,
+		strconv.FormatFloat(suggestMinWordSimilarity, 'f', -1, 64),
+		strconv.FormatFloat(suggestMinSimilarity, 'f', -1, 64)); err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Suggest는 idx_tags_name_trgm, idx_whatsnews_title_trgm 인덱스로 부분 일치하거나
+// 비슷한(<%) 태그와 제목을 찾는다.
+func (s *PgStore) Suggest(ctx context.Context, q string, limit int) (Suggestions, error) {
+	tx, err := s.pgTrgmTx(ctx)
+	if err != nil {
+		return Suggestions{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	like := likeContains(q)
+	res := Suggestions{}
+	rows, err := tx.Query(ctx, `
+SELECT t.id, t.name, COALESCE(t.namespace, ''), COALESCE(t.source_id, ''), COALESCE(s.news_cnt, 0)
+FROM   tags t
+LEFT   JOIN tag_stats s ON s.tag_id = t.id
+WHERE  t.name ILIKE $1 ESCAPE '\' OR $2 <% t.name
+ORDER  BY word_similarity($2, t.name) DESC, 5 DESC, t.name
+LIMIT  $3`, like, q, limit)
+	if err != nil {
+		return Suggestions{}, err
+	}
+	if res.Tags, err = pgx.CollectRows(rows, pgx.RowToStructByPos[Tag]); err != nil {
+		return Suggestions{}, err
+	}
+
+	rows, err = tx.Query(ctx, `
+SELECT s.code, s.name, s.aliases,
+       (SELECT COUNT(*)::int FROM whatsnews_services ws WHERE ws.service_code = s.code)
+FROM   services s,
+       LATERAL (SELECT MAX(word_similarity($2, n)) AS score, bool_or(n ILIKE $1 ESCAPE '\') AS matched
+                FROM   unnest(ARRAY[s.code::text, s.name::text] || s.aliases) n) m
+WHERE  m.matched OR m.score >= $4
+ORDER  BY m.score DESC, 4 DESC, s.name
+LIMIT  $3`, like, q, limit, suggestMinWordSimilarity)
+	if err != nil {
+		return Suggestions{}, err
+	}
+	if res.Services, err = pgx.CollectRows(rows, pgx.RowToStructByPos[Service]); err != nil {
+		return Suggestions{}, err
+	}
+
+	rows, err = tx.Query(ctx, `
+SELECT id, title
+FROM   whatsnews
+WHERE  title ILIKE $1 ESCAPE '\' OR $2 <% title
+ORDER  BY word_similarity($2, title) DESC, source_created_at DESC NULLS LAST, id DESC
+LIMIT  $3`, like, q, limit)
+	if err != nil {
+		return Suggestions{}, err
+	}
+	if res.Titles, err = pgx.CollectRows(rows, pgx.RowToStructByPos[TitleSuggestion]); err != nil {
+		return Suggestions{}, err
+	}
+	return res.orEmpty(), nil
+}
+
+// SimilarWords는 word와 비슷한(%) 단어를 태그 이름, 서비스 이름·별칭, 제목에서 모은다.
+func (s *PgStore) SimilarWords(ctx context.Context, word string) ([]string, error) {
+	tx, err := s.pgTrgmTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+WITH words AS (
+  SELECT regexp_split_to_table(lower(name), '[^[:alnum:]]+') AS w FROM tags WHERE $1 <% name
+  UNION ALL
+  SELECT regexp_split_to_table(lower(array_to_string(ARRAY[code::text, name::text] || aliases, ' ')), '[^[:alnum:]]+')
+  FROM   services
+  UNION ALL
+  SELECT regexp_split_to_table(lower(title), '[^[:alnum:]]+')
+  FROM   (SELECT title FROM whatsnews WHERE $1 <% title
+          ORDER  BY word_similarity($1, title) DESC LIMIT $2) t
+)
+SELECT DISTINCT w FROM words WHERE w % $1`, word, suggestTitleCandidates)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// Suggest는 pg_trgm이 없으므로 태그와 서비스는 전부 읽어 Go에서 word_similarity를 계산하고,
+// 제목은 q의 단어가 모두 들어 있는 것을 LIKE로 찾는다 (제목의 오타는 did_you_mean으로만
+// 잡는다). FTS5 인덱스는 porter로 어간을 잘라 두어 입력 중인 단어의 접두어 검색에 맞지 않는다.
+func (s *SQLiteStore) Suggest(ctx context.Context, q string, limit int) (Suggestions, error) {
+	res := Suggestions{}
+	tags, err := s.GetTags(ctx, TagsQuery{Limit: -1})
+	if err != nil {
+		return Suggestions{}, err
+	}
+	// GetTags와 GetServices는 뉴스가 많은 순이라 유사도가 같으면 그 순서를 따른다
+	res.Tags = rankSuggestions(q, tags.Items, limit, func(t Tag) []string { return []string{t.Name} })
+
+	services, err := s.GetServices(ctx)
+	if err != nil {
+		return Suggestions{}, err
+	}
+	res.Services = rankSuggestions(q, services, limit, func(svc Service) []string {
+		return append([]string{svc.Code, svc.Name}, svc.Aliases...)
+	})
+
+	if words := searchWords(q); len(words) > 0 {
+		var (
+			conds []string
+			args  []any
+		)
+		for _, w := range words {
+			conds = append(conds, `title LIKE ? ESCAPE '\'`)
+			args = append(args, likeContains(w))
+		}
+		rows, err := s.db.QueryContext(ctx, `
+SELECT id, title
+FROM   whatsnews
+WHERE  `+strings.Join(conds, " AND ")+`
+ORDER  BY source_created_at DESC, id DESC
+LIMIT  ?`, append(args, limit)...)
+		if err != nil {
+			return Suggestions{}, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var t TitleSuggestion
+			if err := rows.Scan(&t.Id, &t.Title); err != nil {
+				return Suggestions{}, err
+			}
+			res.Titles = append(res.Titles, t)
+		}
+		if err := rows.Err(); err != nil {
+			return Suggestions{}, err
+		}
+	}
+	return res.orEmpty(), nil
+}
+
+// SimilarWords는 태그 이름과 서비스 이름·별칭에서 word와 비슷한 단어를 모은다.
+func (s *SQLiteStore) SimilarWords(ctx context.Context, word string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT name FROM tags
+UNION
+SELECT code || ' ' || name FROM services
+UNION
+SELECT a.value FROM services, json_each(services.aliases) a`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := map[string]bool{}
+	var out []string
+	for rows.Next() {
+		var name sql.NullString
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		for _, w := range searchWords(name.String) {
+			if !seen[w] && titleSimilarity(word, w) >= suggestMinSimilarity {
+				out = append(out, w)
+			}
+			seen[w] = true
+		}
+	}
+	return out, rows.Err()
+}
+
+// rankSuggestions는 q가 이름에 들어 있거나 word_similarity가 suggestMinWordSimilarity 이상인
+// 항목을 유사도순(같으면 items 순)으로 limit개 고른다.
+func rankSuggestions[T any](q string, items []T, limit int, names func(T) []string) []T {
+	type scored struct {
+		item  T
+		score float64
+	}
+	var cands []scored
+	for _, it := range items {
+		if matched, score := suggestScore(q, names(it)...); matched || score >= suggestMinWordSimilarity {
+			cands = append(cands, scored{it, score})
+		}
+	}
+	sort.SliceStable(cands, func(i, j int) bool {
+		return cands[i].score > cands[j].score
+	})
+	out := []T{}
+	for i := 0; i < len(cands) && i < limit; i++ {
+		out = append(out, cands[i].item)
+	}
+	return out
+}
+
+// orEmpty는 JSON에서 null 대신 빈 배열이 나가게 한다.
+func (s Suggestions) orEmpty() Suggestions {
+	if s.Tags == nil {
+		s.Tags = []Tag{}
+	}
+	if s.Services == nil {
+		s.Services = []Service{}
+	}
+	if s.Titles == nil {
+		s.Titles = []TitleSuggestion{}
+	}
+	return s
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestWordSimilarity(t *testing.T) {
+	// pg_trgm 문서의 예: word_similarity('word', 'two words') = 0.8
+	if got := wordSimilarity("word", "two words"); math.Abs(got-0.8) > 1e-9 {
+		t.Errorf("wordSimilarity(word, two words) = %v, want 0.8", got)
+	}
+	if got := wordSimilarity("lamda", "AWS Lambda"); got < suggestMinWordSimilarity {
+		t.Errorf("wordSimilarity(lamda, AWS Lambda) = %v", got)
+	}
+	if got := wordSimilarity("lamda", "Amazon RDS"); got >= suggestMinWordSimilarity {
+		t.Errorf("wordSimilarity(lamda, Amazon RDS) = %v", got)
+	}
+}
+
+func TestFormatSearch(t *testing.T) {
+	for _, q := range []string{`lambda`, `"auto scaling" -preview`, `ec2 OR ecs`, `lamb*`, `"auto sca"*`} {
+		if got := formatSearch(parseSearch(q)); got != q {
+			t.Errorf("formatSearch(parseSearch(%q)) = %q", q, got)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	for _, it := range []AwsApiItem{
+		awsTestItem("a", "AWS Lambda adds Python 3.13 runtime", "2024-06-01T00:00:00Z", "AWS Lambda"),
+		awsTestItem("b", "AWS Lambda supports larger payloads", "2024-06-02T00:00:00Z", "AWS Lambda"),
+		awsTestItem("c", "Amazon RDS supports new engine", "2024-06-03T00:00:00Z", "Amazon RDS"),
+	} {
+		if err := s.InsertAwsItem(ctx, it); err != nil {
+			t.Fatalf("InsertAwsItem: %v", err)
+		}
+	}
+	if err := s.RebuildTagStats(ctx); err != nil {
+		t.Fatalf("RebuildTagStats: %v", err)
+	}
+
+	// 오타도 태그와 서비스를 찾는다. 제목은 SQLite에서 부분 일치로만 찾는다
+	res, err := GetSuggestions(ctx, s, "lamda", 5)
+	if err != nil {
+		t.Fatalf("GetSuggestions: %v", err)
+	}
+	if len(res.Tags) != 1 || res.Tags[0].Name != "AWS Lambda" || res.Tags[0].NewsCount != 2 {
+		t.Errorf("tags: %+v", res.Tags)
+	}
+	if len(res.Services) == 0 || res.Services[0].Code != "lambda" {
+		t.Errorf("services: %+v", res.Services)
+	}
+	if len(res.Titles) != 0 {
+		t.Errorf("titles: %+v", res.Titles)
+	}
+	if res.DidYouMean != "lambda" {
+		t.Errorf("did_you_mean: %q", res.DidYouMean)
+	}
+
+	res, err = GetSuggestions(ctx, s, "lambda pay", 5)
+	if err != nil {
+		t.Fatalf("GetSuggestions: %v", err)
+	}
+	if len(res.Titles) != 1 || res.Titles[0].Title != "AWS Lambda supports larger payloads" {
+		t.Errorf("titles: %+v", res.Titles)
+	}
+	if res.DidYouMean != "" {
+		t.Errorf("did_you_mean with results: %q", res.DidYouMean)
+	}
+
+	// 구문과 제외어는 그대로 두고, 고친 검색어로도 뉴스가 없으면 제안하지 않는다
+	for q, want := range map[string]string{
+		`"amazn rds" -lamda`: `"amazon rds" -lamda`,
+		"lamda rds":          "",
+		"zzzzqq":             "",
+	} {
+		res, err := GetSuggestions(ctx, s, q, 5)
+		if err != nil {
+			t.Fatalf("GetSuggestions(%q): %v", q, err)
+		}
+		if res.DidYouMean != want {
+			t.Errorf("did_you_mean for %q: got %q, want %q", q, res.DidYouMean, want)
+		}
+	}
+
+	// LIKE 와일드카드는 글자 그대로 찾는다
+	for _, q := range []string{"%", "_", `\`} {
+		res, err := GetSuggestions(ctx, s, q, 5)
+		if err != nil {
+			t.Fatalf("GetSuggestions(%q): %v", q, err)
+		}
+		if len(res.Tags)+len(res.Services)+len(res.Titles) != 0 {
+			t.Errorf("suggestions for %q: %+v", q, res)
+		}
+	}
+	if got, want := likeContains(`50%_off\`), `%50\%\_off\\%`; got != want {
+		t.Errorf("likeContains: got %q, want %q", got, want)
+	}
+}
@@ -2,12 +2,31 @@ package internal
 
 import (
 	"context"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type Tag struct {
 	Id        int    `json:"id"`
 	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"` // 예: general-products, marketing-marchitecture, year
+	SourceId  string `json:"source_id,omitempty"` // AWS 태그 id (whats-new-v2#general-products#amazon-ec2)
+	NewsCount int    `json:"news_count"`
+}
+
+// TagsQuery는 GetTags의 검색 조건.
+type TagsQuery struct {
+	Limit     int
+	Offset    int
+	Name      string // 부분 일치
+	Namespace string // 정확히 일치, 빈 값이면 전체
+}
+
+// TagNamespace는 네임스페이스별 태그/뉴스 건수. 네임스페이스가 없는 옛 태그는 Name이 빈 값.
+type TagNamespace struct {
+	Name      string `json:"name"`
+	TagCount  int    `json:"tag_count"`
 	NewsCount int    `json:"news_count"`
 }
 
@@ -20,27 +39,29 @@ type TagsResult struct {
 	TotalPage int   `json:"total_page"`
 }
 
-func GetTags(ctx context.Context, pool *pgxpool.Pool,
-	limit, offset int, nameFilter string) (TagsResult, error) {
-
+func (s *PgStore) GetTags(ctx context.Context, tq TagsQuery) (TagsResult, error) {
 	const q = `
 SELECT t.id,
        t.name,
+       COALESCE(t.namespace, '')      AS namespace,
+       COALESCE(t.source_id, '')      AS source_id,
        COALESCE(s.news_cnt, 0)        AS news_cnt,
        COUNT(*) OVER()                AS total_rows
 FROM   tags        AS t
 LEFT   JOIN tag_stats AS s ON s.tag_id = t.id
 WHERE  ($1 = '' OR t.name ILIKE $1)
+AND    ($4 = '' OR t.namespace = $4)
 ORDER  BY news_cnt DESC, t.name
 LIMIT  $2 OFFSET $3;
 `
 
 	like := "%"
-	if nameFilter != "" {
-		like = "%" + nameFilter + "%"
+	if tq.Name != "" {
+		like = "%" + tq.Name + "%"
 	}
+	limit, offset := tq.Limit, tq.Offset
 
-	rows, err := pool.Query(ctx, q, like, limit, offset)
+	rows, err := s.pool.Query(ctx, q, like, limit, offset, tq.Namespace)
 	if err != nil {
 		return TagsResult{}, err
 	}
@@ -57,7 +78,7 @@ LIMIT  $2 OFFSET $3;
 			t         Tag
 			totalRows int
 		)
-		if err := rows.Scan(&t.Id, &t.Name, &t.NewsCount, &totalRows); err != nil {
+		if err := rows.Scan(&t.Id, &t.Name, &t.Namespace, &t.SourceId, &t.NewsCount, &totalRows); err != nil {
 			return TagsResult{}, err
 		}
 		if res.Total == 0 { // 첫 행에서 totalRows 확보
@@ -75,3 +96,98 @@ LIMIT  $2 OFFSET $3;
 
 	return res, rows.Err()
 }
+
+func (s *PgStore) GetTagNamespaces(ctx context.Context) ([]TagNamespace, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT COALESCE(t.namespace, '')       AS namespace,
+       COUNT(*)                        AS tag_cnt,
+       COALESCE(SUM(s.news_cnt), 0)    AS news_cnt
+FROM   tags        AS t
+LEFT   JOIN tag_stats AS s ON s.tag_id = t.id
+GROUP  BY 1
+ORDER  BY 1;
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TagNamespace
+	for rows.Next() {
+		var ns TagNamespace
+		if err := rows.Scan(&ns.Name, &ns.TagCount, &ns.NewsCount); err != nil {
+			return nil, err
+		}
+		out = append(out, ns)
+	}
+	return out, rows.Err()
+}
+
+// upsertTag는 AWS 태그를 tags에 넣고 id를 돌려준다.
+// AWS 태그 id가 있으면 그것으로 식별하고, id 없이 이름만으로 들어온 옛 행은 이 때 id와 네임스페이스를 채운다.
+func upsertTag(ctx context.Context, tx pgx.Tx, tag AwsTag) (int, error) {
+	var tagID int
+	if tag.Id == "" {
+		err := tx.QueryRow(ctx, "SELECT id FROM tags WHERE name=$1 ORDER BY id LIMIT 1", tag.Name).Scan(&tagID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = tx.QueryRow(ctx,
+				"INSERT INTO tags(name, created_at) VALUES($1, NOW()) RETURNING id", tag.Name).Scan(&tagID)
+		}
+		return tagID, err
+	}
+
+	namespace := awsTagNamespace(tag)
+	err := tx.QueryRow(ctx,
+		`UPDATE tags SET source_id = $1, namespace = $2
+         WHERE  id = (SELECT id FROM tags WHERE name = $3 AND source_id IS NULL ORDER BY id LIMIT 1)
+         RETURNING id`, tag.Id, namespace, tag.Name).Scan(&tagID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = tx.QueryRow(ctx,
+			`INSERT INTO tags(name, source_id, namespace, created_at) VALUES($1, $2, $3, NOW())
+             ON CONFLICT (source_id) DO UPDATE SET name = EXCLUDED.name, namespace = EXCLUDED.namespace
+             RETURNING id`, tag.Name, tag.Id, namespace).Scan(&tagID)
+	}
+	return tagID, err
+}
+
+// incrementTagStats는 whatsnews_tags에 새 연결이 추가된 트랜잭션 안에서 호출해
+// tag_stats.news_cnt를 1 증가시킨다.
+func incrementTagStats(ctx context.Context, tx pgx.Tx, tagID int) error {
+	_, err := tx.Exec(ctx,
+		`INSERT INTO tag_stats(tag_id, news_cnt) VALUES($1, 1)
+         ON CONFLICT (tag_id) DO UPDATE SET news_cnt = tag_stats.news_cnt + 1`, tagID)
+	return err
+}
+
+// RebuildTagStats는 whatsnews_tags 기준으로 tag_stats를 다시 계산한다.
+// 카운터를 거치지 않고 적재된 데이터가 있을 때 정합성을 맞추는 용도.
+func (s *PgStore) RebuildTagStats(ctx context.Context) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `LOCK TABLE tag_stats IN EXCLUSIVE MODE`); err != nil {
+		return err
+	}
+	// 값이 바뀐 행만 쓴다. 행이 바뀌면 data_version이 올라가 응답 캐시가 비워지므로,
+	// 스케줄러가 시작할 때마다 하는 재계산이 이미 맞는 통계로 캐시를 무효화하지 않게 한다.
+	if _, err := tx.Exec(ctx, `
+DELETE FROM tag_stats s
+WHERE  NOT EXISTS (SELECT 1 FROM whatsnews_tags wnt WHERE wnt.tag_id = s.tag_id);
+`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+INSERT INTO tag_stats(tag_id, news_cnt)
+SELECT wnt.tag_id, COUNT(*)
+FROM   whatsnews_tags wnt
+GROUP  BY wnt.tag_id
+ON CONFLICT (tag_id) DO UPDATE SET news_cnt = EXCLUDED.news_cnt
+WHERE  tag_stats.news_cnt <> EXCLUDED.news_cnt;
+`); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
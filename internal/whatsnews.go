@@ -3,11 +3,10 @@ package internal
 import (
 	"context"
 	"encoding/json"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type WhatsNews struct {
@@ -16,163 +15,436 @@ type WhatsNews struct {
 	Content         string     `json:"content"`
 	SourceUrl       string     `json:"source_url"`
 	SourceCreatedAt *time.Time `json:"source_created_at"`
+	IngestedAt      *time.Time `json:"ingested_at"` // 이 서비스에 처음 저장된 시각 (since 기준)
+	UpdatedAt       *time.Time `json:"updated_at"`
 	Tags            []Tag      `json:"tags"`
+	Regions         []string   `json:"regions"`  // 언급된 리전 코드
+	Services        []string   `json:"services"` // 언급된 서비스 코드
+	// Sources는 이 레코드로 합쳐진 출처 목록. 첫 항목이 레코드 자체의 출처다.
+	Sources []NewsSource `json:"sources"`
+	// Relevant는 인벤토리에 있는 서비스와 연결된 발표인지
+	Relevant bool `json:"relevant"`
+
+	// 검색어가 있을 때만 채워진다
+	Rank     float64 `json:"rank,omitempty"`
+	Headline string  `json:"headline,omitempty"` // 검색어를 <mark>로 감싼 제목
+	Snippet  string  `json:"snippet,omitempty"`  // 검색어를 <mark>로 감싼 본문 발췌(평문)
 }
 
-type WhatsNewsResult struct {
-	Items     []WhatsNews `json:"items"`
-	Total     int         `json:"total"`
-	Limit     int         `json:"limit"`
-	Offset    int         `json:"offset"`
-	Page      int         `json:"page"`
-	TotalPage int         `json:"total_page"`
-}
-
-func GetWhatsnews(
-	ctx context.Context,
-	pool *pgxpool.Pool,
-	limit, offset int,
-	tagIDs []int,
-	search string,
-) (WhatsNewsResult, error) {
-
-	if limit <= 0 {
-		limit = 30
-	}
-	if offset < 0 {
-		offset = 0
-	}
-
-	var (
-		conds   []string
-		args    []any
-		paramNo = 1
-	)
-
-	// 검색어
-	if strings.TrimSpace(search) != "" {
-		conds = append(
-			conds,
-			"(wn.title ILIKE $"+strconv.Itoa(paramNo)+" OR wn.content ILIKE $"+strconv.Itoa(paramNo)+")",
-		)
-		args = append(args, "%"+search+"%")
-		paramNo++
+// setSources는 레코드 자체의 출처와 병합된 출처(JSON 배열)로 Sources를 채운다.
+func (it *WhatsNews) setSources(sourceType, sourceID string, merged []byte) error {
+	it.Sources = []NewsSource{primaryNewsSource(sourceType, sourceID, it.SourceUrl)}
+	var rest []NewsSource
+	if err := json.Unmarshal(merged, &rest); err != nil {
+		return err
 	}
+	it.Sources = append(it.Sources, rest...)
+	return nil
+}
+
+// WhatsNewsResult는 목록 한 페이지. Total/TotalPage는 WhatsNewsQuery.SkipTotal이면 null.
+// NextCursor는 newest 정렬에서 다음 페이지가 있을 때 다음 요청의 cursor로 넘길 값.
+type WhatsNewsResult struct {
+	Items      []WhatsNews `json:"items"`
+	Total      *int        `json:"total"`
+	Limit      int         `json:"limit"`
+	Offset     int         `json:"offset"`
+	Page       int         `json:"page"`
+	TotalPage  *int        `json:"total_page"`
+	HasMore    bool        `json:"has_more"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
 
-	// 태그 배열
-	hasTags := len(tagIDs) > 0
-	tagParamNo := 0
-	if hasTags {
-		tagParamNo = paramNo
-		args = append(args, tagIDs) // $tagParamNo
-		paramNo++
+// 정렬 기준
+const (
+	SortNewest    = "newest"
+	SortOldest    = "oldest"
+	SortUpdated   = "updated"   // 최근에 갱신된 순 (updated_at)
+	SortRelevance = "relevance" // 검색어가 없으면 SortNewest와 같다
+	// sortID는 id 오름차순. API에서는 받지 않고 스트림 재전송에만 쓴다
+	sortID = "id"
+)
+
+// 태그 매칭 방식
+const (
+	TagModeAll = "all"
+	TagModeAny = "any"
+)
+
+// validSort는 API에서 받을 수 있는 정렬 기준인지.
+func validSort(s string) bool {
+	switch s {
+	case SortNewest, SortOldest, SortUpdated, SortRelevance:
+		return true
 	}
+	return false
+}
+
+// WhatsNewsQuery는 GetWhatsnews의 검색 조건.
+type WhatsNewsQuery struct {
+	Limit  int
+	Offset int
+	IDs    []int  // 지정하면 이 id의 뉴스만
+	TagIDs []int  // TagMode에 따라 모두(all) 또는 하나라도(any) 가진 뉴스만
+	Search string // 문법은 search.go 참고
+	Sort   string
+
+	TagMode string
+	// ExcludeTagIDs 중 하나라도 가진 뉴스는 뺀다
+	ExcludeTagIDs []int
+
+	// From 이후(포함), To 이전(제외)에 발표된 뉴스만 (source_created_at)
+	From, To *time.Time
+	// Since 이후(포함)에 저장된 뉴스만 (created_at). 폴링하는 클라이언트용
+	Since *time.Time
+	// SinceID보다 큰 id의 뉴스만. 스트림 재전송(Last-Event-ID)용
+	SinceID int
 
-	buildWhere := func(extra string) string {
-		all := make([]string, len(conds))
-		copy(all, conds)
-		if extra != "" {
-			all = append(all, extra)
+	// After가 있으면 Offset 대신 이 위치 다음부터 읽는다 (newest 정렬만)
+	After *WhatsNewsCursor
+	// SkipTotal이면 전체 건수를 세지 않는다
+	SkipTotal bool
+
+	// Regions는 리전 코드 목록. 하나라도 언급한 뉴스만
+	Regions []string
+	// Services는 서비스 코드 목록. 하나라도 언급한 뉴스만
+	Services []string
+	// SourceTypes는 출처 종류(SourceAwsApi 등) 목록. 레코드 자체나 병합된 출처 중
+	// 하나라도 해당하는 뉴스만
+	SourceTypes []string
+	// RelevantOnly이면 인벤토리의 서비스와 연결된 뉴스만
+	RelevantOnly bool
+
+	// Facets는 네임스페이스 -> 태그 이름 목록. 같은 네임스페이스 안에서는 OR,
+	// 네임스페이스끼리는 AND로 묶는다. 이름은 대소문자를 구분하지 않는다.
+	Facets map[string][]string
+}
+
+// facetNamespaces는 쿼리 문자열을 결정적으로 만들기 위해 정렬된 네임스페이스 목록을 돌려준다.
+func (q *WhatsNewsQuery) facetNamespaces() []string {
+	var out []string
+	for ns, names := range q.Facets {
+		if len(names) > 0 {
+			out = append(out, ns)
 		}
-		if len(all) == 0 {
-			return ""
+	}
+	sort.Strings(out)
+	return out
+}
+
+func lowerAll(in []string) []string {
+	out := make([]string, len(in))
+	for i, s := range in {
+		out[i] = strings.ToLower(s)
+	}
+	return out
+}
+
+// normalize는 기본값을 채우고 적용 불가능한 정렬 기준을 기본값으로 되돌린다.
+func (q *WhatsNewsQuery) normalize() {
+	if q.Limit <= 0 {
+		q.Limit = 30
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	if q.Sort == SortRelevance && strings.TrimSpace(q.Search) == "" {
+		q.Sort = SortNewest
+	}
+	if q.Sort == "" {
+		q.Sort = SortNewest
+	}
+	if q.TagMode != TagModeAny {
+		q.TagMode = TagModeAll
+	}
+	if q.After != nil {
+		q.Offset = 0
+	}
+	for _, t := range []**time.Time{&q.From, &q.To, &q.Since} {
+		*t = utcTime(*t)
+	}
+}
+
+// orderBy는 정렬 기준의 ORDER BY 목록 (whatsnews 컬럼 이름, 별칭 없음).
+// relevance의 검색 점수(rank)는 저장소마다 컬럼이 달라 호출하는 쪽에서 앞에 붙인다.
+func (q *WhatsNewsQuery) orderBy() string {
+	switch q.Sort {
+	case SortOldest:
+		return "source_created_at, id"
+	case SortUpdated:
+		return "updated_at DESC, id"
+	case sortID:
+		return "id"
+	}
+	return "source_created_at DESC, id"
+}
+
+// validate는 normalize 뒤에 호출한다.
+func (q *WhatsNewsQuery) validate() error {
+	if q.After != nil && q.Sort != SortNewest {
+		return ErrCursorSort
+	}
+	return nil
+}
+
+// newWhatsNewsResult는 q.Limit+1개까지 읽은 items로 결과를 만든다. 넘친 한 개는 다음 페이지가
+// 있다는 표시로만 쓴다.
+func newWhatsNewsResult(items []WhatsNews, total *int, q WhatsNewsQuery) WhatsNewsResult {
+	res := WhatsNewsResult{
+		Items:  items,
+		Total:  total,
+		Limit:  q.Limit,
+		Offset: q.Offset,
+		Page:   q.Offset/q.Limit + 1,
+	}
+	if len(items) > q.Limit {
+		res.Items = items[:q.Limit]
+		res.HasMore = true
+		if q.Sort == SortNewest {
+			res.NextCursor = cursorAfter(res.Items[q.Limit-1]).Encode()
 		}
-		return "WHERE " + strings.Join(all, " AND ")
 	}
+	if total != nil {
+		totalPage := max((*total+q.Limit-1)/q.Limit, 1)
+		res.TotalPage = &totalPage
+	}
+	return res
+}
 
-	var (
-		countSQL string
-		dataSQL  string
-	)
+// headlineOptions는 ts_headline 옵션. 본문은 마크업을 걷어낸 평문에서 발췌한다.
+const headlineOptions = `StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" … "`
 
-	limitParam := paramNo
-	offsetParam := paramNo + 1
-	args = append(args, limit, offset)
+// pgFilter는 WhatsNewsQuery의 필터 조건을 SQL로 옮긴 것. 목록(GetWhatsnews)과
+// 통계(stats.go)가 같은 조건을 쓴다. 조건은 whatsnews를 wn으로 참조한다.
+type pgFilter struct {
+	ctes      []string
+	conds     []string
+	args      []any
+	hasSearch bool // 검색어가 있으면 from()에 q(tsquery)가 붙는다
+}
 
-	if hasTags {
-		candidateCond := "wn.id IN (SELECT whatsnew_id FROM candidates)"
+func (f *pgFilter) arg(v any) string {
+	f.args = append(f.args, v)
+	return "$" + strconv.Itoa(len(f.args))
+}
 
-		countSQL = `
-WITH wanted AS (
-  SELECT unnest($` + strconv.Itoa(tagParamNo) + `::int[]) AS tag_id
+// with는 WITH 절. CTE가 없으면 빈 문자열.
+func (f *pgFilter) with() string {
+	if len(f.ctes) == 0 {
+		return ""
+	}
+	return "WITH " + strings.Join(f.ctes, ", ")
+}
+
+func (f *pgFilter) from() string {
+	if f.hasSearch {
+		return "whatsnews wn CROSS JOIN q"
+	}
+	return "whatsnews wn"
+}
+
+// where는 WHERE 절. 조건이 없으면 빈 문자열.
+func (f *pgFilter) where() string {
+	if len(f.conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(f.conds, " AND ")
+}
+
+// newPgFilter는 normalize된 q로 조건을 만든다. 커서(After)와 정렬·페이지는 다루지 않는다.
+func newPgFilter(q WhatsNewsQuery) *pgFilter {
+	f := &pgFilter{}
+	arg := f.arg
+
+	// 검색어: search_vector(제목 A, 본문 B 가중치)에 대한 전문 검색
+	if tsq := buildTSQuery(q.Search); tsq != "" {
+		f.hasSearch = true
+		f.ctes = append(f.ctes, `q AS (
+  SELECT to_tsquery('english', `+arg(tsq)+`) AS query
+)`)
+		f.conds = append(f.conds, "wn.search_vector @@ q.query")
+	}
+
+	// 태그 배열. any/exclude는 whatsnews_tags 인덱스를 타는 semi/anti join으로,
+	// all은 (tag_id, whatsnew_id) 인덱스에서 모은 뒤 개수로 거른다
+	switch {
+	case len(q.TagIDs) > 0 && q.TagMode == TagModeAny:
+		f.conds = append(f.conds, `wn.id IN (
+    SELECT whatsnew_id FROM whatsnews_tags
+    WHERE  tag_id = ANY(`+arg(q.TagIDs)+`::int[]))`)
+	case len(q.TagIDs) > 0:
+		f.ctes = append(f.ctes, `wanted AS (
+  SELECT DISTINCT unnest(`+arg(q.TagIDs)+`::int[]) AS tag_id
 ), candidates AS (
   SELECT wnt.whatsnew_id
   FROM   whatsnews_tags wnt
   JOIN   wanted w ON w.tag_id = wnt.tag_id
   GROUP  BY wnt.whatsnew_id
   HAVING COUNT(DISTINCT w.tag_id) = (SELECT COUNT(*) FROM wanted)
-)
+)`)
+		f.conds = append(f.conds, "wn.id IN (SELECT whatsnew_id FROM candidates)")
+	}
+	if len(q.ExcludeTagIDs) > 0 {
+		f.conds = append(f.conds, `NOT EXISTS (
+    SELECT 1 FROM whatsnews_tags xt
+    WHERE  xt.whatsnew_id = wn.id AND xt.tag_id = ANY(`+arg(q.ExcludeTagIDs)+`::int[]))`)
+	}
+
+	if len(q.IDs) > 0 {
+		f.conds = append(f.conds, "wn.id = ANY("+arg(q.IDs)+"::int[])")
+	}
+
+	// 네임스페이스별 패싯
+	for _, ns := range q.facetNamespaces() {
+		f.conds = append(f.conds, `EXISTS (
+    SELECT 1
+    FROM   whatsnews_tags ft
+    JOIN   tags t ON t.id = ft.tag_id
+    WHERE  ft.whatsnew_id = wn.id
+    AND    t.namespace = `+arg(ns)+`
+    AND    lower(t.name) = ANY(`+arg(lowerAll(q.Facets[ns]))+`::text[]))`)
+	}
+
+	if len(q.Regions) > 0 {
+		f.conds = append(f.conds, `EXISTS (
+    SELECT 1 FROM whatsnews_regions wr
+    WHERE  wr.whatsnew_id = wn.id AND wr.region_code = ANY(`+arg(q.Regions)+`::text[]))`)
+	}
+
+	if len(q.Services) > 0 {
+		f.conds = append(f.conds, `EXISTS (
+    SELECT 1 FROM whatsnews_services ws
+    WHERE  ws.whatsnew_id = wn.id AND ws.service_code = ANY(`+arg(q.Services)+`::text[]))`)
+	}
+
+	if len(q.SourceTypes) > 0 {
+		types := arg(q.SourceTypes)
+		f.conds = append(f.conds, `(wn.source_type = ANY(`+types+`::text[]) OR EXISTS (
+    SELECT 1 FROM whatsnews_merges sm
+    WHERE  sm.whatsnew_id = wn.id AND sm.undone_at IS NULL AND sm.source_type = ANY(`+types+`::text[])))`)
+	}
+
+	if q.RelevantOnly {
+		f.conds = append(f.conds, relevantExpr)
+	}
+
+	if q.From != nil {
+		f.conds = append(f.conds, "wn.source_created_at >= "+arg(*q.From))
+	}
+	if q.To != nil {
+		f.conds = append(f.conds, "wn.source_created_at < "+arg(*q.To))
+	}
+	if q.Since != nil {
+		f.conds = append(f.conds, "wn.created_at >= "+arg(*q.Since))
+	}
+	if q.SinceID > 0 {
+		f.conds = append(f.conds, "wn.id > "+arg(q.SinceID))
+	}
+	return f
+}
+
+func (s *PgStore) GetWhatsnews(ctx context.Context, q WhatsNewsQuery) (WhatsNewsResult, error) {
+	q.normalize()
+	if err := q.validate(); err != nil {
+		return WhatsNewsResult{}, err
+	}
+
+	f := newPgFilter(q)
+	arg, hasSearch := f.arg, f.hasSearch
+	with, from, where := f.with(), f.from(), f.where()
+
+	var total *int
+	if !q.SkipTotal {
+		countSQL := with + `
 SELECT COUNT(*)
-FROM   whatsnews wn
-` + buildWhere(candidateCond) + `;
+FROM   ` + from + `
+` + where + `;
 `
+		total = new(int)
+		if err := s.pool.QueryRow(ctx, countSQL, f.args...).Scan(total); err != nil {
+			return WhatsNewsResult{}, err
+		}
+	}
 
-		dataSQL = `
-WITH wanted AS (
-  SELECT unnest($` + strconv.Itoa(tagParamNo) + `::int[]) AS tag_id
-), candidates AS (
-  SELECT wnt.whatsnew_id
-  FROM   whatsnews_tags wnt
-  JOIN   wanted w ON w.tag_id = wnt.tag_id
-  GROUP  BY wnt.whatsnew_id
-  HAVING COUNT(DISTINCT w.tag_id) = (SELECT COUNT(*) FROM wanted)
-), filtered AS (
-  SELECT  wn.id, wn.title, wn.content, wn.source_url, wn.source_created_at
-  FROM    whatsnews wn
-  ` + buildWhere(candidateCond) + `
-  ORDER BY wn.source_created_at DESC, wn.id
-  LIMIT   $` + strconv.Itoa(limitParam) + ` OFFSET $` + strconv.Itoa(offsetParam) + `
+	// 커서 조건은 전체 건수에 영향을 주지 않도록 목록 쿼리에만 붙인다.
+	// PostgreSQL의 DESC 정렬은 NULL이 먼저 온다
+	if c := q.After; c != nil {
+		cond := "(wn.source_created_at IS NOT NULL OR wn.id > " + arg(c.Id) + ")"
+		if c.SourceCreatedAt != nil {
+			t := arg(*c.SourceCreatedAt)
+			cond = "(wn.source_created_at < " + t + " OR (wn.source_created_at = " + t + " AND wn.id > " + arg(c.Id) + "))"
+		}
+		if where == "" {
+			where = "WHERE " + cond
+		} else {
+			where += " AND " + cond
+		}
+	}
+
+	rankExpr := "0::float8"
+	if hasSearch {
+		rankExpr = "ts_rank_cd(wn.search_vector, q.query)::float8"
+	}
+	order := q.orderBy()
+	if q.Sort == SortRelevance {
+		order = "rank DESC, " + order
+	}
+	headline := "''"
+	snippet := "''"
+	outerFrom := "filtered f"
+	if hasSearch {
+		headline = "ts_headline('english', f.title, q.query, '" + headlineOptions + "')"
+		snippet = `ts_headline('english', regexp_replace(COALESCE(f.content, ''), '<[^>]*>', ' ', 'g'), q.query, '` + headlineOptions + `')`
+		outerFrom += " CROSS JOIN q"
+	}
+
+	limitArg := arg(q.Limit + 1)
+	offsetArg := arg(q.Offset)
+	dataSQL := with
+	if dataSQL == "" {
+		dataSQL = "WITH "
+	} else {
+		dataSQL += ", "
+	}
+	dataSQL += `filtered AS (
+  SELECT  wn.id, wn.title, wn.content, wn.source_url, wn.source_created_at,
+          wn.created_at, wn.updated_at, wn.source_type, wn.source_id, ` + relevantExpr + ` AS relevant,
+          ` + rankExpr + ` AS rank
+  FROM    ` + from + `
+  ` + where + `
+  ORDER BY ` + order + `
+  LIMIT   ` + limitArg + ` OFFSET ` + offsetArg + `
 )
 SELECT f.id, f.title, f.content, f.source_url, f.source_created_at,
-       COALESCE(t.tags,'[]') AS tags
-FROM   filtered f
+       f.created_at, f.updated_at, f.rank, ` + headline + ` AS headline, ` + snippet + ` AS snippet,
+       COALESCE(t.tags,'[]') AS tags,
+       f.source_type, f.source_id, COALESCE(m.sources,'[]') AS sources, f.relevant,
+       ARRAY(SELECT region_code FROM whatsnews_regions
+             WHERE  whatsnew_id = f.id ORDER BY region_code) AS regions,
+       ARRAY(SELECT service_code FROM whatsnews_services
+             WHERE  whatsnew_id = f.id ORDER BY service_code) AS services
+FROM   ` + outerFrom + `
 LEFT JOIN LATERAL (
   SELECT json_agg(
-           jsonb_build_object('id',t.id,'name',t.name)
+           jsonb_build_object('id',t.id,'name',t.name,'namespace',t.namespace)
            ORDER BY t.name
          ) AS tags
   FROM   whatsnews_tags wnt2
   JOIN   tags t ON t.id = wnt2.tag_id
   WHERE  wnt2.whatsnew_id = f.id
 ) t ON TRUE
-ORDER BY f.source_created_at DESC, f.id;
-`
-	} else { // 태그 선택이 없을 때
-		countSQL = `
-SELECT COUNT(*) FROM whatsnews wn
-` + buildWhere("") + `;
-`
-
-		dataSQL = `
-WITH filtered AS (
-  SELECT  wn.id, wn.title, wn.content, wn.source_url, wn.source_created_at
-  FROM    whatsnews wn
-  ` + buildWhere("") + `
-  ORDER BY wn.source_created_at DESC, wn.id
-  LIMIT   $` + strconv.Itoa(limitParam) + ` OFFSET $` + strconv.Itoa(offsetParam) + `
-)
-SELECT f.id, f.title, f.content, f.source_url, f.source_created_at,
-       COALESCE(t.tags,'[]') AS tags
-FROM   filtered f
 LEFT JOIN LATERAL (
   SELECT json_agg(
-           jsonb_build_object('id',t.id,'name',t.name)
-           ORDER BY t.name
-         ) AS tags
-  FROM   whatsnews_tags wnt2
-  JOIN   tags t ON t.id = wnt2.tag_id
-  WHERE  wnt2.whatsnew_id = f.id
-) t ON TRUE
-ORDER BY f.source_created_at DESC, f.id;
+           jsonb_build_object('type',m.source_type,'source_id',m.source_id,'url',m.url)
+           ORDER BY m.id
+         ) AS sources
+  FROM   whatsnews_merges m
+  WHERE  m.whatsnew_id = f.id AND m.undone_at IS NULL
+) m ON TRUE
+ORDER BY ` + prefixColumns("f.", order) + `;
 `
-	}
 
-	var total int
-	if err := pool.QueryRow(ctx, countSQL, args[:limitParam-1]...).Scan(&total); err != nil {
-		return WhatsNewsResult{}, err
-	}
-
-	rows, err := pool.Query(ctx, dataSQL, args...)
+	rows, err := s.pool.Query(ctx, dataSQL, f.args...)
 	if err != nil {
 		return WhatsNewsResult{}, err
 	}
@@ -180,32 +452,35 @@ ORDER BY f.source_created_at DESC, f.id;
 
 	var items []WhatsNews
 	for rows.Next() {
-		var it WhatsNews
-		var tagsJSON []byte
-		if err := rows.Scan(&it.Id, &it.Title, &it.Content, &it.SourceUrl, &it.SourceCreatedAt, &tagsJSON); err != nil {
+		var (
+			it                   WhatsNews
+			tagsJSON, sourceJSON []byte
+			sourceType, sourceID string
+		)
+		if err := rows.Scan(&it.Id, &it.Title, &it.Content, &it.SourceUrl, &it.SourceCreatedAt,
+			&it.IngestedAt, &it.UpdatedAt, &it.Rank, &it.Headline, &it.Snippet, &tagsJSON, &sourceType, &sourceID, &sourceJSON, &it.Relevant, &it.Regions, &it.Services); err != nil {
 			return WhatsNewsResult{}, err
 		}
 		if err := json.Unmarshal(tagsJSON, &it.Tags); err != nil {
 			return WhatsNewsResult{}, err
 		}
+		if err := it.setSources(sourceType, sourceID, sourceJSON); err != nil {
+			return WhatsNewsResult{}, err
+		}
 		items = append(items, it)
 	}
 	if err := rows.Err(); err != nil {
 		return WhatsNewsResult{}, err
 	}
 
-	page := offset/limit + 1
-	totalPage := (total + limit - 1) / limit
-	if totalPage == 0 {
-		totalPage = 1
-	}
+	return newWhatsNewsResult(items, total, q), nil
+}
 
-	return WhatsNewsResult{
-		Items:     items,
-		Total:     total,
-		Limit:     limit,
-		Offset:    offset,
-		Page:      page,
-		TotalPage: totalPage,
-	}, nil
+// prefixColumns는 "a DESC, b" 형태의 ORDER BY 목록 각 항목 앞에 테이블 별칭을 붙인다.
+func prefixColumns(alias, order string) string {
+	parts := strings.Split(order, ", ")
+	for i, p := range parts {
+		parts[i] = alias + p
+	}
+	return strings.Join(parts, ", ")
 }
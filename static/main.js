@@ -14,9 +14,11 @@ let currentPage = 1,
   tagListData = [],
   newsSearchKeyword = "",
   cardItems = [],
+  nextCursor = "",
   totalCount = 0,
   isLoading = false,
-  isEndOfList = false;
+  isEndOfList = false,
+  suggestTimer = null;
 
 /* ========= 사이드바 모바일 ========= */
 function openSidebar() {
@@ -159,6 +161,7 @@ document.addEventListener("DOMContentLoaded", () => {
   document.getElementById("sidebarOverlay").onclick = closeSidebar;
 
   document.getElementById("searchBtn").onclick = () => {
+    hideSuggest();
     newsSearchKeyword = document.getElementById("searchInput").value.trim();
     resetAndLoadNews();
   };
@@ -181,10 +184,17 @@ document.addEventListener("DOMContentLoaded", () => {
   };
   document.getElementById("searchInput").addEventListener("keyup", (e) => {
     if (e.key === "Enter") {
+      hideSuggest();
       newsSearchKeyword = e.target.value.trim();
       resetAndLoadNews();
+    } else if (e.key === "Escape") {
+      hideSuggest();
     }
   });
+  document
+    .getElementById("searchInput")
+    .addEventListener("input", onSearchInput);
+  document.getElementById("searchInput").addEventListener("blur", hideSuggest);
   document.getElementById("tagSearch").addEventListener("keyup", (e) => {
     if (e.key === "Enter") {
       tagSearchKeyword = e.target.value.trim();
@@ -298,14 +308,113 @@ function renderSelectedTags() {
   });
 }
 
+/* ========= 검색어 자동 완성 ========= */
+function onSearchInput(e) {
+  clearTimeout(suggestTimer);
+  const q = e.target.value.trim();
+  if (!q) {
+    hideSuggest();
+    return;
+  }
+  suggestTimer = setTimeout(() => loadSuggest(q), 200);
+}
+function loadSuggest(q) {
+  fetch(`/api/suggest?q=${encodeURIComponent(q)}`)
+    .then((r) => (r.ok ? r.json() : null))
+    .then((data) => {
+      /* 응답이 오는 사이 입력이 바뀌었으면 버린다 */
+      if (!data || document.getElementById("searchInput").value.trim() !== q)
+        return;
+      renderSuggest(data);
+    });
+}
+function renderSuggest(data) {
+  const box = document.getElementById("suggestBox");
+  box.innerHTML = "";
+  const section = (label, items, render) => {
+    if (!items.length) return;
+    const head = document.createElement("div");
+    head.className = "px-3 pt-2 pb-1 text-xs font-semibold text-gray-400";
+    head.textContent = label;
+    box.appendChild(head);
+    items.forEach((it) => {
+      const [text, onPick] = render(it);
+      const row = document.createElement("button");
+      row.type = "button";
+      row.className =
+        "block w-full truncate text-left px-3 py-1.5 hover:bg-blue-50";
+      row.textContent = text;
+      /* 입력창 blur보다 먼저 처리되도록 mousedown */
+      row.onmousedown = (e) => {
+        e.preventDefault();
+        hideSuggest();
+        onPick();
+      };
+      box.appendChild(row);
+    });
+  };
+  if (data.did_you_mean)
+    section("혹시 이것을 찾으셨나요?", [data.did_you_mean], (q) => [
+      q,
+      () => runSearch(q),
+    ]);
+  section("태그", data.tags, (t) => [
+    `${t.name} (${t.news_count})`,
+    () => addSelectedTag(t),
+  ]);
+  section("서비스", data.services, (s) => [s.name, () => runSearch(s.name)]);
+  section("제목", data.titles, (t) => [
+    t.title,
+    () => (location.href = `/whatsnews/${t.id}`),
+  ]);
+  box.classList.toggle("hidden", !box.children.length);
+}
+function hideSuggest() {
+  clearTimeout(suggestTimer);
+  document.getElementById("suggestBox").classList.add("hidden");
+}
+function runSearch(q) {
+  document.getElementById("searchInput").value = q;
+  newsSearchKeyword = q;
+  resetAndLoadNews();
+}
+function addSelectedTag(tag) {
+  if (!selectedTags.some((t) => t.id === tag.id))
+    selectedTags.push({ id: tag.id, name: tag.name });
+  renderTagList();
+  resetAndLoadNews();
+}
+/* 검색 결과가 없으면 고친 검색어를 제안한다 */
+function showDidYouMean(q) {
+  fetch(`/api/suggest?q=${encodeURIComponent(q)}&limit=1`)
+    .then((r) => (r.ok ? r.json() : null))
+    .then((data) => {
+      if (!data || !data.did_you_mean || newsSearchKeyword !== q) return;
+      const el = document.getElementById("didYouMean");
+      const a = document.createElement("a");
+      a.href = "#";
+      a.className = "font-semibold text-blue-700 hover:underline";
+      a.textContent = data.did_you_mean;
+      a.onclick = (e) => {
+        e.preventDefault();
+        runSearch(data.did_you_mean);
+      };
+      el.innerHTML = "";
+      el.append("검색 결과가 없습니다. 혹시 ", a, " 을(를) 찾으셨나요?");
+      el.classList.remove("hidden");
+    });
+}
+
 /* ========= 뉴스 API ========= */
 function resetAndLoadNews() {
   currentPage = 1;
   cardItems = [];
+  nextCursor = "";
   isLoading = false;
   isEndOfList = false;
   document.getElementById("cardList").innerHTML = "";
   document.getElementById("newsCount").textContent = "";
+  document.getElementById("didYouMean").classList.add("hidden");
   loadNewsPage();
 }
 function loadNewsPage() {
@@ -313,21 +422,28 @@ function loadNewsPage() {
   isLoading = true;
   document.getElementById("loading").style.display = "";
 
-  let url = `/api/whatsnews?limit=${PAGE_SIZE}&offset=${cardItems.length}`;
+  /* 첫 페이지에서만 전체 건수를 세고, 이후는 커서로 이어 읽는다 */
+  let url = `/api/whatsnews?limit=${PAGE_SIZE}`;
+  if (nextCursor)
+    url += `&cursor=${encodeURIComponent(nextCursor)}&total=false`;
   if (selectedTags.length)
     url += `&tags=${selectedTags.map((t) => t.id).join(",")}`;
   if (newsSearchKeyword)
     url += `&search=${encodeURIComponent(newsSearchKeyword)}`;
+  const firstPage = !nextCursor;
 
   fetch(url)
     .then((r) => r.json())
     .then((data) => {
       const items = data.items || [];
       cardItems = cardItems.concat(items);
-      totalCount = data.total || 0;
+      if (data.total != null) totalCount = data.total;
+      nextCursor = data.next_cursor || "";
       appendCards(items);
       document.getElementById("newsCount").textContent = `전체 ${totalCount}건`;
-      if (cardItems.length >= totalCount || !items.length) isEndOfList = true;
+      if (!data.has_more || !nextCursor) isEndOfList = true;
+      if (firstPage && !totalCount && newsSearchKeyword)
+        showDidYouMean(newsSearchKeyword);
     })
     .finally(() => {
       isLoading = false;
@@ -406,9 +522,14 @@ function appendCards(items) {
     /* ─ Date ─ */
     const date = document.createElement("div");
     date.className = "text-gray-500 text-sm mb-2";
-    date.textContent = it.source_created_at
+    /* ─ 날짜는 퍼머링크 ─ */
+    const dateLink = document.createElement("a");
+    dateLink.href = `/whatsnews/${it.id}`;
+    dateLink.className = "hover:underline";
+    dateLink.textContent = it.source_created_at
       ? it.source_created_at.slice(0, 10)
-      : "";
+      : "permalink";
+    date.appendChild(dateLink);
 
     /* ─ Tags ─ */
     const tagWrap = document.createElement("div");
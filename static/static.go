@@ -0,0 +1,10 @@
+// Package static은 웹 UI 파일을 바이너리에 넣는다. 서빙은 internal/assets.go가 한다.
+// 빌드 전에 만든 압축본(main.js.br, main.js.gz)이 있으면 함께 들어가 그대로 쓰인다.
+package static
+
+import "embed"
+
+// Files에는 이 파일(static.go)도 들어가지만 서빙하지 않는다.
+//
+//go:embed *
+var Files embed.FS